@@ -0,0 +1,112 @@
+// Copyright © 2017-2020 Aqua Security Software Ltd. <info@aquasec.com>
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package check
+
+import (
+	"path/filepath"
+	"regexp"
+)
+
+const (
+	// ProcessesCategory is the category of checks that inspect the command line
+	// of running processes, which need to see the processes of the host.
+	ProcessesCategory = "processes"
+	// FilesCategory is the category of checks that inspect files, which need the
+	// host filesystem and, for the files only root can read, to run as root.
+	FilesCategory = "files"
+)
+
+var (
+	processAudit = regexp.MustCompile(`(^|[\s/'"])ps\s`)
+	auditPath    = regexp.MustCompile(`(?:^|[\s'"=])(/[^\s'"|;]+)`)
+
+	// binDirs hold the commands run by audits rather than the files they inspect.
+	binDirs = map[string]bool{"/bin": true, "/sbin": true, "/usr/bin": true, "/usr/sbin": true, "/usr/local/bin": true}
+)
+
+// Capabilities tells what kube-bench could see of the host it ran on, and so
+// which checks could be fully evaluated. Consumers of the results can discount
+// the groups that were structurally blind, whose checks may pass or fail only
+// because the host wasn't visible.
+type Capabilities struct {
+	HostPID        bool `json:"host_pid"`
+	HostFilesystem bool `json:"host_filesystem"`
+	Root           bool `json:"root"`
+	// Categories tells for each category of checks whether they could be fully evaluated.
+	Categories map[string]bool `json:"categories"`
+	// BlindGroups are the IDs of the groups with checks that couldn't be fully evaluated.
+	BlindGroups []string `json:"blind_groups,omitempty"`
+}
+
+// SetCapabilities records the capabilities kube-bench ran with in the results,
+// along with the groups whose checks couldn't be fully evaluated with them.
+func (controls *Controls) SetCapabilities(hostPID, hostFilesystem, root bool) {
+	caps := &Capabilities{
+		HostPID:        hostPID,
+		HostFilesystem: hostFilesystem,
+		Root:           root,
+		Categories: map[string]bool{
+			ProcessesCategory: hostPID,
+			FilesCategory:     hostFilesystem && root,
+		},
+	}
+
+	for _, group := range controls.Groups {
+		for _, check := range group.Checks {
+			if !caps.canEvaluate(check) {
+				caps.BlindGroups = append(caps.BlindGroups, group.ID)
+				break
+			}
+		}
+	}
+	controls.Capabilities = caps
+}
+
+func (caps *Capabilities) canEvaluate(c *Check) bool {
+	for _, category := range c.categories() {
+		if !caps.Categories[category] {
+			return false
+		}
+	}
+	return true
+}
+
+// categories returns the categories of a check, from what its audit inspects.
+func (c *Check) categories() []string {
+	if c.Type == MANUAL || c.Type == "skip" {
+		return nil
+	}
+
+	var categories []string
+	audits := c.Audit + "\n" + c.AuditConfig
+	if processAudit.MatchString(audits) {
+		categories = append(categories, ProcessesCategory)
+	}
+	if c.Grep != nil || inspectsFiles(audits) {
+		categories = append(categories, FilesCategory)
+	}
+	return categories
+}
+
+// inspectsFiles reports whether an audit refers to absolute paths other than
+// those of the commands it runs.
+func inspectsFiles(audit string) bool {
+	for _, m := range auditPath.FindAllStringSubmatch(audit, -1) {
+		if !binDirs[filepath.Dir(m[1])] {
+			return true
+		}
+	}
+	return false
+}
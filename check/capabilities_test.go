@@ -0,0 +1,57 @@
+// Copyright © 2017-2020 Aqua Security Software Ltd. <info@aquasec.com>
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package check
+
+import (
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+)
+
+func TestCheck_Categories(t *testing.T) {
+	cases := []struct {
+		check      Check
+		categories []string
+	}{
+		{check: Check{Audit: "/bin/ps -ef | grep kube-apiserver | grep -v grep"}, categories: []string{ProcessesCategory}},
+		{check: Check{Audit: "ps -ef | grep kubelet", AuditConfig: "cat /var/lib/kubelet/config.yaml"}, categories: []string{ProcessesCategory, FilesCategory}},
+		{check: Check{Audit: "/bin/sh -c 'if test -e /etc/kubernetes/admin.conf; then stat -c %a /etc/kubernetes/admin.conf; fi'"}, categories: []string{FilesCategory}},
+		{check: Check{Grep: &grepAudit{Path: "/var/log/audit.log"}}, categories: []string{FilesCategory}},
+		{check: Check{Audit: "ps -ef | grep kubelet", Type: MANUAL}},
+		{check: Check{Audit: "echo $HOME"}},
+	}
+	for _, c := range cases {
+		assert.Equal(t, c.categories, c.check.categories(), c.check.Audit)
+	}
+}
+
+func TestControls_SetCapabilities(t *testing.T) {
+	controls := &Controls{Groups: []*Group{
+		{ID: "1.1", Checks: []*Check{{Audit: "stat -c %a /etc/kubernetes/manifests/kube-apiserver.yaml"}}},
+		{ID: "1.2", Checks: []*Check{{Audit: "/bin/ps -ef | grep kube-apiserver | grep -v grep"}}},
+		{ID: "1.3", Checks: []*Check{{Type: MANUAL}}},
+	}}
+
+	controls.SetCapabilities(false, true, true)
+	assert.Equal(t, &Capabilities{
+		HostFilesystem: true,
+		Root:           true,
+		Categories:     map[string]bool{ProcessesCategory: false, FilesCategory: true},
+		BlindGroups:    []string{"1.2"},
+	}, controls.Capabilities)
+
+	controls.SetCapabilities(true, true, false)
+	assert.Equal(t, []string{"1.1"}, controls.Capabilities.BlindGroups, "files may only be readable by root")
+}
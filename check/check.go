@@ -41,6 +41,11 @@ const (
 	WARN State = "WARN"
 	// INFO informational message
 	INFO State = "INFO"
+	// INCOMPLETE check didn't run because the scan was interrupted.
+	INCOMPLETE State = "INCOMPLETE"
+	// ERROR check whose audit command exceeded its resource limits, or whose
+	// definition is invalid.
+	ERROR State = "ERROR"
 
 	// MASTER a master node
 	MASTER NodeType = "master"
@@ -62,6 +67,45 @@ const (
 	MANUAL string = "manual"
 )
 
+// ReasonCode identifies why a check was skipped, downgraded or short-circuited
+// instead of being evaluated by its tests, for consumers of the results.
+type ReasonCode string
+
+const (
+	// ReasonNoTests is a scored check without tests.
+	ReasonNoTests ReasonCode = "no_tests"
+	// ReasonSkip is a check of type skip.
+	ReasonSkip ReasonCode = "skip"
+	// ReasonManual is a check of type manual.
+	ReasonManual ReasonCode = "manual"
+	// ReasonMissingAudit is a check without an audit command.
+	ReasonMissingAudit ReasonCode = "missing_audit"
+	// ReasonCommandNotFound is a check whose audit commands aren't installed.
+	ReasonCommandNotFound ReasonCode = "command_not_found"
+	// ReasonAuditUnavailable is a check without a recorded audit output.
+	ReasonAuditUnavailable ReasonCode = "audit_unavailable"
+	// ReasonGrepUnsupported is a grep audit evaluated against recorded outputs.
+	ReasonGrepUnsupported ReasonCode = "grep_unsupported"
+	// ReasonGrepFailed is a grep audit that couldn't read its files.
+	ReasonGrepFailed ReasonCode = "grep_failed"
+	// ReasonGrepTruncated is a grep audit that stopped at its limits before the
+	// result of the tests was decided.
+	ReasonGrepTruncated ReasonCode = "grep_truncated"
+	// ReasonLimitExceeded is a check whose audit exceeded its resource limits.
+	ReasonLimitExceeded ReasonCode = "limit_exceeded"
+	// ReasonUnscoredFailure is a failing check downgraded to WARN as it isn't scored.
+	ReasonUnscoredFailure ReasonCode = "unscored_failure"
+	// ReasonInterrupted is a check that didn't run because the scan was interrupted.
+	ReasonInterrupted ReasonCode = "interrupted"
+	// ReasonFiltered is a check that wasn't selected to run.
+	ReasonFiltered ReasonCode = "filtered"
+	// ReasonNotApplicable is a check whose requirements the facts don't meet.
+	ReasonNotApplicable ReasonCode = "not_applicable"
+	// ReasonInvalidDefinition is a check left out of the run as its definition
+	// in the controls file is invalid.
+	ReasonInvalidDefinition ReasonCode = "invalid_definition"
+)
+
 // Check contains information about a recommendation in the
 // CIS Kubernetes document.
 type Check struct {
@@ -70,17 +114,40 @@ type Check struct {
 	Audit          string      `json:"audit"`
 	AuditConfig    string      `yaml:"audit_config"`
 	Type           string      `json:"type"`
-	Commands       []*exec.Cmd `json:"omit"`
-	ConfigCommands []*exec.Cmd `json:"omit"`
-	Tests          *tests      `json:"omit"`
-	Set            bool        `json:"omit"`
+	Commands       []*exec.Cmd `json:"-"`
+	ConfigCommands []*exec.Cmd `json:"-"`
+	Tests          *tests      `json:"-"`
+	Grep           *grepAudit  `yaml:"grep" json:"-"`
+	Set            bool        `json:"-"`
 	Remediation    string      `json:"remediation"`
+	Impact         string      `yaml:"impact" json:"impact,omitempty"`
+	DefaultValue   string      `yaml:"default_value" json:"default_value,omitempty"`
+	Owner          string      `yaml:"owner" json:"owner,omitempty"`
 	TestInfo       []string    `json:"test_info"`
 	State          `json:"status"`
-	ActualValue    string `json:"actual_value"`
-	Scored         bool   `json:"scored"`
-	ExpectedResult string `json:"expected_result"`
-	Reason     string `json:"reason,omitempty"`
+	ActualValue    string        `json:"actual_value"`
+	Scored         bool          `json:"scored"`
+	ExpectedResult string        `json:"expected_result"`
+	Expected       string        `yaml:"-" json:"expected"`
+	TestResults    []*TestResult `yaml:"-" json:"test_results,omitempty"`
+	Reason         string        `json:"reason,omitempty"`
+	// ReasonCode is set with Reason when the check wasn't evaluated by its tests.
+	ReasonCode ReasonCode `yaml:"-" json:"reason_code,omitempty"`
+	// Regression is set when a check that passed in the baseline fails.
+	Regression bool `yaml:"-" json:"regression,omitempty"`
+	// Annotations are the key=value pairs given with --annotate, attached to
+	// the result of every check.
+	Annotations map[string]string `yaml:"-" json:"annotations,omitempty"`
+	// Requires are the facts the check applies to, such as proxymode: ipvs,
+	// each one with a value or a comma-separated list of values.
+	Requires map[string]string `yaml:"requires" json:"-"`
+	// notApplicable is why the facts don't meet the requirements of the check.
+	notApplicable string
+	// invalid holds the errors in the definition of the check, which keep it
+	// from running.
+	invalid string
+	// Trace, when set, receives every step of the evaluation of the check.
+	Trace io.Writer `yaml:"-" json:"-"`
 }
 
 // Runner wraps the basic Run method.
@@ -94,21 +161,59 @@ func NewRunner() Runner {
 	return &defaultRunner{}
 }
 
-type defaultRunner struct{}
+// AuditFunc returns the output of an audit command, in place of running it.
+type AuditFunc func(audit string) (string, error)
+
+// NewAuditRunner constructs a Runner that evaluates the tests of the checks
+// against the outputs returned by audit, instead of running their audit
+// commands, e.g. to run the checks against recorded data.
+func NewAuditRunner(audit AuditFunc) Runner {
+	return &defaultRunner{audit: audit}
+}
+
+type defaultRunner struct {
+	limits Limits
+	audit  AuditFunc
+}
 
 func (r *defaultRunner) Run(c *Check) State {
-	return c.run()
+	return c.runWith(r)
 }
 
-// Run executes the audit commands specified in a check and outputs
-// the results.
+// run executes the audit commands of a check without resource limits.
 func (c *Check) run() State {
+	return c.runWith(&defaultRunner{})
+}
+
+// runWith executes the audit commands specified in a check with the given
+// runner and outputs the results.
+func (c *Check) runWith(r *defaultRunner) State {
+	if c.Trace != nil {
+		c.tracef("check %s: %s", c.ID, c.Text)
+		c.tracef("type: %q, scored: %t", c.Type, c.Scored)
+		defer func() { c.traceVerdict() }()
+	}
+
+	if c.invalid != "" {
+		c.Reason = c.invalid
+		c.ReasonCode = ReasonInvalidDefinition
+		c.State = ERROR
+		return c.State
+	}
+
+	if c.notApplicable != "" {
+		c.Reason = c.notApplicable
+		c.ReasonCode = ReasonNotApplicable
+		c.State = INFO
+		return c.State
+	}
 
 	// Since this is an Scored check
 	// without tests return a 'WARN' to alert
 	// the user that this check needs attention
 	if c.Scored && len(strings.TrimSpace(c.Type)) == 0 && c.Tests == nil {
 		c.Reason = "There are no tests"
+		c.ReasonCode = ReasonNoTests
 		c.State = WARN
 		return c.State
 	}
@@ -116,6 +221,7 @@ func (c *Check) run() State {
 	// If check type is skip, force result to INFO
 	if c.Type == "skip" {
 		c.Reason = "Test marked as skip"
+		c.ReasonCode = ReasonSkip
 		c.State = INFO
 		return c.State
 	}
@@ -123,14 +229,25 @@ func (c *Check) run() State {
 	// If check type is manual force result to WARN
 	if c.Type == MANUAL {
 		c.Reason = "Test marked as a manual test"
+		c.ReasonCode = ReasonManual
 		c.State = WARN
 		return c.State
 	}
 
+	if c.Grep != nil {
+		if r.audit != nil {
+			c.Reason = "grep audits can't be evaluated against recorded outputs"
+			c.ReasonCode = ReasonGrepUnsupported
+			c.State = WARN
+			return c.State
+		}
+		return c.runGrep()
+	}
+
 	lastCommand := c.Audit
 	hasAuditConfig := c.ConfigCommands != nil
 
-	state, finalOutput, retErrmsgs := performTest(c.Audit, c.Commands, c.Tests)
+	state, finalOutput, retErrmsgs := c.performTest(c.Audit, c.Commands, c.Tests, r)
 	if len(state) > 0 {
 		c.Reason = retErrmsgs
 		c.State = state
@@ -166,7 +283,11 @@ func (c *Check) run() State {
 			currentTests.TestItems[i] = nti
 		}
 
-		state, finalOutput, retErrmsgs = performTest(c.AuditConfig, c.ConfigCommands, currentTests)
+		c.tracef("the tests failed, evaluating them against audit_config")
+		// The reason the audit failed for, such as a missing audit, doesn't
+		// hold for the result of audit_config.
+		c.ReasonCode = ""
+		state, finalOutput, retErrmsgs = c.performTest(c.AuditConfig, c.ConfigCommands, currentTests, r)
 		if len(state) > 0 {
 			c.Reason = retErrmsgs
 			c.State = state
@@ -175,15 +296,27 @@ func (c *Check) run() State {
 		errmsgs += retErrmsgs
 	}
 
-	if finalOutput != nil && finalOutput.testResult {
-		c.State = PASS
+	return c.setResult(finalOutput, errmsgs, lastCommand)
+}
+
+// setResult sets the state of the check from the output of its tests.
+func (c *Check) setResult(finalOutput *testOutput, errmsgs, lastCommand string) State {
+	if finalOutput != nil {
 		c.ActualValue = finalOutput.actualResult
 		c.ExpectedResult = finalOutput.ExpectedResult
+		c.TestResults = finalOutput.testResults
+	}
+
+	if finalOutput != nil && finalOutput.testResult {
+		c.State = PASS
 	} else {
 		if c.Scored {
 			c.State = FAIL
 		} else {
 			c.Reason = errmsgs
+			if c.ReasonCode == "" {
+				c.ReasonCode = ReasonUnscoredFailure
+			}
 			c.State = WARN
 		}
 	}
@@ -251,6 +384,10 @@ func isShellCommand(s string) bool {
 	cmd := exec.Command("/bin/sh", "-c", "command -v "+s)
 
 	out, err := cmd.Output()
+	if _, ok := err.(*exec.ExitError); ok {
+		// command -v fails for commands that aren't found.
+		return false
+	}
 	if err != nil {
 		exitWithError(fmt.Errorf("failed to check if command: %q is valid %v", s, err))
 	}
@@ -261,27 +398,54 @@ func isShellCommand(s string) bool {
 	return false
 }
 
-func performTest(audit string, commands []*exec.Cmd, tests *tests) (State, *testOutput, string) {
+func (c *Check) performTest(audit string, commands []*exec.Cmd, tests *tests, r *defaultRunner) (State, *testOutput, string) {
 	if len(strings.TrimSpace(audit)) == 0 {
+		c.ReasonCode = ReasonMissingAudit
 		return "", failTestItem("missing command"), "missing audit command"
 	}
 
+	c.tracef("audit: %s", audit)
 	var out bytes.Buffer
-	state, retErrmsgs := runExecCommands(audit, commands, &out)
-	if len(state) > 0 {
+	var state State
+	var retErrmsgs string
+	if r.audit != nil {
+		c.tracef("evaluated against a recorded output")
+		output, err := r.audit(audit)
+		if err != nil {
+			c.ReasonCode = ReasonAuditUnavailable
+			return WARN, nil, err.Error()
+		}
+		out.WriteString(output)
+	} else {
+		for i, cmd := range commands {
+			c.tracef("command %d: %q", i+1, cmd.Args)
+		}
+		state, retErrmsgs = runExecCommands(audit, commands, &out, r.limits)
+	}
+	switch state {
+	case "":
+	case ERROR:
+		c.ReasonCode = ReasonLimitExceeded
+		return state, nil, retErrmsgs
+	default:
+		if c.ReasonCode == "" {
+			c.ReasonCode = ReasonCommandNotFound
+		}
 		return state, nil, retErrmsgs
 	}
 	errmsgs := retErrmsgs
+	c.tracef("output:\n%s", out.String())
 
 	finalOutput := tests.execute(out.String())
 	if finalOutput == nil {
 		errmsgs += fmt.Sprintf("Final output is <<EMPTY>>. Failed to run: %s\n", audit)
 	}
+	c.traceTests(tests, finalOutput)
 
 	return "", finalOutput, errmsgs
 }
 
-func runExecCommands(audit string, commands []*exec.Cmd, out *bytes.Buffer) (State, string) {
+func runExecCommands(audit string, commands []*exec.Cmd, out *bytes.Buffer, limits Limits) (State, string) {
 	var err error
 	errmsgs := ""
 
@@ -304,6 +468,12 @@ func runExecCommands(audit string, commands []*exec.Cmd, out *bytes.Buffer) (Sta
 	//   cmd0 out -> cmd1 in, cmd1 out -> cmd2 in ... cmdn out -> os.stdout
 	//   cmd0 err should terminate chain
 	cs := commands
+	if limits.enabled() {
+		cs = make([]*exec.Cmd, n)
+		for i, cmd := range commands {
+			cs[i] = limits.wrap(cmd)
+		}
+	}
 
 	// Initialize command pipeline
 	cs[n-1].Stdout = out
@@ -327,12 +497,18 @@ func runExecCommands(audit string, commands []*exec.Cmd, out *bytes.Buffer) (Sta
 		i++
 	}
 
+	watchdog := startWatchdog(limits.Timeout, cs)
+
 	// Complete command pipeline
+	breach := ""
 	i = 0
 	for i < n {
 		err := cs[i].Wait()
 		if err != nil {
 			errmsgs += fmt.Sprintf("failed to run: %s, command: %s, error: %s\n", audit, cs[i].Args, err)
+			if b := limits.limitBreach(err); b != "" && breach == "" {
+				breach = fmt.Sprintf("audit command %s %s", commands[i].Args[0], b)
+			}
 		}
 
 		if i < n-1 {
@@ -341,7 +517,14 @@ func runExecCommands(audit string, commands []*exec.Cmd, out *bytes.Buffer) (Sta
 		i++
 	}
 
+	if watchdog.stop() {
+		breach = fmt.Sprintf("audit exceeded the time limit of %v", limits.Timeout)
+	}
+
 	glog.V(3).Infof("Command %q - Output:\n\n %q\n - Error Messages:%q \n", audit, out.String(), errmsgs)
+	if breach != "" {
+		return ERROR, breach
+	}
 	return "", errmsgs
 }
 
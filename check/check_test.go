@@ -15,6 +15,7 @@
 package check
 
 import (
+	"fmt"
 	"os/exec"
 	"testing"
 )
@@ -111,3 +112,94 @@ func TestCheckAuditConfig(t *testing.T) {
 		}
 	}
 }
+
+func TestCheck_RunRecordsActualAndExpected(t *testing.T) {
+	c := Check{
+		Scored: true,
+		Audit:  "echo --anonymous-auth=true",
+		Tests: &tests{TestItems: []*testItem{{
+			Flag:    "--anonymous-auth",
+			Set:     true,
+			Compare: compare{Op: "eq", Value: "false"},
+		}}},
+	}
+	c.Commands = textToCommand(c.Audit)
+	c.Expected = c.Tests.expected()
+
+	c.run()
+
+	if c.State != FAIL {
+		t.Fatalf("expected FAIL, actual %s", c.State)
+	}
+	if c.ActualValue != "--anonymous-auth=true\n" {
+		t.Errorf("unexpected actual value %q", c.ActualValue)
+	}
+	if c.ExpectedResult != "'true' is equal to 'false'" {
+		t.Errorf("unexpected expected result %q", c.ExpectedResult)
+	}
+	if c.Expected != "'--anonymous-auth' is equal to 'false'" {
+		t.Errorf("unexpected expected %q", c.Expected)
+	}
+}
+
+func TestAuditRunner(t *testing.T) {
+	outputs := map[string]string{"ps -ef | grep kubelet": "kubelet --anonymous-auth=false"}
+	runner := NewAuditRunner(func(audit string) (string, error) {
+		out, ok := outputs[audit]
+		if !ok {
+			return "", fmt.Errorf("unknown audit %s", audit)
+		}
+		return out, nil
+	})
+
+	c := &Check{Scored: true, Audit: "ps -ef | grep kubelet", Tests: &tests{TestItems: []*testItem{{Flag: "--anonymous-auth", Set: true}}}}
+	if state := runner.Run(c); state != PASS {
+		t.Errorf("expected PASS, actual %s", state)
+	}
+
+	c = &Check{Scored: true, Audit: "cat /etc/kubernetes/kubelet.conf", Tests: &tests{TestItems: []*testItem{{Flag: "server", Set: true}}}}
+	if state := runner.Run(c); state != WARN || c.Reason != "unknown audit cat /etc/kubernetes/kubelet.conf" {
+		t.Errorf("expected WARN for an unknown audit, actual %s: %s", c.State, c.Reason)
+	}
+}
+
+func TestCheck_ReasonCode(t *testing.T) {
+	failing := &tests{TestItems: []*testItem{{Flag: "--missing", Set: true}}}
+	testCases := []struct {
+		check    Check
+		expected ReasonCode
+	}{
+		{check: Check{Type: MANUAL}, expected: ReasonManual},
+		{check: Check{Type: "skip"}, expected: ReasonSkip},
+		{check: Check{Scored: true}, expected: ReasonNoTests},
+		{check: Check{Scored: true, Tests: failing}, expected: ReasonMissingAudit},
+		{check: Check{Scored: true, Audit: "not-a-command", Commands: textToCommand("not-a-command"), Tests: failing}, expected: ReasonCommandNotFound},
+		{check: Check{Scored: false, Audit: "echo", Commands: textToCommand("echo"), Tests: failing}, expected: ReasonUnscoredFailure},
+		{check: Check{Scored: true, Audit: "echo", Commands: textToCommand("echo"), Tests: failing}, expected: ""},
+	}
+	for _, tc := range testCases {
+		tc.check.run()
+		if tc.check.ReasonCode != tc.expected {
+			t.Errorf("expected reason code %q for %+v, actual %q", tc.expected, tc.check, tc.check.ReasonCode)
+		}
+	}
+
+	// A check without an audit evaluated with its audit_config has the reason
+	// code of the result of audit_config.
+	c := &Check{Scored: true, AuditConfig: "echo 'enabled: true'", ConfigCommands: textToCommand("echo 'enabled: true'"),
+		Tests: &tests{TestItems: []*testItem{{Path: "{.enabled}", Set: true}}}}
+	if state := c.run(); state != PASS || c.ReasonCode != "" {
+		t.Errorf("expected PASS without reason code, actual %s with %q", state, c.ReasonCode)
+	}
+	c = &Check{Scored: false, AuditConfig: "echo 'enabled: true'", ConfigCommands: textToCommand("echo 'enabled: true'"),
+		Tests: &tests{TestItems: []*testItem{{Path: "{.disabled}", Set: true}}}}
+	if state := c.run(); state != WARN || c.ReasonCode != ReasonUnscoredFailure {
+		t.Errorf("expected WARN with reason code %q, actual %s with %q", ReasonUnscoredFailure, state, c.ReasonCode)
+	}
+
+	c = &Check{Scored: true, Audit: "cat /etc/kubernetes/kubelet.conf", Tests: failing}
+	NewAuditRunner(func(string) (string, error) { return "", fmt.Errorf("no output") }).Run(c)
+	if c.ReasonCode != ReasonAuditUnavailable {
+		t.Errorf("expected reason code %q, actual %q", ReasonAuditUnavailable, c.ReasonCode)
+	}
+}
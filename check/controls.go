@@ -19,6 +19,8 @@ import (
 	"encoding/json"
 	"encoding/xml"
 	"fmt"
+	"sort"
+	"strings"
 
 	"github.com/golang/glog"
 	"github.com/onsi/ginkgo/reporters"
@@ -31,27 +33,70 @@ type Controls struct {
 	Version string   `json:"version"`
 	Text    string   `json:"text"`
 	Type    NodeType `json:"node_type"`
-	Groups  []*Group `json:"tests"`
+	ScanID  string   `yaml:"-" json:"scan_id,omitempty"`
+	// Timestamp is when the checks ran, as RFC3339 in UTC.
+	Timestamp string   `yaml:"-" json:"timestamp,omitempty"`
+	Groups    []*Group `json:"tests"`
 	Summary
+	Totals       Counts        `yaml:"-" json:"summary"`
+	Capabilities *Capabilities `yaml:"-" json:"capabilities,omitempty"`
+	// Skipped are the checks of the controls that didn't run at all.
+	Skipped []*SkippedCheck `yaml:"-" json:"skipped,omitempty"`
+	// Coverage tells how many of the checks were executed in the last run.
+	Coverage Coverage `yaml:"-" json:"coverage"`
+}
+
+// SkippedCheck is a check of the controls left out of the results, and why.
+type SkippedCheck struct {
+	ID         string     `json:"test_number"`
+	Text       string     `json:"test_desc"`
+	Section    string     `json:"section"`
+	ReasonCode ReasonCode `json:"reason_code"`
+	Reason     string     `json:"reason"`
 }
 
 // Group is a collection of similar checks.
 type Group struct {
-	ID     string   `yaml:"id" json:"section"`
-	Pass   int      `json:"pass"`
-	Fail   int      `json:"fail"`
-	Warn   int      `json:"warn"`
-	Info   int      `json:"info"`
-	Text   string   `json:"desc"`
-	Checks []*Check `json:"results"`
+	ID   string `yaml:"id" json:"section"`
+	Pass int    `json:"pass"`
+	Fail int    `json:"fail"`
+	Warn int    `json:"warn"`
+	Info int    `json:"info"`
+	// Incomplete is the number of checks that didn't run because the scan was interrupted.
+	Incomplete int `json:"incomplete,omitempty"`
+	// Error is the number of checks whose audit exceeded its resource limits,
+	// or whose definition is invalid.
+	Error   int      `json:"error,omitempty"`
+	Text    string   `json:"desc"`
+	Owner   string   `yaml:"owner" json:"owner,omitempty"`
+	Checks  []*Check `json:"results"`
+	Summary Counts   `yaml:"-" json:"summary"`
 }
 
 // Summary is a summary of the results of control checks run.
 type Summary struct {
-	Pass int `json:"total_pass"`
-	Fail int `json:"total_fail"`
-	Warn int `json:"total_warn"`
-	Info int `json:"total_info"`
+	Pass       int `json:"total_pass"`
+	Fail       int `json:"total_fail"`
+	Warn       int `json:"total_warn"`
+	Info       int `json:"total_info"`
+	Incomplete int `json:"total_incomplete,omitempty"`
+	Error      int `json:"total_error,omitempty"`
+}
+
+// Counts holds the number of checks in each state for a group or a section,
+// so consumers of the results don't have to recompute them from the checks.
+type Counts struct {
+	Pass       int `json:"pass"`
+	Fail       int `json:"fail"`
+	Warn       int `json:"warn"`
+	Info       int `json:"info"`
+	Incomplete int `json:"incomplete,omitempty"`
+	Error      int `json:"error,omitempty"`
+	Total      int `json:"total"`
+}
+
+func newCounts(pass, fail, warn, info, incomplete, errors int) Counts {
+	return Counts{Pass: pass, Fail: fail, Warn: warn, Info: info, Incomplete: incomplete, Error: errors, Total: pass + fail + warn + info + incomplete + errors}
 }
 
 // Predicate a predicate on the given Group and Check arguments.
@@ -70,10 +115,19 @@ func NewControls(t NodeType, in []byte) (*Controls, error) {
 		return nil, fmt.Errorf("non-%s controls file specified", t)
 	}
 
-	// Prepare audit commands
+	c.prepare()
+	return c, nil
+}
+
+// prepare sets the owners, expected results and audit commands of the checks.
+func (c *Controls) prepare() {
 	for _, group := range c.Groups {
 		for _, check := range group.Checks {
 			glog.V(3).Infof("Check.ID %s", check.ID)
+			if check.Owner == "" {
+				check.Owner = group.Owner
+			}
+			check.Expected = check.Tests.expected()
 			check.Commands = textToCommand(check.Audit)
 			if len(check.AuditConfig) > 0 {
 				glog.V(3).Infof("Check.ID has audit_config %s", check.ID)
@@ -81,20 +135,133 @@ func NewControls(t NodeType, in []byte) (*Controls, error) {
 			}
 		}
 	}
+}
 
-	return c, nil
+// SetOwners overrides the owner of groups and checks with the given map of
+// group or check IDs to owners. An owner set on a check ID takes precedence
+// over the one set on its group ID.
+func (controls *Controls) SetOwners(owners map[string]string) {
+	if len(owners) == 0 {
+		return
+	}
+
+	for _, group := range controls.Groups {
+		if owner, ok := owners[group.ID]; ok {
+			group.Owner = owner
+			for _, check := range group.Checks {
+				check.Owner = owner
+			}
+		}
+		for _, check := range group.Checks {
+			if owner, ok := owners[check.ID]; ok {
+				check.Owner = owner
+			}
+		}
+	}
+}
+
+// OverrideExpectedValues replaces the values the tests of checks compare to
+// with the given map of check IDs to values, keyed by the flag or path of the
+// test, in any case. The value of the controls file and source are recorded in
+// the results of the overridden tests.
+func (controls *Controls) OverrideExpectedValues(values map[string]map[string]string, source string) {
+	if len(values) == 0 {
+		return
+	}
+
+	for _, group := range controls.Groups {
+		for _, check := range group.Checks {
+			overrides, ok := values[check.ID]
+			if !ok || check.Tests == nil {
+				continue
+			}
+			for _, item := range check.Tests.TestItems {
+				for key, value := range overrides {
+					if (item.Flag != "" && strings.EqualFold(key, item.Flag)) || (item.Path != "" && strings.EqualFold(key, item.Path)) {
+						item.override = &ValueOverride{BenchmarkValue: item.Compare.Value, Source: source}
+						item.Compare.Value = value
+					}
+				}
+			}
+			check.Expected = check.Tests.expected()
+		}
+	}
+}
+
+// Overlay applies the groups and checks of overlay onto the controls. A check
+// replaces the check with the same ID, wherever it is, and the other checks
+// are added to the group with the same ID, which is created if missing. The
+// text of the controls and of the groups is replaced when set in overlay.
+func (controls *Controls) Overlay(overlay *Controls) {
+	if overlay.ID != "" {
+		controls.ID = overlay.ID
+	}
+	if overlay.Version != "" {
+		controls.Version = overlay.Version
+	}
+	if overlay.Text != "" {
+		controls.Text = overlay.Text
+	}
+
+	type location struct {
+		group *Group
+		index int
+	}
+	groups := make(map[string]*Group)
+	checks := make(map[string]location)
+	for _, group := range controls.Groups {
+		groups[group.ID] = group
+		for i, check := range group.Checks {
+			checks[check.ID] = location{group, i}
+		}
+	}
+
+	for _, og := range overlay.Groups {
+		group, ok := groups[og.ID]
+		if !ok {
+			group = &Group{ID: og.ID}
+			groups[og.ID] = group
+			controls.Groups = append(controls.Groups, group)
+		}
+		if og.Text != "" {
+			group.Text = og.Text
+		}
+		if og.Owner != "" {
+			group.Owner = og.Owner
+		}
+
+		for _, check := range og.Checks {
+			if l, ok := checks[check.ID]; ok {
+				l.group.Checks[l.index] = check
+				continue
+			}
+			checks[check.ID] = location{group, len(group.Checks)}
+			group.Checks = append(group.Checks, check)
+		}
+	}
 }
 
 // RunChecks runs the checks with the given Runner. Only checks for which the filter Predicate returns `true` will run.
 func (controls *Controls) RunChecks(runner Runner, filter Predicate) Summary {
 	var g []*Group
 	m := make(map[string]*Group)
-	controls.Summary.Pass, controls.Summary.Fail, controls.Summary.Warn, controls.Info = 0, 0, 0, 0
+	controls.Summary.Pass, controls.Summary.Fail, controls.Summary.Warn, controls.Info, controls.Incomplete, controls.Summary.Error = 0, 0, 0, 0, 0, 0
+
+	controls.Skipped = nil
+	controls.Coverage = Coverage{Sections: []*SectionCoverage{}}
 
 	for _, group := range controls.Groups {
 		for _, check := range group.Checks {
 
 			if !filter(group, check) {
+				controls.Skipped = append(controls.Skipped, &SkippedCheck{
+					ID:         check.ID,
+					Text:       check.Text,
+					Section:    group.ID,
+					ReasonCode: ReasonFiltered,
+					Reason:     "Not selected to run",
+				})
+				controls.Coverage.add(group, ReasonFiltered)
 				continue
 			}
 
@@ -123,13 +290,71 @@ func (controls *Controls) RunChecks(runner Runner, filter Predicate) Summary {
 			}
 
 			summarize(controls, state)
+			controls.Coverage.add(group, check.ReasonCode)
 		}
 	}
 
+	for _, group := range g {
+		group.Summary = newCounts(group.Pass, group.Fail, group.Warn, group.Info, group.Incomplete, group.Error)
+	}
+	controls.Totals = newCounts(controls.Summary.Pass, controls.Summary.Fail, controls.Summary.Warn, controls.Summary.Info, controls.Summary.Incomplete, controls.Summary.Error)
+
 	controls.Groups = g
 	return controls.Summary
 }
 
+// CheckRequirements marks the checks whose requirements aren't met by the
+// facts as not applicable, so that they are reported as INFO without running.
+func (controls *Controls) CheckRequirements(facts map[string]string) {
+	for _, group := range controls.Groups {
+		for _, check := range group.Checks {
+			check.notApplicable = unmetRequirement(check.Requires, facts)
+		}
+	}
+}
+
+// unmetRequirement describes the first of the requirements the facts don't
+// meet, if any.
+func unmetRequirement(requires, facts map[string]string) string {
+	names := make([]string, 0, len(requires))
+	for name := range requires {
+		names = append(names, name)
+	}
+	sort.Strings(names)
+
+	for _, name := range names {
+		want := requires[name]
+		got, ok := facts[name]
+		if !ok {
+			return fmt.Sprintf("Not applicable: %s is unknown, the check requires %s", name, want)
+		}
+		met := false
+		for _, v := range strings.Split(want, ",") {
+			if strings.EqualFold(strings.TrimSpace(v), got) {
+				met = true
+				break
+			}
+		}
+		if !met {
+			return fmt.Sprintf("Not applicable: %s is %s, the check requires %s", name, got, want)
+		}
+	}
+	return ""
+}
+
+// Annotate attaches the annotations to the result of every check of the
+// controls.
+func (controls *Controls) Annotate(annotations map[string]string) {
+	if len(annotations) == 0 {
+		return
+	}
+	for _, group := range controls.Groups {
+		for _, check := range group.Checks {
+			check.Annotations = annotations
+		}
+	}
+}
+
 // JSON encodes the results of last run to JSON.
 func (controls *Controls) JSON() ([]byte, error) {
 	return json.Marshal(controls)
@@ -140,8 +365,9 @@ func (controls *Controls) JUnit() ([]byte, error) {
 	suite := reporters.JUnitTestSuite{
 		Name:      controls.Text,
 		TestCases: []reporters.JUnitTestCase{},
-		Tests:     controls.Summary.Pass + controls.Summary.Fail + controls.Summary.Info + controls.Summary.Warn,
+		Tests:     controls.Summary.Pass + controls.Summary.Fail + controls.Summary.Info + controls.Summary.Warn + controls.Summary.Incomplete + controls.Summary.Error,
 		Failures:  controls.Summary.Fail,
+		Errors:    controls.Summary.Error,
 	}
 	for _, g := range controls.Groups {
 		for _, check := range g.Checks {
@@ -163,9 +389,11 @@ func (controls *Controls) JUnit() ([]byte, error) {
 			switch check.State {
 			case FAIL:
 				tc.FailureMessage = &reporters.JUnitFailureMessage{Message: check.Remediation}
-			case WARN, INFO:
+			case ERROR:
+				tc.FailureMessage = &reporters.JUnitFailureMessage{Type: "error", Message: check.Reason}
+			case WARN, INFO, INCOMPLETE:
 				// WARN and INFO are two different versions of skipped tests. Either way it would be a false positive/negative to report
-				// it any other way.
+				// it any other way. INCOMPLETE checks didn't run at all.
 				tc.Skipped = &reporters.JUnitSkipped{}
 			case PASS:
 			default:
@@ -197,6 +425,10 @@ func summarize(controls *Controls, state State) {
 		controls.Summary.Warn++
 	case INFO:
 		controls.Summary.Info++
+	case INCOMPLETE:
+		controls.Summary.Incomplete++
+	case ERROR:
+		controls.Summary.Error++
 	default:
 		glog.Warningf("Unrecognized state %s", state)
 	}
@@ -212,6 +444,10 @@ func summarizeGroup(group *Group, state State) {
 		group.Warn++
 	case INFO:
 		group.Info++
+	case INCOMPLETE:
+		group.Incomplete++
+	case ERROR:
+		group.Error++
 	default:
 		glog.Warningf("Unrecognized state %s", state)
 	}
@@ -93,6 +93,32 @@ groups:
 		assert.EqualError(t, err, "failed to unmarshal YAML: yaml: unmarshal errors:\n  line 1: cannot unmarshal !!str `BOOM` into check.Controls")
 	})
 
+	t.Run("Should load impact and default value of checks", func(t *testing.T) {
+		// given
+		in := []byte(`
+---
+type: "master"
+groups:
+- id: G1
+  checks:
+  - id: G1/C1
+    impact: "Anonymous requests will be rejected."
+    default_value: "By default, anonymous access is enabled."
+`)
+		// when
+		controls, err := NewControls(MASTER, in)
+		// then
+		assert.NoError(t, err)
+		c := controls.Groups[0].Checks[0]
+		assert.Equal(t, "Anonymous requests will be rejected.", c.Impact)
+		assert.Equal(t, "By default, anonymous access is enabled.", c.DefaultValue)
+		// and
+		out, err := json.Marshal(c)
+		assert.NoError(t, err)
+		assert.Contains(t, string(out), `"impact":"Anonymous requests will be rejected."`)
+		assert.Contains(t, string(out), `"default_value":"By default, anonymous access is enabled."`)
+	})
+
 }
 
 func TestControls_RunChecks(t *testing.T) {
@@ -160,8 +186,116 @@ groups:
 		assert.Equal(t, 0, controls.Summary.Info)
 		assert.Equal(t, 0, controls.Summary.Warn)
 		// and
+		assert.Equal(t, Counts{Pass: 1, Total: 1}, G1.Summary)
+		assert.Equal(t, Counts{Fail: 1, Total: 1}, G2.Summary)
+		assert.Equal(t, Counts{Pass: 1, Fail: 1, Total: 2}, controls.Totals)
+		// and
 		runner.AssertExpectations(t)
 	})
+
+	t.Run("Should count incomplete and error checks", func(t *testing.T) {
+		// given
+		runner := new(mockRunner)
+		// and
+		controls, err := NewControls(NODE, []byte(`
+---
+type: "node"
+groups:
+- id: G1
+  checks:
+  - id: G1/C1
+  - id: G1/C2
+  - id: G1/C3
+`))
+		assert.NoError(t, err)
+		// and
+		runner.On("Run", controls.Groups[0].Checks[0]).Return(PASS)
+		runner.On("Run", controls.Groups[0].Checks[1]).Return(INCOMPLETE)
+		runner.On("Run", controls.Groups[0].Checks[2]).Return(ERROR)
+		// when
+		summary := controls.RunChecks(runner, func(group *Group, c *Check) bool { return true })
+		// then
+		assert.Equal(t, Summary{Pass: 1, Incomplete: 1, Error: 1}, summary)
+		assert.Equal(t, 1, controls.Groups[0].Incomplete)
+		assert.Equal(t, 1, controls.Groups[0].Error)
+		assert.Equal(t, Counts{Pass: 1, Incomplete: 1, Error: 1, Total: 3}, controls.Totals)
+	})
+}
+
+func TestControls_SetOwners(t *testing.T) {
+	in := []byte(`
+---
+type: "node"
+groups:
+- id: G1
+  owner: node-team
+  checks:
+  - id: G1/C1
+  - id: G1/C2
+    owner: security-team
+- id: G2
+  checks:
+  - id: G2/C1
+  - id: G2/C2
+`)
+	controls, err := NewControls(NODE, in)
+	assert.NoError(t, err)
+	assert.Equal(t, "node-team", controls.Groups[0].Checks[0].Owner, "checks inherit the owner of their group")
+	assert.Equal(t, "security-team", controls.Groups[0].Checks[1].Owner)
+	assert.Equal(t, "", controls.Groups[1].Checks[0].Owner)
+
+	controls.SetOwners(map[string]string{"G1": "platform-team", "G2/C2": "rbac-team"})
+	assert.Equal(t, "platform-team", controls.Groups[0].Owner)
+	assert.Equal(t, "platform-team", controls.Groups[0].Checks[0].Owner)
+	assert.Equal(t, "platform-team", controls.Groups[0].Checks[1].Owner)
+	assert.Equal(t, "", controls.Groups[1].Checks[0].Owner)
+	assert.Equal(t, "rbac-team", controls.Groups[1].Checks[1].Owner)
+}
+
+func TestControls_Overlay(t *testing.T) {
+	controls, err := NewControls(NODE, []byte(`
+---
+type: "node"
+text: "Node"
+groups:
+- id: G1
+  text: "Group 1"
+  checks:
+  - id: G1/C1
+    audit: "echo base"
+  - id: G1/C2
+`))
+	assert.NoError(t, err)
+	overlay, err := NewControls(NODE, []byte(`
+---
+type: "node"
+groups:
+- id: G2
+  text: "Group 2"
+  checks:
+  - id: G1/C2
+    type: "skip"
+  - id: G2/C1
+- id: G1
+  checks:
+  - id: G1/C3
+`))
+	assert.NoError(t, err)
+
+	controls.Overlay(overlay)
+	assert.Equal(t, "Node", controls.Text, "unset fields are kept")
+	assert.Len(t, controls.Groups, 2)
+	g1, g2 := controls.Groups[0], controls.Groups[1]
+	assert.Equal(t, "Group 1", g1.Text)
+	assert.Equal(t, "Group 2", g2.Text)
+
+	assert.Len(t, g1.Checks, 3)
+	assert.Equal(t, "echo base", g1.Checks[0].Audit)
+	assert.Equal(t, "G1/C2", g1.Checks[1].ID)
+	assert.Equal(t, "skip", g1.Checks[1].Type, "checks are replaced where they are")
+	assert.Equal(t, "G1/C3", g1.Checks[2].ID)
+	assert.Len(t, g2.Checks, 1)
+	assert.Equal(t, "G2/C1", g2.Checks[0].ID)
 }
 
 func TestControls_JUnitIncludesJSON(t *testing.T) {
@@ -184,7 +318,7 @@ func TestControls_JUnitIncludesJSON(t *testing.T) {
 			},
 			expect: []byte(`<testsuite name="" tests="0" failures="0" errors="0" time="0">
     <testcase name="check1id check1text" classname="" time="0">
-        <system-out>{&#34;test_number&#34;:&#34;check1id&#34;,&#34;test_desc&#34;:&#34;check1text&#34;,&#34;audit&#34;:&#34;&#34;,&#34;AuditConfig&#34;:&#34;&#34;,&#34;type&#34;:&#34;&#34;,&#34;remediation&#34;:&#34;&#34;,&#34;test_info&#34;:null,&#34;status&#34;:&#34;PASS&#34;,&#34;actual_value&#34;:&#34;&#34;,&#34;scored&#34;:false,&#34;expected_result&#34;:&#34;&#34;}</system-out>
+        <system-out>{&#34;test_number&#34;:&#34;check1id&#34;,&#34;test_desc&#34;:&#34;check1text&#34;,&#34;audit&#34;:&#34;&#34;,&#34;AuditConfig&#34;:&#34;&#34;,&#34;type&#34;:&#34;&#34;,&#34;remediation&#34;:&#34;&#34;,&#34;test_info&#34;:null,&#34;status&#34;:&#34;PASS&#34;,&#34;actual_value&#34;:&#34;&#34;,&#34;scored&#34;:false,&#34;expected_result&#34;:&#34;&#34;,&#34;expected&#34;:&#34;&#34;}</system-out>
     </testcase>
 </testsuite>`),
 		}, {
@@ -207,7 +341,7 @@ func TestControls_JUnitIncludesJSON(t *testing.T) {
 			},
 			expect: []byte(`<testsuite name="" tests="402" failures="99" errors="0" time="0">
     <testcase name="check1id check1text" classname="" time="0">
-        <system-out>{&#34;test_number&#34;:&#34;check1id&#34;,&#34;test_desc&#34;:&#34;check1text&#34;,&#34;audit&#34;:&#34;&#34;,&#34;AuditConfig&#34;:&#34;&#34;,&#34;type&#34;:&#34;&#34;,&#34;remediation&#34;:&#34;&#34;,&#34;test_info&#34;:null,&#34;status&#34;:&#34;PASS&#34;,&#34;actual_value&#34;:&#34;&#34;,&#34;scored&#34;:false,&#34;expected_result&#34;:&#34;&#34;}</system-out>
+        <system-out>{&#34;test_number&#34;:&#34;check1id&#34;,&#34;test_desc&#34;:&#34;check1text&#34;,&#34;audit&#34;:&#34;&#34;,&#34;AuditConfig&#34;:&#34;&#34;,&#34;type&#34;:&#34;&#34;,&#34;remediation&#34;:&#34;&#34;,&#34;test_info&#34;:null,&#34;status&#34;:&#34;PASS&#34;,&#34;actual_value&#34;:&#34;&#34;,&#34;scored&#34;:false,&#34;expected_result&#34;:&#34;&#34;,&#34;expected&#34;:&#34;&#34;}</system-out>
     </testcase>
 </testsuite>`),
 		}, {
@@ -227,19 +361,19 @@ func TestControls_JUnitIncludesJSON(t *testing.T) {
 			},
 			expect: []byte(`<testsuite name="" tests="0" failures="0" errors="0" time="0">
     <testcase name="check1id check1text" classname="" time="0">
-        <system-out>{&#34;test_number&#34;:&#34;check1id&#34;,&#34;test_desc&#34;:&#34;check1text&#34;,&#34;audit&#34;:&#34;&#34;,&#34;AuditConfig&#34;:&#34;&#34;,&#34;type&#34;:&#34;&#34;,&#34;remediation&#34;:&#34;&#34;,&#34;test_info&#34;:null,&#34;status&#34;:&#34;PASS&#34;,&#34;actual_value&#34;:&#34;&#34;,&#34;scored&#34;:false,&#34;expected_result&#34;:&#34;&#34;}</system-out>
+        <system-out>{&#34;test_number&#34;:&#34;check1id&#34;,&#34;test_desc&#34;:&#34;check1text&#34;,&#34;audit&#34;:&#34;&#34;,&#34;AuditConfig&#34;:&#34;&#34;,&#34;type&#34;:&#34;&#34;,&#34;remediation&#34;:&#34;&#34;,&#34;test_info&#34;:null,&#34;status&#34;:&#34;PASS&#34;,&#34;actual_value&#34;:&#34;&#34;,&#34;scored&#34;:false,&#34;expected_result&#34;:&#34;&#34;,&#34;expected&#34;:&#34;&#34;}</system-out>
     </testcase>
     <testcase name="check2id check2text" classname="" time="0">
         <skipped></skipped>
-        <system-out>{&#34;test_number&#34;:&#34;check2id&#34;,&#34;test_desc&#34;:&#34;check2text&#34;,&#34;audit&#34;:&#34;&#34;,&#34;AuditConfig&#34;:&#34;&#34;,&#34;type&#34;:&#34;&#34;,&#34;remediation&#34;:&#34;&#34;,&#34;test_info&#34;:null,&#34;status&#34;:&#34;INFO&#34;,&#34;actual_value&#34;:&#34;&#34;,&#34;scored&#34;:false,&#34;expected_result&#34;:&#34;&#34;}</system-out>
+        <system-out>{&#34;test_number&#34;:&#34;check2id&#34;,&#34;test_desc&#34;:&#34;check2text&#34;,&#34;audit&#34;:&#34;&#34;,&#34;AuditConfig&#34;:&#34;&#34;,&#34;type&#34;:&#34;&#34;,&#34;remediation&#34;:&#34;&#34;,&#34;test_info&#34;:null,&#34;status&#34;:&#34;INFO&#34;,&#34;actual_value&#34;:&#34;&#34;,&#34;scored&#34;:false,&#34;expected_result&#34;:&#34;&#34;,&#34;expected&#34;:&#34;&#34;}</system-out>
     </testcase>
     <testcase name="check3id check3text" classname="" time="0">
         <skipped></skipped>
-        <system-out>{&#34;test_number&#34;:&#34;check3id&#34;,&#34;test_desc&#34;:&#34;check3text&#34;,&#34;audit&#34;:&#34;&#34;,&#34;AuditConfig&#34;:&#34;&#34;,&#34;type&#34;:&#34;&#34;,&#34;remediation&#34;:&#34;&#34;,&#34;test_info&#34;:null,&#34;status&#34;:&#34;WARN&#34;,&#34;actual_value&#34;:&#34;&#34;,&#34;scored&#34;:false,&#34;expected_result&#34;:&#34;&#34;}</system-out>
+        <system-out>{&#34;test_number&#34;:&#34;check3id&#34;,&#34;test_desc&#34;:&#34;check3text&#34;,&#34;audit&#34;:&#34;&#34;,&#34;AuditConfig&#34;:&#34;&#34;,&#34;type&#34;:&#34;&#34;,&#34;remediation&#34;:&#34;&#34;,&#34;test_info&#34;:null,&#34;status&#34;:&#34;WARN&#34;,&#34;actual_value&#34;:&#34;&#34;,&#34;scored&#34;:false,&#34;expected_result&#34;:&#34;&#34;,&#34;expected&#34;:&#34;&#34;}</system-out>
     </testcase>
     <testcase name="check4id check4text" classname="" time="0">
         <failure type=""></failure>
-        <system-out>{&#34;test_number&#34;:&#34;check4id&#34;,&#34;test_desc&#34;:&#34;check4text&#34;,&#34;audit&#34;:&#34;&#34;,&#34;AuditConfig&#34;:&#34;&#34;,&#34;type&#34;:&#34;&#34;,&#34;remediation&#34;:&#34;&#34;,&#34;test_info&#34;:null,&#34;status&#34;:&#34;FAIL&#34;,&#34;actual_value&#34;:&#34;&#34;,&#34;scored&#34;:false,&#34;expected_result&#34;:&#34;&#34;}</system-out>
+        <system-out>{&#34;test_number&#34;:&#34;check4id&#34;,&#34;test_desc&#34;:&#34;check4text&#34;,&#34;audit&#34;:&#34;&#34;,&#34;AuditConfig&#34;:&#34;&#34;,&#34;type&#34;:&#34;&#34;,&#34;remediation&#34;:&#34;&#34;,&#34;test_info&#34;:null,&#34;status&#34;:&#34;FAIL&#34;,&#34;actual_value&#34;:&#34;&#34;,&#34;scored&#34;:false,&#34;expected_result&#34;:&#34;&#34;,&#34;expected&#34;:&#34;&#34;}</system-out>
     </testcase>
 </testsuite>`),
 		},
@@ -290,3 +424,170 @@ func assertEqualGroupSummary(t *testing.T, pass, fail, info, warn int, actual *G
 	assert.Equal(t, info, actual.Info)
 	assert.Equal(t, warn, actual.Warn)
 }
+
+func TestControls_OverrideExpectedValues(t *testing.T) {
+	controls, err := NewControls(NODE, []byte(`
+---
+type: "node"
+groups:
+- id: G1
+  checks:
+  - id: G1/C1
+    audit: "echo '--tls-cipher-suites=TLS_A,TLS_B --read-only-port=0'"
+    tests:
+      bin_op: and
+      test_items:
+      - flag: "--tls-cipher-suites"
+        set: true
+        compare:
+          op: valid_elements
+          value: "TLS_B,TLS_C"
+      - flag: "--read-only-port"
+        set: true
+        compare:
+          op: eq
+          value: 0
+  - id: G1/C2
+    audit: "echo 'readOnlyPort: 0'"
+    tests:
+      test_items:
+      - path: "{.readOnlyPort}"
+        set: true
+        compare:
+          op: eq
+          value: 10255
+`))
+	assert.NoError(t, err)
+
+	controls.OverrideExpectedValues(map[string]map[string]string{
+		"G1/C1": {"--tls-cipher-suites": "TLS_A,TLS_B"},
+		// Keys from viper are in lower case.
+		"G1/C2": {"{.readonlyport}": "0"},
+		"G9/C9": {"--missing": "1"},
+	}, "test")
+	var all Predicate = func(group *Group, c *Check) bool { return true }
+	controls.RunChecks(NewRunner(), all)
+
+	c1 := controls.Groups[0].Checks[0]
+	assert.Equal(t, PASS, c1.State)
+	assert.Equal(t, &ValueOverride{BenchmarkValue: "TLS_B,TLS_C", Source: "test"}, c1.TestResults[0].Override)
+	assert.Nil(t, c1.TestResults[1].Override)
+	assert.Contains(t, c1.Expected, "TLS_A,TLS_B")
+
+	c2 := controls.Groups[0].Checks[1]
+	assert.Equal(t, PASS, c2.State)
+	assert.Equal(t, "10255", c2.TestResults[0].Override.BenchmarkValue)
+}
+
+func TestControls_RunChecksSkipped(t *testing.T) {
+	controls, err := NewControls(NODE, []byte(`
+---
+type: "node"
+groups:
+- id: G1
+  checks:
+  - id: G1/C1
+    type: "skip"
+  - id: G1/C2
+    text: "Not selected"
+    type: "skip"
+`))
+	assert.NoError(t, err)
+
+	controls.RunChecks(NewRunner(), func(group *Group, c *Check) bool { return c.ID == "G1/C1" })
+	assert.Equal(t, []*SkippedCheck{{ID: "G1/C2", Text: "Not selected", Section: "G1", ReasonCode: ReasonFiltered, Reason: "Not selected to run"}}, controls.Skipped)
+	assert.Equal(t, ReasonSkip, controls.Groups[0].Checks[0].ReasonCode)
+}
+
+func TestControls_RunChecksCoverage(t *testing.T) {
+	controls, err := NewControls(NODE, []byte(`
+---
+type: "node"
+groups:
+- id: G1
+  text: "First"
+  checks:
+  - id: G1/C1
+    audit: "echo a"
+    tests:
+      test_items:
+      - flag: "a"
+        set: true
+    scored: true
+  - id: G1/C2
+    type: "manual"
+- id: G2
+  text: "Second"
+  checks:
+  - id: G2/C1
+    type: "skip"
+  - id: G2/C2
+    audit: "kube-bench-missing-command"
+    tests:
+      test_items:
+      - flag: "a"
+        set: true
+    scored: true
+  - id: G2/C3
+    type: "skip"
+`))
+	assert.NoError(t, err)
+
+	controls.RunChecks(NewRunner(), func(group *Group, c *Check) bool { return c.ID != "G2/C3" })
+	assert.Equal(t, CoverageCounts{Total: 5, Executed: 1, Manual: 1, Skipped: 1, NotEvaluated: 1, Filtered: 1}, controls.Coverage.CoverageCounts)
+	assert.Equal(t, float64(20), controls.Coverage.Percent())
+	assert.Equal(t, []*SectionCoverage{
+		{Section: "G1", Text: "First", CoverageCounts: CoverageCounts{Total: 2, Executed: 1, Manual: 1}},
+		{Section: "G2", Text: "Second", CoverageCounts: CoverageCounts{Total: 3, Skipped: 1, NotEvaluated: 1, Filtered: 1}},
+	}, controls.Coverage.Sections)
+}
+
+func TestControls_Annotate(t *testing.T) {
+	controls := &Controls{Groups: []*Group{{ID: "G1", Checks: []*Check{{ID: "G1/C1"}, {ID: "G1/C2"}}}}}
+
+	controls.Annotate(nil)
+	assert.Nil(t, controls.Groups[0].Checks[0].Annotations)
+
+	annotations := map[string]string{"ticket": "CHG-1"}
+	controls.Annotate(annotations)
+	for _, c := range controls.Groups[0].Checks {
+		assert.Equal(t, annotations, c.Annotations)
+	}
+}
+
+func TestControls_CheckRequirements(t *testing.T) {
+	controls, err := NewControls(NODE, []byte(`
+---
+type: "node"
+groups:
+- id: G1
+  checks:
+  - id: G1/C1
+    type: "skip"
+  - id: G1/C2
+    type: "skip"
+    requires:
+      proxymode: "iptables"
+  - id: G1/C3
+    type: "skip"
+    requires:
+      proxymode: "IPVS, nftables"
+  - id: G1/C4
+    type: "skip"
+    requires:
+      featuregates: "true"
+`))
+	assert.NoError(t, err)
+
+	controls.CheckRequirements(map[string]string{"proxymode": "ipvs"})
+	controls.RunChecks(NewRunner(), func(*Group, *Check) bool { return true })
+
+	checks := controls.Groups[0].Checks
+	assert.Equal(t, ReasonSkip, checks[0].ReasonCode)
+	assert.Equal(t, ReasonNotApplicable, checks[1].ReasonCode)
+	assert.Equal(t, INFO, checks[1].State)
+	assert.Equal(t, "Not applicable: proxymode is ipvs, the check requires iptables", checks[1].Reason)
+	assert.Equal(t, ReasonSkip, checks[2].ReasonCode)
+	assert.Equal(t, "Not applicable: featuregates is unknown, the check requires true", checks[3].Reason)
+	assert.Equal(t, 4, controls.Coverage.Skipped)
+}
@@ -0,0 +1,89 @@
+// Copyright © 2017-2020 Aqua Security Software Ltd. <info@aquasec.com>
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package check
+
+// CoverageCounts tells how many of the checks of the benchmark were actually
+// evaluated, so that a high pass rate can't hide the checks that never ran.
+type CoverageCounts struct {
+	Total int `json:"total"`
+	// Executed is the number of checks whose tests were evaluated against the
+	// output of their audit.
+	Executed int `json:"executed"`
+	// Manual is the number of checks left to be verified by hand.
+	Manual int `json:"manual"`
+	// Skipped is the number of checks of type skip, or whose requirements
+	// aren't met, not applicable to the cluster.
+	Skipped int `json:"skipped"`
+	// NotEvaluated is the number of checks which ran but couldn't be evaluated,
+	// because of a missing command, a failed audit or an interrupted scan.
+	NotEvaluated int `json:"not_evaluated"`
+	// Filtered is the number of checks not selected to run.
+	Filtered int `json:"filtered"`
+}
+
+// SectionCoverage is the coverage of the checks of a group.
+type SectionCoverage struct {
+	Section string `json:"section"`
+	Text    string `json:"desc"`
+	CoverageCounts
+}
+
+// Coverage is the coverage of the checks of the controls, overall and per
+// section.
+type Coverage struct {
+	CoverageCounts
+	Sections []*SectionCoverage `json:"sections"`
+}
+
+// Percent is the percentage of the checks which were executed.
+func (c CoverageCounts) Percent() float64 {
+	if c.Total == 0 {
+		return 0
+	}
+	return float64(c.Executed) * 100 / float64(c.Total)
+}
+
+func (c *CoverageCounts) add(code ReasonCode) {
+	c.Total++
+	switch code {
+	case "", ReasonUnscoredFailure:
+		c.Executed++
+	case ReasonManual:
+		c.Manual++
+	case ReasonSkip, ReasonNotApplicable:
+		c.Skipped++
+	case ReasonFiltered:
+		c.Filtered++
+	default:
+		c.NotEvaluated++
+	}
+}
+
+// add counts a check of group, given the reason code it ended with.
+func (c *Coverage) add(group *Group, code ReasonCode) {
+	var section *SectionCoverage
+	for _, s := range c.Sections {
+		if s.Section == group.ID {
+			section = s
+			break
+		}
+	}
+	if section == nil {
+		section = &SectionCoverage{Section: group.ID, Text: group.Text}
+		c.Sections = append(c.Sections, section)
+	}
+	section.add(code)
+	c.CoverageCounts.add(code)
+}
@@ -0,0 +1,101 @@
+// Copyright © 2017-2020 Aqua Security Software Ltd. <info@aquasec.com>
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package check
+
+import (
+	"encoding/json"
+	"fmt"
+	"strings"
+	"time"
+)
+
+// defectDojoNow is the time findings are dated with, replaced in tests.
+var defectDojoNow = time.Now
+
+// defectDojoFinding is a finding of the DefectDojo Generic Findings Import format.
+type defectDojoFinding struct {
+	Title          string `json:"title"`
+	Description    string `json:"description"`
+	Severity       string `json:"severity"`
+	Mitigation     string `json:"mitigation"`
+	Impact         string `json:"impact,omitempty"`
+	Date           string `json:"date"`
+	UniqueID       string `json:"unique_id_from_tool"`
+	VulnID         string `json:"vuln_id_from_tool"`
+	ComponentName  string `json:"component_name"`
+	StaticFinding  bool   `json:"static_finding"`
+	DynamicFinding bool   `json:"dynamic_finding"`
+}
+
+// DefectDojo encodes the failed and warning checks of the last run of controls
+// in the DefectDojo Generic Findings Import format.
+func (controls *Controls) DefectDojo() ([]byte, error) {
+	report := struct {
+		Findings []defectDojoFinding `json:"findings"`
+	}{Findings: []defectDojoFinding{}}
+
+	date := defectDojoNow().UTC().Format("2006-01-02")
+	for _, g := range controls.Groups {
+		for _, check := range g.Checks {
+			severity := defectDojoSeverity(check)
+			if severity == "" {
+				continue
+			}
+
+			var desc strings.Builder
+			fmt.Fprintf(&desc, "%s\n\nSection: %s %s\nStatus: %s\nScored: %t\n", check.Text, g.ID, g.Text, check.State, check.Scored)
+			if check.Audit != "" {
+				fmt.Fprintf(&desc, "Audit: %s\n", check.Audit)
+			}
+			if check.Expected != "" {
+				fmt.Fprintf(&desc, "Expected: %s\n", check.Expected)
+			}
+			if check.Reason != "" {
+				fmt.Fprintf(&desc, "Reason: %s\n", check.Reason)
+			}
+
+			report.Findings = append(report.Findings, defectDojoFinding{
+				Title:          fmt.Sprintf("%s %s", check.ID, check.Text),
+				Description:    desc.String(),
+				Severity:       severity,
+				Mitigation:     check.Remediation,
+				Impact:         check.Impact,
+				Date:           date,
+				UniqueID:       fmt.Sprintf("%s/%s/%s", controls.Version, controls.Type, check.ID),
+				VulnID:         check.ID,
+				ComponentName:  string(controls.Type),
+				StaticFinding:  true,
+				DynamicFinding: false,
+			})
+		}
+	}
+
+	return json.Marshal(report)
+}
+
+// defectDojoSeverity maps the state of a check to the severity of its finding,
+// or returns an empty string for checks that aren't reported as findings.
+func defectDojoSeverity(check *Check) string {
+	switch check.State {
+	case FAIL:
+		if check.Scored {
+			return "High"
+		}
+		return "Medium"
+	case WARN:
+		return "Low"
+	}
+	return ""
+}
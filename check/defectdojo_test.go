@@ -0,0 +1,67 @@
+// Copyright © 2017-2020 Aqua Security Software Ltd. <info@aquasec.com>
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package check
+
+import (
+	"encoding/json"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+)
+
+func TestControls_DefectDojo(t *testing.T) {
+	defectDojoNow = func() time.Time { return time.Date(2020, 3, 1, 12, 0, 0, 0, time.UTC) }
+	defer func() { defectDojoNow = time.Now }()
+
+	controls := &Controls{
+		Version: "cis-1.5",
+		Type:    NODE,
+		Groups: []*Group{{
+			ID:   "4.2",
+			Text: "Kubelet",
+			Checks: []*Check{
+				{ID: "4.2.1", Text: "anonymous-auth", State: FAIL, Scored: true, Remediation: "Set --anonymous-auth=false", Impact: "Anonymous requests will be rejected."},
+				{ID: "4.2.2", Text: "authorization-mode", State: FAIL},
+				{ID: "4.2.3", Text: "client-ca-file", State: WARN},
+				{ID: "4.2.4", Text: "read-only-port", State: PASS, Scored: true},
+				{ID: "4.2.5", Text: "streaming-connection-idle-timeout", State: INFO},
+			},
+		}},
+	}
+
+	out, err := controls.DefectDojo()
+	assert.NoError(t, err)
+
+	var report struct {
+		Findings []defectDojoFinding `json:"findings"`
+	}
+	assert.NoError(t, json.Unmarshal(out, &report))
+	assert.Len(t, report.Findings, 3)
+
+	f := report.Findings[0]
+	assert.Equal(t, "4.2.1 anonymous-auth", f.Title)
+	assert.Equal(t, "High", f.Severity)
+	assert.Equal(t, "Set --anonymous-auth=false", f.Mitigation)
+	assert.Equal(t, "Anonymous requests will be rejected.", f.Impact)
+	assert.Equal(t, "2020-03-01", f.Date)
+	assert.Equal(t, "cis-1.5/node/4.2.1", f.UniqueID)
+	assert.Equal(t, "Medium", report.Findings[1].Severity)
+	assert.Equal(t, "Low", report.Findings[2].Severity)
+
+	out, err = (&Controls{}).DefectDojo()
+	assert.NoError(t, err)
+	assert.Equal(t, `{"findings":[]}`, string(out))
+}
@@ -0,0 +1,129 @@
+// Copyright © 2017-2020 Aqua Security Software Ltd. <info@aquasec.com>
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package check
+
+import (
+	"bytes"
+	htmltemplate "html/template"
+	"strings"
+	"text/template"
+)
+
+// docFuncs are the functions of the documentation templates.
+var docFuncs = map[string]interface{}{
+	"kind":   docKind,
+	"trim":   strings.TrimSpace,
+	"indent": func(s string) string { return strings.Replace(strings.TrimSpace(s), "\n", "\n    ", -1) },
+}
+
+// docKind describes how a check is evaluated.
+func docKind(c *Check) string {
+	switch {
+	case c.Type == MANUAL:
+		return "Manual"
+	case c.Type == "skip":
+		return "Skipped"
+	case c.Scored:
+		return "Automated, scored"
+	default:
+		return "Automated, not scored"
+	}
+}
+
+var markdownDoc = template.Must(template.New("markdown").Funcs(docFuncs).Parse(
+	`# {{.ID}} {{.Text}}
+{{if .Version}}
+Benchmark version: {{.Version}}
+{{end}}{{range .Groups}}
+## {{.ID}} {{.Text}}
+{{range .Checks}}
+### {{.ID}} {{.Text}}
+
+*{{kind .}}*{{if .Owner}}, owned by {{.Owner}}{{end}}
+{{if .Audit}}
+**Audit:**
+
+    {{indent .Audit}}
+{{end}}{{if .AuditConfig}}
+**Audit config:**
+
+    {{indent .AuditConfig}}
+{{end}}{{if .Grep}}
+**Audit:** search {{.Grep.Path}} for ` + "`{{.Grep.Pattern}}`" + `
+{{end}}{{if .Expected}}
+**Expected result:** {{.Expected}}
+{{end}}{{if .Remediation}}
+**Remediation:**
+
+    {{indent .Remediation}}
+{{end}}{{if .Impact}}
+**Impact:** {{trim .Impact}}
+{{end}}{{if .DefaultValue}}
+**Default value:** {{trim .DefaultValue}}
+{{end}}{{end}}{{end}}`))
+
+var htmlDoc = htmltemplate.Must(htmltemplate.New("html").Funcs(docFuncs).Parse(
+	`<!DOCTYPE html>
+<html>
+<head>
+<meta charset="utf-8">
+<title>{{.ID}} {{.Text}}</title>
+</head>
+<body>
+<h1>{{.ID}} {{.Text}}</h1>
+{{if .Version}}<p>Benchmark version: {{.Version}}</p>
+{{end}}{{range .Groups}}<section id="{{.ID}}">
+<h2>{{.ID}} {{.Text}}</h2>
+{{range .Checks}}<article id="{{.ID}}">
+<h3>{{.ID}} {{.Text}}</h3>
+<p><em>{{kind .}}</em>{{if .Owner}}, owned by {{.Owner}}{{end}}</p>
+{{if .Audit}}<h4>Audit</h4>
+<pre>{{trim .Audit}}</pre>
+{{end}}{{if .AuditConfig}}<h4>Audit config</h4>
+<pre>{{trim .AuditConfig}}</pre>
+{{end}}{{if .Grep}}<h4>Audit</h4>
+<p>Search {{.Grep.Path}} for <code>{{.Grep.Pattern}}</code></p>
+{{end}}{{if .Expected}}<h4>Expected result</h4>
+<p>{{.Expected}}</p>
+{{end}}{{if .Remediation}}<h4>Remediation</h4>
+<pre>{{trim .Remediation}}</pre>
+{{end}}{{if .Impact}}<h4>Impact</h4>
+<p>{{trim .Impact}}</p>
+{{end}}{{if .DefaultValue}}<h4>Default value</h4>
+<p>{{trim .DefaultValue}}</p>
+{{end}}</article>
+{{end}}</section>
+{{end}}</body>
+</html>
+`))
+
+// Markdown documents the checks of the controls, with their audits and
+// remediations, in Markdown.
+func (controls *Controls) Markdown() ([]byte, error) {
+	var buf bytes.Buffer
+	if err := markdownDoc.Execute(&buf, controls); err != nil {
+		return nil, err
+	}
+	return buf.Bytes(), nil
+}
+
+// HTML documents the checks of the controls like Markdown, in an HTML page.
+func (controls *Controls) HTML() ([]byte, error) {
+	var buf bytes.Buffer
+	if err := htmlDoc.Execute(&buf, controls); err != nil {
+		return nil, err
+	}
+	return buf.Bytes(), nil
+}
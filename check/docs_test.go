@@ -0,0 +1,70 @@
+// Copyright © 2017-2020 Aqua Security Software Ltd. <info@aquasec.com>
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package check
+
+import (
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+)
+
+func TestControls_Docs(t *testing.T) {
+	controls, err := NewControls(NODE, []byte(`
+---
+id: 4
+text: "Worker Node Security Configuration"
+type: "node"
+version: 1.5
+groups:
+- id: 4.2
+  text: "Kubelet"
+  checks:
+  - id: 4.2.1
+    text: "Ensure that the --anonymous-auth argument is set to false (Scored)"
+    audit: "ps -ef | grep kubelet"
+    tests:
+      test_items:
+      - flag: "--anonymous-auth"
+        set: true
+        compare:
+          op: eq
+          value: false
+    remediation: |
+      Set authentication: anonymous: enabled to false.
+      Restart the kubelet service.
+    scored: true
+  - id: 4.2.2
+    text: "Ensure <that> policies are reviewed (Not Scored)"
+    type: "manual"
+    remediation: "Review the policies."
+`))
+	assert.NoError(t, err)
+
+	md, err := controls.Markdown()
+	assert.NoError(t, err)
+	assert.Contains(t, string(md), "# 4 Worker Node Security Configuration\n")
+	assert.Contains(t, string(md), "## 4.2 Kubelet\n")
+	assert.Contains(t, string(md), "### 4.2.1 Ensure that the --anonymous-auth argument is set to false (Scored)\n\n*Automated, scored*\n")
+	assert.Contains(t, string(md), "**Audit:**\n\n    ps -ef | grep kubelet\n")
+	assert.Contains(t, string(md), "**Expected result:** '--anonymous-auth' is equal to 'false'\n")
+	assert.Contains(t, string(md), "**Remediation:**\n\n    Set authentication: anonymous: enabled to false.\n    Restart the kubelet service.\n")
+	assert.Contains(t, string(md), "*Manual*\n")
+
+	html, err := controls.HTML()
+	assert.NoError(t, err)
+	assert.Contains(t, string(html), `<article id="4.2.1">`)
+	assert.Contains(t, string(html), "<pre>ps -ef | grep kubelet</pre>")
+	assert.Contains(t, string(html), "<h3>4.2.2 Ensure &lt;that&gt; policies are reviewed (Not Scored)</h3>")
+}
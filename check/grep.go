@@ -0,0 +1,219 @@
+// Copyright © 2017-2020 Aqua Security Software Ltd. <info@aquasec.com>
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package check
+
+import (
+	"bufio"
+	"fmt"
+	"io"
+	"os"
+	"path/filepath"
+	"regexp"
+	"strings"
+
+	"github.com/golang/glog"
+)
+
+const (
+	defaultGrepMaxBytes   = 64 * 1024 * 1024
+	defaultGrepMaxMatches = 1000
+	grepMaxLineLength     = 64 * 1024
+)
+
+// grepAudit searches files for the lines matching a pattern without running a
+// command, for checks on files that may be too large to load in memory, such
+// as audit logs or journal exports. The matching lines are the output the
+// tests of the check are evaluated against.
+//
+// grep:
+//
+//	path: /var/log/kubernetes/audit/*.log
+//	pattern: "\"verb\":\"delete\""
+//	ignore_case: (true|false)
+//	max_bytes: 67108864
+//	max_matches: 1000
+type grepAudit struct {
+	Path       string `yaml:"path"`
+	Pattern    string `yaml:"pattern"`
+	IgnoreCase bool   `yaml:"ignore_case"`
+	// MaxBytes is the number of bytes read from the files after which the search stops.
+	MaxBytes int64 `yaml:"max_bytes"`
+	// MaxMatches is the number of matching lines after which the search stops.
+	MaxMatches int `yaml:"max_matches"`
+}
+
+// grepResult holds the matching lines, and why the search stopped early if it did.
+type grepResult struct {
+	lines   []string
+	stopped string
+}
+
+func (g *grepAudit) run() (*grepResult, error) {
+	pattern := g.Pattern
+	if g.IgnoreCase {
+		pattern = "(?i)" + pattern
+	}
+	re, err := regexp.Compile(pattern)
+	if err != nil {
+		return nil, fmt.Errorf("invalid grep pattern %q: %v", g.Pattern, err)
+	}
+
+	files, err := filepath.Glob(g.Path)
+	if err != nil {
+		return nil, fmt.Errorf("invalid grep path %q: %v", g.Path, err)
+	}
+	if len(files) == 0 {
+		return nil, fmt.Errorf("no file matches %s", g.Path)
+	}
+
+	limit := g.MaxBytes
+	if limit <= 0 {
+		limit = defaultGrepMaxBytes
+	}
+	maxBytes, maxMatches := limit, g.MaxMatches
+	if maxMatches <= 0 {
+		maxMatches = defaultGrepMaxMatches
+	}
+
+	res := &grepResult{}
+	for i, file := range files {
+		f, err := os.Open(file)
+		if err != nil {
+			return nil, err
+		}
+		read, more, err := grepReader(io.LimitReader(f, maxBytes), re, maxMatches, res)
+		f.Close()
+		if err != nil {
+			return nil, fmt.Errorf("failed to read %s: %v", file, err)
+		}
+
+		maxBytes -= read
+		if len(res.lines) >= maxMatches {
+			// The search only stopped early if there was more to read.
+			if more || i < len(files)-1 {
+				res.stopped = fmt.Sprintf("stopped after %d matching lines", maxMatches)
+			}
+			break
+		}
+		if maxBytes <= 0 {
+			res.stopped = fmt.Sprintf("stopped after reading %d bytes", limit)
+			break
+		}
+	}
+	return res, nil
+}
+
+// grepReader appends the lines of r matching re to res until there are
+// maxMatches of them, and returns the number of bytes read and whether r has
+// more to read. Lines longer than grepMaxLineLength are truncated, so that a
+// file without newlines isn't loaded in memory at once.
+func grepReader(r io.Reader, re *regexp.Regexp, maxMatches int, res *grepResult) (int64, bool, error) {
+	br := bufio.NewReaderSize(r, grepMaxLineLength)
+	var read int64
+	for len(res.lines) < maxMatches {
+		line, isPrefix, err := br.ReadLine()
+		read += int64(len(line))
+		if err == io.EOF {
+			return read, false, nil
+		} else if err != nil {
+			return read, false, err
+		}
+
+		if re.Match(line) {
+			res.lines = append(res.lines, string(line))
+		}
+
+		// Skip the rest of a truncated line.
+		for isPrefix {
+			var rest []byte
+			rest, isPrefix, err = br.ReadLine()
+			read += int64(len(rest))
+			if err == io.EOF {
+				return read, false, nil
+			} else if err != nil {
+				return read, false, err
+			}
+		}
+		read++
+	}
+	_, err := br.Peek(1)
+	return read, err == nil, nil
+}
+
+// runGrep runs the tests of a check against the lines matched by its grep audit.
+// When the search stopped at its limits, the check is a WARN unless the lines
+// read already decide the result of its tests.
+func (c *Check) runGrep() State {
+	res, err := c.Grep.run()
+	if err != nil {
+		c.Reason = err.Error()
+		c.ReasonCode = ReasonGrepFailed
+		c.State = WARN
+		return c.State
+	}
+
+	output := strings.Join(res.lines, "\n")
+	c.tracef("grep %q in %s, matching lines:\n%s", c.Grep.Pattern, c.Grep.Path, output)
+	errmsgs := ""
+	finalOutput := c.Tests.execute(output)
+	c.traceTests(c.Tests, finalOutput)
+	if finalOutput == nil {
+		errmsgs = fmt.Sprintf("Final output is <<EMPTY>>. Failed to grep: %s\n", c.Grep.Path)
+	}
+	state := c.setResult(finalOutput, errmsgs, fmt.Sprintf("grep %q %s", c.Grep.Pattern, c.Grep.Path))
+	if res.stopped == "" {
+		return state
+	}
+
+	reason := fmt.Sprintf("grep of %s %s", c.Grep.Path, res.stopped)
+	glog.V(1).Info(fmt.Sprintf("Check.ID: %s %s", c.ID, reason))
+	if grepDecided(c.Tests, output, finalOutput) {
+		if c.Reason == "" {
+			c.Reason = reason
+		}
+		return state
+	}
+	c.Reason = reason + ", the lines which weren't read could change the result"
+	c.ReasonCode = ReasonGrepTruncated
+	c.State = WARN
+	return c.State
+}
+
+// grepDecided tells whether the lines matched by a grep audit that stopped at
+// its limits decide the result of the tests, whatever the lines which weren't
+// read. A flag found in the lines stays present with the same value, as its
+// first occurrence is compared, while a missing flag could be in the lines
+// which weren't read. The documents of path tests are only complete once all
+// the lines are read.
+func grepDecided(ts *tests, output string, finalOutput *testOutput) bool {
+	if ts == nil || finalOutput == nil || len(finalOutput.testResults) != len(ts.TestItems) {
+		return false
+	}
+
+	allDecided := true
+	for i, t := range ts.TestItems {
+		decided := t.Flag != "" && strings.Contains(output, t.Flag)
+		allDecided = allDecided && decided
+		if !decided {
+			continue
+		}
+		// A decided failure of an AND, or success of an OR, decides the result.
+		pass := finalOutput.testResults[i].Pass
+		if ts.BinOp == or && pass || ts.BinOp != or && !pass {
+			return true
+		}
+	}
+	return allDecided
+}
@@ -0,0 +1,152 @@
+// Copyright © 2017-2020 Aqua Security Software Ltd. <info@aquasec.com>
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package check
+
+import (
+	"io/ioutil"
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+)
+
+func TestGrepAudit(t *testing.T) {
+	dir, err := ioutil.TempDir("", "kube-bench-grep")
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer os.RemoveAll(dir)
+
+	ioutil.WriteFile(filepath.Join(dir, "audit-1.log"), []byte("{\"verb\":\"get\"}\n{\"verb\":\"DELETE\"}\n"), 0644)
+	ioutil.WriteFile(filepath.Join(dir, "audit-2.log"), []byte(strings.Repeat("x", 2*grepMaxLineLength)+"\n{\"verb\":\"delete\"}"), 0644)
+
+	cases := []struct {
+		name    string
+		grep    grepAudit
+		lines   []string
+		stopped string
+	}{
+		{
+			name:  "all files",
+			grep:  grepAudit{Path: filepath.Join(dir, "*.log"), Pattern: `"verb":"delete"`, IgnoreCase: true},
+			lines: []string{`{"verb":"DELETE"}`, `{"verb":"delete"}`},
+		},
+		{
+			name:    "max matches",
+			grep:    grepAudit{Path: filepath.Join(dir, "*.log"), Pattern: "verb", MaxMatches: 2},
+			lines:   []string{`{"verb":"get"}`, `{"verb":"DELETE"}`},
+			stopped: "stopped after 2 matching lines",
+		},
+		{
+			name:  "max matches at the end of the files",
+			grep:  grepAudit{Path: filepath.Join(dir, "audit-1.log"), Pattern: "verb", MaxMatches: 2},
+			lines: []string{`{"verb":"get"}`, `{"verb":"DELETE"}`},
+		},
+		{
+			name:    "max bytes",
+			grep:    grepAudit{Path: filepath.Join(dir, "*.log"), Pattern: "verb", MaxBytes: 20},
+			lines:   []string{`{"verb":"get"}`},
+			stopped: "stopped after reading 20 bytes",
+		},
+		{
+			name:  "long lines are skipped",
+			grep:  grepAudit{Path: filepath.Join(dir, "audit-2.log"), Pattern: "delete"},
+			lines: []string{`{"verb":"delete"}`},
+		},
+	}
+	for _, c := range cases {
+		t.Run(c.name, func(t *testing.T) {
+			res, err := c.grep.run()
+			assert.NoError(t, err)
+			assert.Equal(t, c.lines, res.lines)
+			assert.Equal(t, c.stopped, res.stopped)
+		})
+	}
+
+	_, err = (&grepAudit{Path: filepath.Join(dir, "missing.log"), Pattern: "verb"}).run()
+	assert.Error(t, err)
+	_, err = (&grepAudit{Path: filepath.Join(dir, "*.log"), Pattern: "("}).run()
+	assert.Error(t, err)
+}
+
+func TestCheck_RunGrep(t *testing.T) {
+	f, err := ioutil.TempFile("", "kube-bench-grep")
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer os.Remove(f.Name())
+	f.WriteString("audit-policy-file=/etc/kubernetes/audit.yaml\n")
+	f.Close()
+
+	c := &Check{
+		Scored: true,
+		Grep:   &grepAudit{Path: f.Name(), Pattern: "audit-policy-file"},
+		Tests:  &tests{TestItems: []*testItem{{Flag: "audit-policy-file", Set: true}}},
+	}
+	assert.Equal(t, PASS, c.run())
+	assert.Equal(t, "audit-policy-file=/etc/kubernetes/audit.yaml", c.ActualValue)
+
+	c.Grep.Path = f.Name() + ".missing"
+	assert.Equal(t, WARN, c.run())
+	assert.Contains(t, c.Reason, "no file matches")
+}
+
+func TestCheck_RunGrepTruncated(t *testing.T) {
+	f, err := ioutil.TempFile("", "kube-bench-grep")
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer os.Remove(f.Name())
+	f.WriteString(`{"verb":"get","resource":"pods"}` + "\n" + `{"verb":"delete","resource":"secrets"}` + "\n")
+	f.Close()
+
+	testCases := []struct {
+		name     string
+		tests    *tests
+		expected State
+		code     ReasonCode
+	}{
+		{"flag found", &tests{TestItems: []*testItem{{Flag: `"verb":"get"`, Set: true}}}, PASS, ""},
+		{"flag found, forbidden", &tests{TestItems: []*testItem{{Flag: `"verb":"get"`, Set: false}}}, FAIL, ""},
+		{"flag not read yet", &tests{TestItems: []*testItem{{Flag: `"verb":"delete"`, Set: true}}}, WARN, ReasonGrepTruncated},
+		{"flag not read yet, forbidden", &tests{TestItems: []*testItem{{Flag: `"verb":"delete"`, Set: false}}}, WARN, ReasonGrepTruncated},
+		{"failure decides an AND", &tests{TestItems: []*testItem{{Flag: `"verb":"get"`, Set: false}, {Flag: `"verb":"delete"`, Set: true}}}, FAIL, ""},
+		{"success decides an OR", &tests{BinOp: or, TestItems: []*testItem{{Flag: `"verb":"get"`, Set: true}, {Flag: `"verb":"delete"`, Set: true}}}, PASS, ""},
+		{"undecided OR", &tests{BinOp: or, TestItems: []*testItem{{Flag: `"verb":"get"`, Set: false}, {Flag: `"verb":"delete"`, Set: true}}}, WARN, ReasonGrepTruncated},
+	}
+	for _, tc := range testCases {
+		t.Run(tc.name, func(t *testing.T) {
+			c := &Check{
+				Scored: true,
+				Grep:   &grepAudit{Path: f.Name(), Pattern: "verb", MaxMatches: 1},
+				Tests:  tc.tests,
+			}
+			assert.Equal(t, tc.expected, c.run())
+			assert.Equal(t, tc.code, c.ReasonCode)
+			assert.Contains(t, c.Reason, "stopped after 1 matching lines")
+		})
+	}
+
+	// The search doesn't stop when all the lines are read.
+	c := &Check{
+		Scored: true,
+		Grep:   &grepAudit{Path: f.Name(), Pattern: "verb", MaxMatches: 2},
+		Tests:  &tests{TestItems: []*testItem{{Flag: `"verb":"delete"`, Set: true}}},
+	}
+	assert.Equal(t, PASS, c.run())
+	assert.Empty(t, c.Reason)
+}
@@ -0,0 +1,101 @@
+// Copyright © 2017-2020 Aqua Security Software Ltd. <info@aquasec.com>
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package check
+
+import (
+	"fmt"
+	"os/exec"
+	"strings"
+	"sync"
+	"time"
+)
+
+// Limits are the resources each audit command may use. A zero value means no limit.
+type Limits struct {
+	// Timeout is the wall-clock time after which the commands of an audit are killed.
+	Timeout time.Duration
+	// CPU is the CPU time a command may use, rounded up to the second.
+	CPU time.Duration
+	// Memory is the virtual memory a command may use, in bytes.
+	Memory int64
+}
+
+// NewLimitedRunner constructs a Runner that runs the audit commands of the
+// checks within the given limits. A check whose audit exceeds them is in the
+// ERROR state.
+func NewLimitedRunner(limits Limits) Runner {
+	return &defaultRunner{limits: limits}
+}
+
+func (l Limits) enabled() bool {
+	return l.Timeout > 0 || l.CPU > 0 || l.Memory > 0
+}
+
+// wrap returns a command that runs cmd under the CPU and memory limits. They
+// are set with the ulimit builtin of the shell, which applies setrlimit to the
+// shell before it execs the command.
+func (l Limits) wrap(cmd *exec.Cmd) *exec.Cmd {
+	var ulimits []string
+	if l.CPU > 0 {
+		ulimits = append(ulimits, fmt.Sprintf("ulimit -t %d", int64((l.CPU+time.Second-1)/time.Second)))
+	}
+	if l.Memory > 0 {
+		ulimits = append(ulimits, fmt.Sprintf("ulimit -v %d", (l.Memory+1023)/1024))
+	}
+	if len(ulimits) == 0 {
+		return cmd
+	}
+
+	script := strings.Join(append(ulimits, `exec "$0" "$@"`), " && ")
+	return exec.Command("/bin/sh", append([]string{"-c", script, cmd.Path}, cmd.Args[1:]...)...)
+}
+
+// watchdog kills the commands of an audit once the timeout has elapsed.
+type watchdog struct {
+	mu      sync.Mutex
+	timer   *time.Timer
+	expired bool
+}
+
+func startWatchdog(timeout time.Duration, commands []*exec.Cmd) *watchdog {
+	w := &watchdog{}
+	if timeout <= 0 {
+		return w
+	}
+
+	w.timer = time.AfterFunc(timeout, func() {
+		w.mu.Lock()
+		defer w.mu.Unlock()
+		w.expired = true
+		for _, cmd := range commands {
+			if cmd.Process != nil {
+				cmd.Process.Kill()
+			}
+		}
+	})
+	return w
+}
+
+// stop stops the watchdog, and reports whether the timeout had elapsed.
+func (w *watchdog) stop() bool {
+	if w.timer == nil {
+		return false
+	}
+
+	w.timer.Stop()
+	w.mu.Lock()
+	defer w.mu.Unlock()
+	return w.expired
+}
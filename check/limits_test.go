@@ -0,0 +1,61 @@
+// Copyright © 2017-2020 Aqua Security Software Ltd. <info@aquasec.com>
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package check
+
+import (
+	"os/exec"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+)
+
+func TestLimits_Wrap(t *testing.T) {
+	cmd := exec.Command("/bin/echo", "a b")
+	assert.Equal(t, cmd, Limits{Timeout: time.Second}.wrap(cmd), "only the CPU and memory limits need a shell")
+
+	wrapped := Limits{CPU: 1500 * time.Millisecond, Memory: 64 * 1024 * 1024}.wrap(cmd)
+	assert.Equal(t, []string{"/bin/sh", "-c", `ulimit -t 2 && ulimit -v 65536 && exec "$0" "$@"`, "/bin/echo", "a b"}, wrapped.Args)
+
+	out, err := wrapped.Output()
+	assert.NoError(t, err)
+	assert.Equal(t, "a b\n", string(out))
+}
+
+func TestLimitedRunner(t *testing.T) {
+	newCheck := func(audit string) *Check {
+		return &Check{
+			ID:       "1.1.1",
+			Scored:   true,
+			Audit:    audit,
+			Commands: textToCommand(audit),
+			Tests:    &tests{TestItems: []*testItem{{Flag: "done", Set: true}}},
+		}
+	}
+
+	c := newCheck("echo done")
+	assert.Equal(t, PASS, NewLimitedRunner(Limits{Timeout: 5 * time.Second, CPU: 5 * time.Second, Memory: 256 * 1024 * 1024}).Run(c))
+
+	c = newCheck("sleep 10")
+	start := time.Now()
+	assert.Equal(t, ERROR, NewLimitedRunner(Limits{Timeout: 100 * time.Millisecond}).Run(c))
+	assert.Equal(t, "audit exceeded the time limit of 100ms", c.Reason)
+	assert.True(t, time.Since(start) < 5*time.Second, "the audit is killed")
+
+	c = newCheck("sleep 10")
+	c.Commands = []*exec.Cmd{exec.Command("/bin/sh", "-c", "while :; do :; done")}
+	assert.Equal(t, ERROR, NewLimitedRunner(Limits{CPU: time.Second, Timeout: 10 * time.Second}).Run(c))
+	assert.Equal(t, "audit command /bin/sh exceeded the CPU time limit of 1s", c.Reason)
+}
@@ -0,0 +1,45 @@
+//go:build !windows
+// +build !windows
+
+// Copyright © 2017-2020 Aqua Security Software Ltd. <info@aquasec.com>
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package check
+
+import (
+	"fmt"
+	"os/exec"
+	"syscall"
+)
+
+// limitBreach describes how a command that failed exceeded its CPU or memory
+// limits, or returns an empty string if it didn't.
+func (l Limits) limitBreach(err error) string {
+	exitErr, ok := err.(*exec.ExitError)
+	if !ok {
+		return ""
+	}
+	status, ok := exitErr.Sys().(syscall.WaitStatus)
+	if !ok || !status.Signaled() {
+		return ""
+	}
+
+	switch sig := status.Signal(); {
+	case l.CPU > 0 && (sig == syscall.SIGXCPU || sig == syscall.SIGKILL):
+		return fmt.Sprintf("exceeded the CPU time limit of %v", l.CPU)
+	case l.Memory > 0 && (sig == syscall.SIGSEGV || sig == syscall.SIGABRT || sig == syscall.SIGKILL):
+		return fmt.Sprintf("killed by %v, likely after exceeding the memory limit of %d bytes", sig, l.Memory)
+	}
+	return ""
+}
@@ -0,0 +1,337 @@
+// Copyright © 2017-2020 Aqua Security Software Ltd. <info@aquasec.com>
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package check
+
+import (
+	"fmt"
+	"regexp"
+	"strconv"
+	"strings"
+
+	"github.com/golang/glog"
+	"gopkg.in/yaml.v2"
+)
+
+var (
+	yamlErrorLine    = regexp.MustCompile(`^(?:yaml: )?line (\d+): (.*)$`)
+	yamlUnknownField = regexp.MustCompile(`^field (\S+) not found in type \S+$`)
+	yamlItemID       = regexp.MustCompile(`^(\s*)-\s+id:\s*(.*?)\s*$`)
+	yamlChecksKey    = regexp.MustCompile(`^(\s*)checks:\s*$`)
+)
+
+// LoadOptions tune how LoadControls parses a controls file.
+type LoadOptions struct {
+	// File is the name of the controls file, reported in errors.
+	File string
+	// AllowUnknownFields logs unknown fields as warnings instead of failing.
+	AllowUnknownFields bool
+	// Strict fails on the groups and checks with errors, instead of leaving
+	// them out of the run.
+	Strict bool
+}
+
+// ParseError is an error in a controls file, located by line and by the ID of
+// the group or check it was found in.
+type ParseError struct {
+	File string
+	Line int
+	// Kind is "group" or "check" when the error was found in one.
+	Kind string
+	ID   string
+	Msg  string
+}
+
+func (e *ParseError) Error() string {
+	var b strings.Builder
+	if e.File != "" {
+		b.WriteString(e.File)
+		b.WriteString(":")
+	}
+	if e.Line > 0 {
+		fmt.Fprintf(&b, "%d:", e.Line)
+	}
+	if b.Len() > 0 {
+		b.WriteString(" ")
+	}
+	if e.Kind != "" {
+		fmt.Fprintf(&b, "%s %s: ", e.Kind, e.ID)
+	}
+	b.WriteString(e.Msg)
+	return b.String()
+}
+
+// ParseErrors are all the errors found in a controls file.
+type ParseErrors []*ParseError
+
+func (errs ParseErrors) Error() string {
+	msgs := make([]string, len(errs))
+	for i, e := range errs {
+		msgs[i] = e.Error()
+	}
+	return strings.Join(msgs, "\n")
+}
+
+// controlsFile is the layout of a controls file, which starts with an empty
+// controls key.
+type controlsFile struct {
+	Header   interface{} `yaml:"controls"`
+	Controls `yaml:",inline"`
+}
+
+// yamlItem is the position of a group or check in a controls file.
+type yamlItem struct {
+	kind string
+	id   string
+	line int
+}
+
+// LoadControls instantiates the Controls of a controls file like NewControls,
+// but reports unknown fields unless opts allow them, and validates the groups
+// and checks. Instead of stopping at the first one, it finds all the errors,
+// each located by line and by group or check ID. The groups and checks they
+// are in are logged and left out of the run, their checks reported in ERROR,
+// so that the others still run; with opts.Strict, they are returned as
+// ParseErrors instead.
+func LoadControls(t NodeType, in []byte, opts LoadOptions) (*Controls, error) {
+	items := indexItems(string(in))
+	newError := func(line int, msg string) *ParseError {
+		e := &ParseError{File: opts.File, Line: line, Msg: msg}
+		for _, item := range items {
+			if item.line > line {
+				break
+			}
+			e.Kind, e.ID = item.kind, item.id
+		}
+		return e
+	}
+
+	var errs ParseErrors
+	f := new(controlsFile)
+	if err := yaml.UnmarshalStrict(in, f); err != nil {
+		msgs := []string{err.Error()}
+		if terr, ok := err.(*yaml.TypeError); ok {
+			msgs = terr.Errors
+		}
+		for _, msg := range msgs {
+			line := 0
+			if m := yamlErrorLine.FindStringSubmatch(msg); m != nil {
+				line, _ = strconv.Atoi(m[1])
+				msg = m[2]
+			}
+			if m := yamlUnknownField.FindStringSubmatch(msg); m != nil {
+				msg = fmt.Sprintf("unknown field %q", m[1])
+				if opts.AllowUnknownFields {
+					glog.Warning(newError(line, msg).Error())
+					continue
+				}
+			}
+			errs = append(errs, newError(line, msg))
+		}
+		// A syntax error leaves nothing worth validating.
+		if _, ok := err.(*yaml.TypeError); !ok {
+			return nil, errs
+		}
+	}
+
+	c := &f.Controls
+	if t != c.Type && (len(errs) == 0 || !opts.Strict) {
+		return nil, fmt.Errorf("non-%s controls file specified", t)
+	}
+
+	lineOf := func(kind, id string) int {
+		for _, item := range items {
+			if item.kind == kind && item.id == id {
+				return item.line
+			}
+		}
+		return 0
+	}
+	decodeErrs := len(errs)
+	errs = append(errs, validateControls(c, func(kind, id, msg string) *ParseError {
+		return &ParseError{File: opts.File, Line: lineOf(kind, id), Kind: kind, ID: id, Msg: msg}
+	})...)
+	if len(errs) > 0 && opts.Strict {
+		return nil, errs
+	}
+	for _, e := range errs {
+		glog.Warning(e.Error())
+	}
+	if err := dropInvalid(c, errs[:decodeErrs]); err != nil {
+		return nil, err
+	}
+
+	c.prepare()
+	return c, nil
+}
+
+// validateControls returns the errors in the groups and checks that would
+// otherwise only show when running them, or make them run wrongly. It leaves
+// out the empty groups and checks, and those without an ID, and replaces the
+// other checks with errors by invalid ones.
+func validateControls(c *Controls, newError func(kind, id, msg string) *ParseError) ParseErrors {
+	var errs ParseErrors
+	seen := make(map[string]bool)
+	groups := c.Groups[:0]
+	for i, group := range c.Groups {
+		if group == nil {
+			errs = append(errs, newError("", "", fmt.Sprintf("group %d is empty", i+1)))
+			continue
+		}
+		checks := group.Checks[:0]
+		for j, check := range group.Checks {
+			if check == nil {
+				errs = append(errs, newError("group", group.ID, fmt.Sprintf("check %d is empty", j+1)))
+				continue
+			}
+			if check.ID == "" {
+				errs = append(errs, newError("group", group.ID, fmt.Sprintf("check %d has no id", j+1)))
+				continue
+			}
+			var checkErrs ParseErrors
+			if seen[check.ID] {
+				checkErrs = append(checkErrs, newError("check", check.ID, "duplicate check id"))
+			}
+			seen[check.ID] = true
+			for _, msg := range validateCheck(check) {
+				checkErrs = append(checkErrs, newError("check", check.ID, msg))
+			}
+			if len(checkErrs) > 0 {
+				check = invalidCheck(check, checkErrs)
+				errs = append(errs, checkErrs...)
+			}
+			checks = append(checks, check)
+		}
+		group.Checks = checks
+		groups = append(groups, group)
+	}
+	c.Groups = groups
+	return errs
+}
+
+// dropInvalid replaces the checks that errs were found in, or that are in a
+// group errs were found in, by invalid ones. Errors outside of any group leave
+// nothing to run, and are returned.
+func dropInvalid(c *Controls, errs ParseErrors) error {
+	var fatal ParseErrors
+	for _, e := range errs {
+		if e.Kind == "" {
+			fatal = append(fatal, e)
+		}
+	}
+	if len(fatal) > 0 {
+		return fatal
+	}
+
+	for _, group := range c.Groups {
+		for i, check := range group.Checks {
+			var checkErrs ParseErrors
+			for _, e := range errs {
+				if (e.Kind == "check" && e.ID == check.ID) || (e.Kind == "group" && e.ID == group.ID) {
+					checkErrs = append(checkErrs, e)
+				}
+			}
+			if len(checkErrs) > 0 {
+				group.Checks[i] = invalidCheck(check, checkErrs)
+			}
+		}
+	}
+	return nil
+}
+
+// invalidCheck returns a check with the description of c which doesn't run,
+// but reports errs in ERROR.
+func invalidCheck(c *Check, errs ParseErrors) *Check {
+	var reasons []string
+	if c.invalid != "" {
+		reasons = append(reasons, c.invalid)
+	}
+	for _, e := range errs {
+		reasons = append(reasons, e.Error())
+	}
+	return &Check{
+		ID:           c.ID,
+		Text:         c.Text,
+		Remediation:  c.Remediation,
+		Impact:       c.Impact,
+		DefaultValue: c.DefaultValue,
+		Owner:        c.Owner,
+		Scored:       c.Scored,
+		invalid:      strings.Join(reasons, "; "),
+	}
+}
+
+func validateCheck(check *Check) []string {
+	var msgs []string
+	switch check.Type {
+	case "", MANUAL, "skip":
+	default:
+		msgs = append(msgs, fmt.Sprintf("unknown type %q", check.Type))
+	}
+
+	if check.Grep != nil {
+		if check.Grep.Path == "" {
+			msgs = append(msgs, "grep has no path")
+		}
+		if _, err := regexp.Compile(check.Grep.Pattern); err != nil {
+			msgs = append(msgs, fmt.Sprintf("invalid grep pattern: %v", err))
+		}
+	}
+
+	if check.Tests == nil {
+		return msgs
+	}
+	switch check.Tests.BinOp {
+	case "", and, or:
+	default:
+		msgs = append(msgs, fmt.Sprintf("unknown bin_op %q", check.Tests.BinOp))
+	}
+	for i, item := range check.Tests.TestItems {
+		if item == nil {
+			msgs = append(msgs, fmt.Sprintf("test item %d is empty", i+1))
+			continue
+		}
+		if op := item.Compare.Op; op != "" {
+			if _, ok := compareOpPatterns[op]; !ok {
+				msgs = append(msgs, fmt.Sprintf("test item %d has unknown compare op %q", i+1, op))
+			}
+		}
+	}
+	return msgs
+}
+
+// indexItems finds the lines of the groups and checks in a controls file,
+// from their id keys. Checks are the items listed under a checks key.
+func indexItems(in string) []yamlItem {
+	var items []yamlItem
+	checksIndent := -1
+	for i, line := range strings.Split(in, "\n") {
+		if m := yamlChecksKey.FindStringSubmatch(line); m != nil {
+			checksIndent = len(m[1])
+			continue
+		}
+		m := yamlItemID.FindStringSubmatch(line)
+		if m == nil {
+			continue
+		}
+		kind := "group"
+		if checksIndent >= 0 && len(m[1]) >= checksIndent {
+			kind = "check"
+		} else {
+			checksIndent = -1
+		}
+		items = append(items, yamlItem{kind: kind, id: strings.Trim(m[2], `'"`), line: i + 1})
+	}
+	return items
+}
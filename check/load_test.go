@@ -0,0 +1,156 @@
+// Copyright © 2017-2020 Aqua Security Software Ltd. <info@aquasec.com>
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package check
+
+import (
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+)
+
+const loadControlsYAML = `---
+controls:
+type: "master"
+groups:
+  - id: 1.1
+    text: "First group"
+    checks:
+      - id: 1.1.1
+        text: "First check"
+        audit: "echo foo"
+        tests:
+          test_items:
+            - flag: "foo"
+              set: true
+        scored: true
+
+      - id: 1.1.2
+        text: "Second check"
+        type: "manual"
+`
+
+func TestLoadControls(t *testing.T) {
+	t.Run("Should load valid controls", func(t *testing.T) {
+		controls, err := LoadControls(MASTER, []byte(loadControlsYAML), LoadOptions{File: "master.yaml"})
+		assert.NoError(t, err)
+		assert.Len(t, controls.Groups[0].Checks, 2)
+		assert.Len(t, controls.Groups[0].Checks[0].Commands, 1)
+	})
+
+	t.Run("Should return error when node type doesn't match", func(t *testing.T) {
+		_, err := LoadControls(NODE, []byte(loadControlsYAML), LoadOptions{File: "master.yaml"})
+		assert.EqualError(t, err, "non-node controls file specified")
+	})
+
+	t.Run("Should locate unknown fields", func(t *testing.T) {
+		in := loadControlsYAML + "        remedation: \"typo\"\n"
+		_, err := LoadControls(MASTER, []byte(in), LoadOptions{File: "master.yaml", Strict: true})
+		assert.EqualError(t, err, `master.yaml:20: check 1.1.2: unknown field "remedation"`)
+	})
+
+	t.Run("Should tolerate unknown fields when allowed", func(t *testing.T) {
+		in := loadControlsYAML + "        remedation: \"typo\"\n"
+		controls, err := LoadControls(MASTER, []byte(in), LoadOptions{File: "master.yaml", AllowUnknownFields: true})
+		assert.NoError(t, err)
+		assert.Equal(t, MANUAL, controls.Groups[0].Checks[1].Type)
+	})
+
+	t.Run("Should report all errors", func(t *testing.T) {
+		in := `---
+type: "master"
+groups:
+  - id: 1.1
+    checks:
+      - id: 1.1.1
+        scored: maybe
+        tests:
+          bin_op: xor
+          test_items:
+            - flag: "foo"
+              compare:
+                op: equals
+      - id: 1.1.1
+        type: "manul"
+  - id: 1.2
+    checks:
+      - text: "No ID"
+      -
+`
+		_, err := LoadControls(MASTER, []byte(in), LoadOptions{File: "master.yaml", Strict: true})
+		assert.EqualError(t, err, "master.yaml:7: check 1.1.1: cannot unmarshal !!str `maybe` into bool\n"+
+			`master.yaml:6: check 1.1.1: unknown bin_op "xor"`+"\n"+
+			`master.yaml:6: check 1.1.1: test item 1 has unknown compare op "equals"`+"\n"+
+			"master.yaml:6: check 1.1.1: duplicate check id\n"+
+			`master.yaml:6: check 1.1.1: unknown type "manul"`+"\n"+
+			"master.yaml:16: group 1.2: check 1 has no id\n"+
+			"master.yaml:16: group 1.2: check 2 is empty")
+	})
+
+	t.Run("Should run the valid checks and report the invalid ones", func(t *testing.T) {
+		in := loadControlsYAML + `        remedation: "typo"
+      - id: 1.1.3
+        text: "Third check"
+        tests:
+          test_items:
+            - flag: "foo"
+              compare:
+                op: equals
+      -
+  - id: 1.2
+    checks:
+      - id: 1.2.1
+        text: "Fourth check"
+        scored: maybe
+`
+		controls, err := LoadControls(MASTER, []byte(in), LoadOptions{File: "master.yaml"})
+		assert.NoError(t, err)
+		if assert.Len(t, controls.Groups, 2) {
+			assert.Len(t, controls.Groups[0].Checks, 3, "the empty check is left out")
+		}
+
+		summary := controls.RunChecks(NewRunner(), func(*Group, *Check) bool { return true })
+		assert.Equal(t, 1, summary.Pass)
+		assert.Equal(t, 3, summary.Error)
+
+		invalid := controls.Groups[0].Checks[1]
+		assert.Equal(t, ERROR, invalid.State)
+		assert.Equal(t, ReasonInvalidDefinition, invalid.ReasonCode)
+		assert.Equal(t, `master.yaml:20: check 1.1.2: unknown field "remedation"`, invalid.Reason)
+		assert.Equal(t, "Second check", invalid.Text)
+		assert.Equal(t, `master.yaml:21: check 1.1.3: test item 1 has unknown compare op "equals"`, controls.Groups[0].Checks[2].Reason)
+		assert.Equal(t, "master.yaml:33: check 1.2.1: cannot unmarshal !!str `maybe` into bool", controls.Groups[1].Checks[0].Reason)
+	})
+
+	t.Run("Should fail on errors outside of any group", func(t *testing.T) {
+		_, err := LoadControls(MASTER, []byte("---\ntype: \"master\"\ngroups: foo\n"), LoadOptions{File: "master.yaml"})
+		assert.Error(t, err)
+		assert.IsType(t, ParseErrors{}, err)
+	})
+
+	t.Run("Should locate syntax errors", func(t *testing.T) {
+		in := "---\ntype: \"master\"\ngroups:\n  - id: 1.1\n    checks:\n      - id: 1.1.1\n        text: \"unterminated\n"
+		_, err := LoadControls(MASTER, []byte(in), LoadOptions{File: "master.yaml"})
+		assert.Error(t, err)
+		assert.IsType(t, ParseErrors{}, err)
+		assert.Contains(t, err.Error(), "master.yaml:")
+		assert.Contains(t, err.Error(), "check 1.1.1: ")
+	})
+
+	t.Run("Should not panic on malformed input", func(t *testing.T) {
+		for _, in := range []string{"", "BOOM", "groups: foo", "groups: [~]", "groups: [{checks: [~, {id: x, tests: {test_items: [~]}}]}]", "[[[", "type: {a: b}"} {
+			assert.NotPanics(t, func() { _, _ = LoadControls(MASTER, []byte(in), LoadOptions{}) }, in)
+		}
+	})
+}
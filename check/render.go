@@ -0,0 +1,90 @@
+// Copyright © 2017-2020 Aqua Security Software Ltd. <info@aquasec.com>
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package check
+
+import (
+	"fmt"
+	"sort"
+	"sync"
+)
+
+// Renderer encodes the results of the last run of controls in an output format.
+type Renderer interface {
+	Render(controls *Controls) ([]byte, error)
+}
+
+// RendererFunc adapts an ordinary function to the Renderer interface.
+type RendererFunc func(controls *Controls) ([]byte, error)
+
+// Render calls f(controls).
+func (f RendererFunc) Render(controls *Controls) ([]byte, error) {
+	return f(controls)
+}
+
+var (
+	renderersMu sync.RWMutex
+	renderers   = make(map[string]Renderer)
+)
+
+// RegisterRenderer makes a Renderer available under the given format name.
+// It returns an error if a renderer is already registered with that name.
+func RegisterRenderer(name string, r Renderer) error {
+	renderersMu.Lock()
+	defer renderersMu.Unlock()
+
+	if r == nil {
+		return fmt.Errorf("renderer %q is nil", name)
+	}
+	if _, dup := renderers[name]; dup {
+		return fmt.Errorf("renderer %q is already registered", name)
+	}
+	renderers[name] = r
+	return nil
+}
+
+// GetRenderer returns the Renderer registered for the given format name.
+func GetRenderer(name string) (Renderer, error) {
+	renderersMu.RLock()
+	defer renderersMu.RUnlock()
+
+	r, ok := renderers[name]
+	if !ok {
+		return nil, fmt.Errorf("unknown output format %q, valid formats are %v", name, rendererNames())
+	}
+	return r, nil
+}
+
+// RendererNames returns the sorted names of all the registered renderers.
+func RendererNames() []string {
+	renderersMu.RLock()
+	defer renderersMu.RUnlock()
+
+	return rendererNames()
+}
+
+func rendererNames() []string {
+	names := make([]string, 0, len(renderers))
+	for name := range renderers {
+		names = append(names, name)
+	}
+	sort.Strings(names)
+	return names
+}
+
+func init() {
+	RegisterRenderer("json", RendererFunc((*Controls).JSON))
+	RegisterRenderer("junit", RendererFunc((*Controls).JUnit))
+	RegisterRenderer("defectdojo", RendererFunc((*Controls).DefectDojo))
+}
@@ -0,0 +1,71 @@
+// Copyright © 2017-2020 Aqua Security Software Ltd. <info@aquasec.com>
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package check
+
+import (
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+)
+
+func TestBuiltinRenderers(t *testing.T) {
+	controls := &Controls{ID: "1", Text: "Master", Groups: []*Group{{ID: "1.1", Checks: []*Check{{ID: "1.1.1", State: PASS}}}}}
+
+	cases := []struct {
+		name string
+		fn   func() ([]byte, error)
+	}{
+		{name: "json", fn: controls.JSON},
+		{name: "junit", fn: controls.JUnit},
+		{name: "defectdojo", fn: controls.DefectDojo},
+	}
+
+	for _, c := range cases {
+		t.Run(c.name, func(t *testing.T) {
+			r, err := GetRenderer(c.name)
+			assert.NoError(t, err)
+
+			got, err := r.Render(controls)
+			assert.NoError(t, err)
+			exp, _ := c.fn()
+			assert.Equal(t, string(exp), string(got))
+		})
+	}
+}
+
+func TestRegisterRenderer(t *testing.T) {
+	r := RendererFunc(func(controls *Controls) ([]byte, error) {
+		return []byte(controls.ID), nil
+	})
+
+	assert.NoError(t, RegisterRenderer("test-id", r))
+	defer func() {
+		renderersMu.Lock()
+		delete(renderers, "test-id")
+		renderersMu.Unlock()
+	}()
+
+	assert.Error(t, RegisterRenderer("test-id", r), "duplicate names are rejected")
+	assert.Error(t, RegisterRenderer("test-nil", nil), "nil renderers are rejected")
+	assert.Contains(t, RendererNames(), "test-id")
+
+	got, err := GetRenderer("test-id")
+	assert.NoError(t, err)
+	out, _ := got.Render(&Controls{ID: "5"})
+	assert.Equal(t, "5", string(out))
+
+	_, err = GetRenderer("missing")
+	assert.Error(t, err)
+}
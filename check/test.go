@@ -49,6 +49,8 @@ type testItem struct {
 	Value   string
 	Set     bool
 	Compare compare
+
+	override *ValueOverride
 }
 
 type compare struct {
@@ -60,6 +62,31 @@ type testOutput struct {
 	testResult     bool
 	actualResult   string
 	ExpectedResult string
+	flagValue      string
+	testResults    []*TestResult
+}
+
+// TestResult is the outcome of a single test item of a check.
+type TestResult struct {
+	Flag           string `json:"flag,omitempty"`
+	Path           string `json:"path,omitempty"`
+	Set            bool   `json:"set"`
+	Op             string `json:"op,omitempty"`
+	Value          string `json:"value,omitempty"`
+	ActualValue    string `json:"actual_value,omitempty"`
+	ExpectedResult string `json:"expected_result"`
+	Pass           bool   `json:"pass"`
+	// Override is set when the compared value was overridden.
+	Override *ValueOverride `json:"override,omitempty"`
+}
+
+// ValueOverride records that the value a test compares to was set outside of
+// the controls file.
+type ValueOverride struct {
+	// BenchmarkValue is the value of the controls file.
+	BenchmarkValue string `json:"benchmark_value"`
+	// Source is where the value in use was set.
+	Source string `json:"source"`
 }
 
 func failTestItem(s string) *testOutput {
@@ -138,17 +165,33 @@ func (t *testItem) execute(s string) *testOutput {
 		notset := !match
 		result.testResult = notset
 	}
+	result.flagValue = flagVal
 	return result
 }
 
+// compareOpPatterns describe the expected result of each compare operation,
+// formatted with the flag value and the compared value.
+var compareOpPatterns = map[string]string{
+	"eq":             "'%s' is equal to '%s'",
+	"noteq":          "'%s' is not equal to '%s'",
+	"gt":             "%s is greater than %s",
+	"gte":            "%s is greater or equal to %s",
+	"lt":             "%s is lower than %s",
+	"lte":            "%s is lower or equal to %s",
+	"has":            "'%s' has '%s'",
+	"nothave":        " '%s' not have '%s'",
+	"regex":          " '%s' matched by '%s'",
+	"valid_elements": "'%s' contains valid elements from '%s'",
+	"bitmask":        "bitmask '%s' AND '%s'",
+}
+
 func compareOp(tCompareOp string, flagVal string, tCompareValue string) (string, bool) {
 
-	expectedResultPattern := ""
+	expectedResultPattern := compareOpPatterns[tCompareOp]
 	testResult := false
 
 	switch tCompareOp {
 	case "eq":
-		expectedResultPattern = "'%s' is equal to '%s'"
 		value := strings.ToLower(flagVal)
 		// Do case insensitive comparaison for booleans ...
 		if value == "false" || value == "true" {
@@ -158,7 +201,6 @@ func compareOp(tCompareOp string, flagVal string, tCompareValue string) (string,
 		}
 
 	case "noteq":
-		expectedResultPattern = "'%s' is not equal to '%s'"
 		value := strings.ToLower(flagVal)
 		// Do case insensitive comparaison for booleans ...
 		if value == "false" || value == "true" {
@@ -175,43 +217,34 @@ func compareOp(tCompareOp string, flagVal string, tCompareValue string) (string,
 		}
 		switch tCompareOp {
 		case "gt":
-			expectedResultPattern = "%s is greater than %s"
 			testResult = a > b
 
 		case "gte":
-			expectedResultPattern = "%s is greater or equal to %s"
 			testResult = a >= b
 
 		case "lt":
-			expectedResultPattern = "%s is lower than %s"
 			testResult = a < b
 
 		case "lte":
-			expectedResultPattern = "%s is lower or equal to %s"
 			testResult = a <= b
 		}
 
 	case "has":
-		expectedResultPattern = "'%s' has '%s'"
 		testResult = strings.Contains(flagVal, tCompareValue)
 
 	case "nothave":
-		expectedResultPattern = " '%s' not have '%s'"
 		testResult = !strings.Contains(flagVal, tCompareValue)
 
 	case "regex":
-		expectedResultPattern = " '%s' matched by '%s'"
 		opRe := regexp.MustCompile(tCompareValue)
 		testResult = opRe.MatchString(flagVal)
 
 	case "valid_elements":
-		expectedResultPattern = "'%s' contains valid elements from '%s'"
 		s := splitAndRemoveLastSeparator(flagVal, defaultArraySeparator)
 		target := splitAndRemoveLastSeparator(tCompareValue, defaultArraySeparator)
 		testResult = allElementsValid(s, target)
 
 	case "bitmask":
-		expectedResultPattern = "bitmask '%s' AND '%s'"
 		requested, err := strconv.ParseInt(flagVal, 8, 64)
 		max, err := strconv.ParseInt(tCompareValue, 8, 64)
 		if err != nil {
@@ -300,6 +333,22 @@ func splitAndRemoveLastSeparator(s, sep string) []string {
 	return ts
 }
 
+// expected describes what the test item expects, from its definition only.
+func (t *testItem) expected() string {
+	name := t.Flag
+	if name == "" {
+		name = t.Path
+	}
+
+	if !t.Set {
+		return fmt.Sprintf("'%s' is not present", name)
+	}
+	if pattern, ok := compareOpPatterns[t.Compare.Op]; ok {
+		return fmt.Sprintf(pattern, name, t.Compare.Value)
+	}
+	return fmt.Sprintf("'%s' is present", name)
+}
+
 type tests struct {
 	TestItems []*testItem `yaml:"test_items"`
 	BinOp     binOp       `yaml:"bin_op"`
@@ -321,9 +370,22 @@ func (ts *tests) execute(s string) *testOutput {
 
 	expectedResultArr := make([]string, len(res))
 
+	finalOutput.testResults = make([]*TestResult, len(res))
+
 	for i, t := range ts.TestItems {
 		res[i] = *(t.execute(s))
 		expectedResultArr[i] = res[i].ExpectedResult
+		finalOutput.testResults[i] = &TestResult{
+			Flag:           t.Flag,
+			Path:           t.Path,
+			Set:            t.Set,
+			Op:             t.Compare.Op,
+			Value:          t.Compare.Value,
+			ActualValue:    res[i].flagValue,
+			ExpectedResult: res[i].ExpectedResult,
+			Pass:           res[i].testResult,
+			Override:       t.override,
+		}
 	}
 
 	var result bool
@@ -359,6 +421,23 @@ func (ts *tests) execute(s string) *testOutput {
 	return finalOutput
 }
 
+// expected describes what the tests expect, from their definition only.
+func (ts *tests) expected() string {
+	if ts == nil || len(ts.TestItems) == 0 {
+		return ""
+	}
+
+	items := make([]string, len(ts.TestItems))
+	for i, t := range ts.TestItems {
+		items[i] = t.expected()
+	}
+
+	if ts.BinOp == or {
+		return strings.Join(items, " OR ")
+	}
+	return strings.Join(items, " AND ")
+}
+
 func toNumeric(a, b string) (c, d int, err error) {
 	c, err = strconv.Atoi(strings.TrimSpace(a))
 	if err != nil {
@@ -17,6 +17,7 @@ package check
 import (
 	"io/ioutil"
 	"os"
+	"reflect"
 	"strings"
 	"testing"
 )
@@ -728,3 +729,63 @@ func TestToNumeric(t *testing.T) {
 		}
 	}
 }
+
+func TestTestsExpected(t *testing.T) {
+	cases := []struct {
+		tests    *tests
+		expected string
+	}{
+		{tests: nil, expected: ""},
+		{tests: &tests{}, expected: ""},
+		{
+			tests:    &tests{TestItems: []*testItem{{Flag: "--profiling", Set: false}}},
+			expected: "'--profiling' is not present",
+		},
+		{
+			tests: &tests{BinOp: or, TestItems: []*testItem{
+				{Flag: "--secure-port", Set: true, Compare: compare{Op: "gt", Value: "0"}},
+				{Flag: "--secure-port", Set: false},
+			}},
+			expected: "--secure-port is greater than 0 OR '--secure-port' is not present",
+		},
+		{
+			tests: &tests{TestItems: []*testItem{
+				{Path: "{.authentication.anonymous.enabled}", Set: true, Compare: compare{Op: "eq", Value: "false"}},
+				{Flag: "--client-ca-file", Set: true},
+			}},
+			expected: "'{.authentication.anonymous.enabled}' is equal to 'false' AND '--client-ca-file' is present",
+		},
+	}
+
+	for _, c := range cases {
+		if got := c.tests.expected(); got != c.expected {
+			t.Errorf("expected %q, got %q", c.expected, got)
+		}
+	}
+}
+
+func TestTestsExecuteResults(t *testing.T) {
+	ts := &tests{
+		TestItems: []*testItem{
+			{Flag: "--authorization-mode", Set: true, Compare: compare{Op: "has", Value: "RBAC"}},
+			{Flag: "--profiling", Set: true, Compare: compare{Op: "eq", Value: "false"}},
+			{Flag: "--basic-auth-file", Set: false},
+		},
+	}
+
+	out := ts.execute("kube-apiserver --authorization-mode=Node,RBAC --profiling=true")
+	if out.testResult {
+		t.Fatalf("expected the tests to fail")
+	}
+
+	expected := []*TestResult{
+		{Flag: "--authorization-mode", Set: true, Op: "has", Value: "RBAC", ActualValue: "Node,RBAC", ExpectedResult: "'Node,RBAC' has 'RBAC'", Pass: true},
+		{Flag: "--profiling", Set: true, Op: "eq", Value: "false", ActualValue: "true", ExpectedResult: "'true' is equal to 'false'", Pass: false},
+		{Flag: "--basic-auth-file", Set: false, ExpectedResult: "'--basic-auth-file' is not present", Pass: true},
+	}
+	if !reflect.DeepEqual(expected, out.testResults) {
+		for i := range out.testResults {
+			t.Errorf("test item %d: expected %+v, got %+v", i, expected[i], out.testResults[i])
+		}
+	}
+}
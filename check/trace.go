@@ -0,0 +1,81 @@
+// Copyright © 2017-2020 Aqua Security Software Ltd. <info@aquasec.com>
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package check
+
+import (
+	"fmt"
+	"io"
+	"strings"
+)
+
+// TraceCheck sets w as the Trace of the check with the given ID, and reports
+// whether the controls have such a check.
+func (controls *Controls) TraceCheck(id string, w io.Writer) bool {
+	found := false
+	for _, group := range controls.Groups {
+		for _, check := range group.Checks {
+			if check.ID == id {
+				check.Trace = w
+				found = true
+			}
+		}
+	}
+	return found
+}
+
+// tracef writes a step of the evaluation of the check to its Trace.
+func (c *Check) tracef(format string, args ...interface{}) {
+	if c.Trace == nil {
+		return
+	}
+	fmt.Fprintf(c.Trace, "[trace %s] %s\n", c.ID, strings.TrimRight(fmt.Sprintf(format, args...), "\n"))
+}
+
+// traceTests writes the evaluation of each test item of the check.
+func (c *Check) traceTests(ts *tests, out *testOutput) {
+	if c.Trace == nil || ts == nil || out == nil {
+		return
+	}
+
+	binOp := ts.BinOp
+	if binOp == "" {
+		binOp = and
+	}
+	c.tracef("%d test items, combined with %s", len(ts.TestItems), binOp)
+	for i, tr := range out.testResults {
+		c.tracef("test item %d: flag: %q, path: %q, set: %t, op: %q, value: %q", i+1, tr.Flag, tr.Path, tr.Set, tr.Op, tr.Value)
+		if tr.Override != nil {
+			c.tracef("test item %d: value overridden by %s, the benchmark value is %q", i+1, tr.Override.Source, tr.Override.BenchmarkValue)
+		}
+		c.tracef("test item %d: matched value %q, %s: %s", i+1, tr.ActualValue, tr.ExpectedResult, passFail(tr.Pass))
+	}
+	c.tracef("tests: %s", passFail(out.testResult))
+}
+
+// traceVerdict writes the state the check ended in, and why.
+func (c *Check) traceVerdict() {
+	if c.Reason != "" {
+		c.tracef("verdict: %s (%s)", c.State, strings.TrimSpace(c.Reason))
+		return
+	}
+	c.tracef("verdict: %s", c.State)
+}
+
+func passFail(pass bool) string {
+	if pass {
+		return "pass"
+	}
+	return "fail"
+}
@@ -0,0 +1,65 @@
+// Copyright © 2017-2020 Aqua Security Software Ltd. <info@aquasec.com>
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package check
+
+import (
+	"bytes"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+)
+
+func TestControls_TraceCheck(t *testing.T) {
+	controls, err := NewControls(MASTER, []byte(`
+---
+type: "master"
+groups:
+- id: 1.2
+  checks:
+  - id: 1.2.1
+    text: "Ensure that the --anonymous-auth argument is set to false (Not Scored)"
+    audit: "echo kube-apiserver --anonymous-auth=true"
+    tests:
+      test_items:
+      - flag: "--anonymous-auth"
+        set: true
+        compare:
+          op: eq
+          value: false
+  - id: 1.2.2
+    audit: "echo untraced"
+    type: "manual"
+`))
+	assert.NoError(t, err)
+
+	var trace bytes.Buffer
+	assert.True(t, controls.TraceCheck("1.2.1", &trace))
+	assert.False(t, controls.TraceCheck("9.9.9", &trace))
+	controls.RunChecks(NewRunner(), func(*Group, *Check) bool { return true })
+
+	assert.Equal(t, `[trace 1.2.1] check 1.2.1: Ensure that the --anonymous-auth argument is set to false (Not Scored)
+[trace 1.2.1] type: "", scored: false
+[trace 1.2.1] audit: echo kube-apiserver --anonymous-auth=true
+[trace 1.2.1] command 1: ["echo" "kube-apiserver" "--anonymous-auth=true"]
+[trace 1.2.1] output:
+kube-apiserver --anonymous-auth=true
+[trace 1.2.1] 1 test items, combined with and
+[trace 1.2.1] test item 1: flag: "--anonymous-auth", path: "", set: true, op: "eq", value: "false"
+[trace 1.2.1] test item 1: matched value "true", 'true' is equal to 'false': fail
+[trace 1.2.1] tests: fail
+[trace 1.2.1] verdict: WARN
+`, trace.String())
+	assert.Nil(t, controls.Groups[0].Checks[1].Trace)
+}
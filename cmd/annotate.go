@@ -0,0 +1,36 @@
+package cmd
+
+import (
+	"fmt"
+	"strings"
+
+	"github.com/spf13/viper"
+)
+
+var (
+	// annotateFlags are the key=value pairs of --annotate.
+	annotateFlags []string
+	// annotations are attached to the result of every check, so that the
+	// metadata of the pipeline running kube-bench travels with its findings.
+	annotations map[string]string
+)
+
+// getAnnotations merges the annotations map of the config with the pairs of
+// --annotate, which take precedence.
+func getAnnotations(v *viper.Viper, pairs []string) (map[string]string, error) {
+	m := make(map[string]string)
+	for key, value := range v.GetStringMapString("annotations") {
+		m[key] = value
+	}
+	for _, pair := range pairs {
+		i := strings.Index(pair, "=")
+		if i <= 0 {
+			return nil, fmt.Errorf("invalid annotation %q, annotations must be key=value", pair)
+		}
+		m[pair[:i]] = pair[i+1:]
+	}
+	if len(m) == 0 {
+		return nil, nil
+	}
+	return m, nil
+}
@@ -0,0 +1,25 @@
+package cmd
+
+import (
+	"testing"
+
+	"github.com/spf13/viper"
+	"github.com/stretchr/testify/assert"
+)
+
+func TestGetAnnotations(t *testing.T) {
+	v := viper.New()
+	m, err := getAnnotations(v, nil)
+	assert.NoError(t, err)
+	assert.Nil(t, m)
+
+	v.Set("annotations", map[string]string{"environment": "staging", "ticket": "CHG-1"})
+	m, err = getAnnotations(v, []string{"ticket=CHG-2", "build=a=b", "empty="})
+	assert.NoError(t, err)
+	assert.Equal(t, map[string]string{"environment": "staging", "ticket": "CHG-2", "build": "a=b", "empty": ""}, m)
+
+	_, err = getAnnotations(v, []string{"ticket"})
+	assert.EqualError(t, err, `invalid annotation "ticket", annotations must be key=value`)
+	_, err = getAnnotations(v, []string{"=CHG-2"})
+	assert.Error(t, err)
+}
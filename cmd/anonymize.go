@@ -0,0 +1,130 @@
+package cmd
+
+import (
+	"crypto/rand"
+	"crypto/sha256"
+	"encoding/hex"
+	"fmt"
+	"net"
+	"os"
+	"regexp"
+	"sort"
+	"strings"
+	"sync"
+
+	"github.com/aquasecurity/kube-bench/check"
+)
+
+var (
+	// homePathPattern matches the user name in home directory paths.
+	homePathPattern = regexp.MustCompile(`(/home|/Users)/([^/\s:'"]+)`)
+	// nodeFlagPattern matches the values of the flags naming a node.
+	nodeFlagPattern = regexp.MustCompile(`(--(?:hostname-override|node-name|node-ip|advertise-address|bind-address)[= ])([^\s,'"]+)`)
+	// ipCandidatePattern matches strings that may be IP addresses, which are checked with net.ParseIP.
+	ipCandidatePattern = regexp.MustCompile(`[0-9a-fA-F]*:[0-9a-fA-F:.]*[0-9a-fA-F]|\b\d{1,3}(?:\.\d{1,3}){3}\b`)
+
+	anonymizerOnce    sync.Once
+	defaultAnonymizer *anonymizer
+)
+
+// anonymizer replaces node names, IP addresses and user names with hashes, so
+// that results can be shared without revealing details of the environment.
+// The hashes are salted with a random value, so they are consistent within a
+// run, and different values stay distinguishable, but they can't be reversed.
+type anonymizer struct {
+	salt      []byte
+	hostnames []string
+}
+
+func newAnonymizer(hostnames ...string) *anonymizer {
+	a := &anonymizer{salt: make([]byte, 16)}
+	if _, err := rand.Read(a.salt); err != nil {
+		exitWithError(fmt.Errorf("failed to generate anonymization salt: %v", err))
+	}
+
+	for _, h := range hostnames {
+		if h == "" {
+			continue
+		}
+		a.hostnames = append(a.hostnames, h)
+		// Also match the short name of a fully qualified hostname.
+		if i := strings.Index(h, "."); i > 0 && net.ParseIP(h) == nil {
+			a.hostnames = append(a.hostnames, h[:i])
+		}
+	}
+	// Replace longer names first, so the short name doesn't match within the FQDN.
+	sort.Slice(a.hostnames, func(i, j int) bool { return len(a.hostnames[i]) > len(a.hostnames[j]) })
+
+	return a
+}
+
+// getAnonymizer returns the anonymizer of this run, which hides the hostname of the node.
+func getAnonymizer() *anonymizer {
+	anonymizerOnce.Do(func() {
+		hostname, _ := os.Hostname()
+		defaultAnonymizer = newAnonymizer(hostname)
+	})
+	return defaultAnonymizer
+}
+
+func (a *anonymizer) hash(kind, s string) string {
+	sum := sha256.Sum256(append(append([]byte{}, a.salt...), s...))
+	return kind + "-" + hex.EncodeToString(sum[:])[:8]
+}
+
+// anonymize returns s with the hostnames, node names, IP addresses and user
+// names of home directories replaced by hashes.
+func (a *anonymizer) anonymize(s string) string {
+	if s == "" {
+		return s
+	}
+
+	for _, h := range a.hostnames {
+		s = strings.Replace(s, h, a.hash("host", h), -1)
+	}
+
+	s = nodeFlagPattern.ReplaceAllStringFunc(s, func(m string) string {
+		parts := nodeFlagPattern.FindStringSubmatch(m)
+		if net.ParseIP(parts[2]) != nil {
+			return parts[1] + a.hash("ip", parts[2])
+		}
+		return parts[1] + a.hash("node", parts[2])
+	})
+
+	s = ipCandidatePattern.ReplaceAllStringFunc(s, func(m string) string {
+		if net.ParseIP(m) == nil {
+			return m
+		}
+		return a.hash("ip", m)
+	})
+
+	return homePathPattern.ReplaceAllStringFunc(s, func(m string) string {
+		parts := homePathPattern.FindStringSubmatch(m)
+		return parts[1] + "/" + a.hash("user", parts[2])
+	})
+}
+
+// anonymizeControls anonymizes every field of the results that may contain
+// details of the environment, such as the output of audit commands.
+func (a *anonymizer) anonymizeControls(controls *check.Controls) {
+	for _, g := range controls.Groups {
+		for _, c := range g.Checks {
+			c.Audit = a.anonymize(c.Audit)
+			c.AuditConfig = a.anonymize(c.AuditConfig)
+			c.ActualValue = a.anonymize(c.ActualValue)
+			c.ExpectedResult = a.anonymize(c.ExpectedResult)
+			c.Expected = a.anonymize(c.Expected)
+			c.Remediation = a.anonymize(c.Remediation)
+			c.Reason = a.anonymize(c.Reason)
+			for i := range c.TestInfo {
+				c.TestInfo[i] = a.anonymize(c.TestInfo[i])
+			}
+			for _, tr := range c.TestResults {
+				tr.Path = a.anonymize(tr.Path)
+				tr.Value = a.anonymize(tr.Value)
+				tr.ActualValue = a.anonymize(tr.ActualValue)
+				tr.ExpectedResult = a.anonymize(tr.ExpectedResult)
+			}
+		}
+	}
+}
@@ -0,0 +1,100 @@
+package cmd
+
+import (
+	"strings"
+	"sync"
+	"testing"
+	"time"
+
+	"github.com/aquasecurity/kube-bench/check"
+	"github.com/stretchr/testify/assert"
+)
+
+func TestAnonymize(t *testing.T) {
+	a := newAnonymizer("worker-1.example.com")
+
+	cases := []struct {
+		in     string
+		hidden []string
+		kept   []string
+	}{
+		{
+			in:     "root 1234 1 2 10:30 ? 00:00:12 /usr/bin/kubelet --hostname-override=ip-172-20-1-5 --node-ip=172.20.1.5 --anonymous-auth=false",
+			hidden: []string{"ip-172-20-1-5", "172.20.1.5"},
+			kept:   []string{"00:00:12", "10:30", "--anonymous-auth=false", "--hostname-override=node-", "--node-ip=ip-"},
+		},
+		{
+			in:     "connecting to worker-1.example.com and worker-1 on fe80::1ff:fe23:4567:890a and 10.0.0.1:6443",
+			hidden: []string{"worker-1", "fe80::1ff:fe23:4567:890a", "10.0.0.1"},
+			kept:   []string{":6443"},
+		},
+		{
+			in:     "/home/alice/.kube/config /Users/bob/kubeconfig /etc/kubernetes/admin.conf",
+			hidden: []string{"alice", "bob"},
+			kept:   []string{"/home/user-", "/.kube/config", "/Users/user-", "/etc/kubernetes/admin.conf"},
+		},
+		{
+			in:   "Ensure that the --kubelet-https argument is set to true v1.17.3",
+			kept: []string{"Ensure that the --kubelet-https argument is set to true v1.17.3"},
+		},
+	}
+
+	for _, c := range cases {
+		out := a.anonymize(c.in)
+		for _, h := range c.hidden {
+			assert.NotContains(t, out, h, out)
+		}
+		for _, k := range c.kept {
+			assert.Contains(t, out, k, out)
+		}
+	}
+
+	assert.Equal(t, a.anonymize("10.0.0.1"), a.anonymize("10.0.0.1"), "hashes are consistent within a run")
+	assert.NotEqual(t, a.anonymize("10.0.0.1"), a.anonymize("10.0.0.2"))
+	assert.NotEqual(t, a.anonymize("10.0.0.1"), newAnonymizer().anonymize("10.0.0.1"), "hashes are salted")
+}
+
+func TestAnonymizeControls(t *testing.T) {
+	a := newAnonymizer("node-a")
+	controls := &check.Controls{Groups: []*check.Group{{Checks: []*check.Check{{
+		ID:          "4.2.1",
+		Audit:       "cat /home/admin/kubelet.conf",
+		ActualValue: "node-a /usr/bin/kubelet --node-ip=192.168.0.10",
+		TestInfo:    []string{"on node-a"},
+		TestResults: []*check.TestResult{{ActualValue: "192.168.0.10"}},
+	}}}}}
+
+	a.anonymizeControls(controls)
+	c := controls.Groups[0].Checks[0]
+	for _, s := range []string{c.Audit, c.ActualValue, c.TestInfo[0], c.TestResults[0].ActualValue} {
+		assert.False(t, strings.Contains(s, "node-a") || strings.Contains(s, "admin") || strings.Contains(s, "192.168"), s)
+	}
+	assert.Equal(t, "4.2.1", c.ID)
+}
+
+func TestAnonymizedDedupKeys(t *testing.T) {
+	defer func(a bool) { anonymize = a }(anonymize)
+	defer func() { anonymizerOnce, defaultAnonymizer = sync.Once{}, nil }()
+	anonymize = true
+
+	c := &check.Check{ID: "4.2.1", Text: "anonymous-auth"}
+	controls := &check.Controls{Type: check.NODE}
+	issues := &issueExporter{cluster: "prod", node: "worker-1"}
+	scc := &sccExporter{resourceName: "cluster", node: "worker-1", now: time.Now}
+	snow := &serviceNowExporter{node: "worker-1"}
+
+	// Each run has its own salt, but the keys stay the same so that later runs
+	// update the issues, findings and records of the previous ones.
+	var keys [][]string
+	for run := 0; run < 2; run++ {
+		anonymizerOnce, defaultAnonymizer = sync.Once{}, nil
+		i := issues.issue(controls, c)
+		r := snow.record(controls, c)
+		keys = append(keys, []string{i.Key, scc.findingID(c), r["correlation_id"]})
+
+		assert.NotContains(t, i.Title+i.Body, "worker-1")
+		assert.NotContains(t, r["short_description"]+r["description"]+r["correlation_id"], "worker-1")
+		assert.NotContains(t, scc.finding(controls, c, "ACTIVE").SourceProperties["node"], "worker-1")
+	}
+	assert.Equal(t, keys[0], keys[1])
+}
@@ -0,0 +1,141 @@
+package cmd
+
+import (
+	"crypto/hmac"
+	"crypto/sha256"
+	"encoding/base64"
+	"encoding/json"
+	"fmt"
+	"net/http"
+	"strings"
+	"time"
+
+	"github.com/aquasecurity/kube-bench/check"
+	"github.com/golang/glog"
+	"github.com/spf13/viper"
+)
+
+const (
+	azureLogsAPIVersion = "2016-04-01"
+	azureLogsResource   = "/api/logs"
+)
+
+func init() {
+	exporterFactories["azureloganalytics"] = newAzureLogAnalyticsExporter
+}
+
+// azureLogRecord is the record of a check sent to Log Analytics.
+type azureLogRecord struct {
+	TimeGenerated string            `json:"TimeGenerated"`
+	ScanID        string            `json:"ScanID,omitempty"`
+	Cluster       string            `json:"Cluster,omitempty"`
+	Node          string            `json:"Node"`
+	NodeType      check.NodeType    `json:"NodeType"`
+	Benchmark     string            `json:"Benchmark"`
+	Section       string            `json:"Section"`
+	CheckID       string            `json:"CheckID"`
+	Description   string            `json:"Description"`
+	State         check.State       `json:"State"`
+	Scored        bool              `json:"Scored"`
+	Remediation   string            `json:"Remediation"`
+	Owner         string            `json:"Owner,omitempty"`
+	Annotations   map[string]string `json:"Annotations,omitempty"`
+}
+
+// azureLogAnalyticsExporter posts the results of the checks to an Azure Log
+// Analytics workspace, where Microsoft Sentinel can query them, with the HTTP
+// Data Collector API.
+type azureLogAnalyticsExporter struct {
+	workspaceID string
+	sharedKey   []byte
+	logType     string
+	url         string
+	cluster     string
+	node        string
+	now         func() time.Time
+}
+
+func newAzureLogAnalyticsExporter(v *viper.Viper) (Exporter, error) {
+	e := &azureLogAnalyticsExporter{
+		workspaceID: v.GetString("workspace_id"),
+		logType:     v.GetString("log_type"),
+		url:         strings.TrimSuffix(v.GetString("api_url"), "/"),
+		cluster:     v.GetString("cluster"),
+		node:        v.GetString("node"),
+		now:         time.Now,
+	}
+	if e.workspaceID == "" || v.GetString("shared_key") == "" {
+		return nil, fmt.Errorf("workspace_id and shared_key must be set")
+	}
+
+	key, err := base64.StdEncoding.DecodeString(v.GetString("shared_key"))
+	if err != nil {
+		return nil, fmt.Errorf("invalid shared_key: %v", err)
+	}
+	e.sharedKey = key
+
+	if e.logType == "" {
+		e.logType = "KubeBench"
+	}
+	if e.url == "" {
+		e.url = fmt.Sprintf("https://%s.ods.opinsights.azure.com", e.workspaceID)
+	}
+	if e.node == "" {
+		e.node = hostName()
+	}
+	return e, nil
+}
+
+func (e *azureLogAnalyticsExporter) Name() string { return "azureloganalytics" }
+
+func (e *azureLogAnalyticsExporter) Export(controls *check.Controls) error {
+	now := e.now().UTC()
+
+	var records []azureLogRecord
+	for _, g := range controls.Groups {
+		for _, c := range g.Checks {
+			records = append(records, azureLogRecord{
+				TimeGenerated: formatTime(now),
+				ScanID:        controls.ScanID,
+				Cluster:       e.cluster,
+				Node:          displayNode(e.node),
+				NodeType:      controls.Type,
+				Benchmark:     controls.Version,
+				Section:       g.ID,
+				CheckID:       c.ID,
+				Description:   c.Text,
+				State:         c.State,
+				Scored:        c.Scored,
+				Remediation:   c.Remediation,
+				Owner:         c.Owner,
+				Annotations:   c.Annotations,
+			})
+		}
+	}
+	if len(records) == 0 {
+		return nil
+	}
+
+	body, err := json.Marshal(records)
+	if err != nil {
+		return err
+	}
+
+	date := now.Format(http.TimeFormat)
+	header := http.Header{}
+	header.Set("Authorization", e.signature(len(body), date))
+	header.Set("Log-Type", e.logType)
+	header.Set("x-ms-date", date)
+	header.Set("time-generated-field", "TimeGenerated")
+
+	glog.V(2).Info(fmt.Sprintf("Sending %d records to Log Analytics workspace %s", len(records), e.workspaceID))
+	return doJSON(http.MethodPost, fmt.Sprintf("%s%s?api-version=%s", e.url, azureLogsResource, azureLogsAPIVersion), header, body, nil)
+}
+
+// signature returns the SharedKey authorization of a request of the Data Collector API.
+func (e *azureLogAnalyticsExporter) signature(contentLength int, date string) string {
+	toSign := fmt.Sprintf("POST\n%d\napplication/json\nx-ms-date:%s\n%s", contentLength, date, azureLogsResource)
+	mac := hmac.New(sha256.New, e.sharedKey)
+	mac.Write([]byte(toSign))
+	return fmt.Sprintf("SharedKey %s:%s", e.workspaceID, base64.StdEncoding.EncodeToString(mac.Sum(nil)))
+}
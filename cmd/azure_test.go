@@ -0,0 +1,56 @@
+package cmd
+
+import (
+	"crypto/hmac"
+	"crypto/sha256"
+	"encoding/base64"
+	"encoding/json"
+	"fmt"
+	"io/ioutil"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+	"time"
+
+	"github.com/spf13/viper"
+	"github.com/stretchr/testify/assert"
+)
+
+func TestAzureLogAnalyticsExporter(t *testing.T) {
+	key := []byte("secret-key")
+	var records []azureLogRecord
+	ts := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		assert.Equal(t, "/api/logs", r.URL.Path)
+		assert.Equal(t, azureLogsAPIVersion, r.URL.Query().Get("api-version"))
+		assert.Equal(t, "KubeBench", r.Header.Get("Log-Type"))
+		assert.Equal(t, "Wed, 01 Jan 2020 00:00:00 GMT", r.Header.Get("x-ms-date"))
+
+		body, _ := ioutil.ReadAll(r.Body)
+		mac := hmac.New(sha256.New, key)
+		fmt.Fprintf(mac, "POST\n%d\napplication/json\nx-ms-date:%s\n/api/logs", len(body), r.Header.Get("x-ms-date"))
+		assert.Equal(t, "SharedKey ws:"+base64.StdEncoding.EncodeToString(mac.Sum(nil)), r.Header.Get("Authorization"))
+
+		json.Unmarshal(body, &records)
+	}))
+	defer ts.Close()
+
+	v := viper.New()
+	v.Set("workspace_id", "ws")
+	v.Set("shared_key", base64.StdEncoding.EncodeToString(key))
+	v.Set("api_url", ts.URL)
+	v.Set("cluster", "aks-prod")
+	v.Set("node", "node-1")
+	e, err := newAzureLogAnalyticsExporter(v)
+	assert.NoError(t, err)
+	e.(*azureLogAnalyticsExporter).now = func() time.Time { return time.Date(2020, 1, 1, 0, 0, 0, 0, time.UTC) }
+
+	assert.NoError(t, e.Export(issueControls()))
+	assert.Len(t, records, 4)
+	assert.Equal(t, "aks-prod", records[0].Cluster)
+	assert.Equal(t, "4.2.1", records[0].CheckID)
+	assert.Equal(t, "2020-01-01T00:00:00Z", records[0].TimeGenerated)
+
+	v.Set("shared_key", "not base64!")
+	_, err = newAzureLogAnalyticsExporter(v)
+	assert.Error(t, err)
+}
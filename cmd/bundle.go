@@ -0,0 +1,386 @@
+package cmd
+
+import (
+	"archive/tar"
+	"bytes"
+	"compress/gzip"
+	"crypto/ed25519"
+	"crypto/rand"
+	"crypto/sha256"
+	"crypto/x509"
+	"encoding/hex"
+	"encoding/json"
+	"encoding/pem"
+	"fmt"
+	"io"
+	"io/ioutil"
+	"os"
+	"path/filepath"
+	"sort"
+	"strings"
+	"time"
+
+	"github.com/aquasecurity/kube-bench/check"
+	"github.com/golang/glog"
+	"github.com/spf13/cobra"
+)
+
+const (
+	bundleManifestFile  = "manifest.json"
+	bundleSignatureFile = "manifest.json.sig"
+)
+
+var (
+	bundleOutput     string
+	bundleSigningKey string
+	bundlePublicKey  string
+	bundleKeyPrefix  string
+)
+
+func init() {
+	RootCmd.AddCommand(bundleCmd)
+	bundleCmd.AddCommand(bundleVerifyCmd)
+	bundleCmd.AddCommand(bundleKeygenCmd)
+
+	bundleCmd.Flags().StringSliceP("targets", "s", []string{}, "Specify targets of the benchmark to run, as with the run command")
+	bundleCmd.Flags().StringVarP(&bundleOutput, "output", "o", "", "Path of the bundle (default kube-bench-<scan ID>.tar.gz)")
+	bundleCmd.Flags().StringVar(&bundleSigningKey, "signing-key", "", "PEM file of the ed25519 private key the bundle is signed with")
+	bundleVerifyCmd.Flags().StringVar(&bundlePublicKey, "public-key", "", "PEM file of the ed25519 public key the bundle was signed with")
+	bundleKeygenCmd.Flags().StringVarP(&bundleKeyPrefix, "output", "o", "kube-bench", "Prefix of the <prefix>.key and <prefix>.pub files written")
+}
+
+// bundleManifest describes the content of a bundle. It is signed, and holds the
+// hashes of all the other files of the bundle.
+type bundleManifest struct {
+	Tool      string            `json:"tool"`
+	Version   string            `json:"version"`
+	ScanID    string            `json:"scan_id"`
+	Benchmark string            `json:"benchmark"`
+	Host      string            `json:"host"`
+	Created   string            `json:"created"`
+	Files     map[string]string `json:"files"`
+	Cfg       map[string]string `json:"cfg"`
+}
+
+var bundleCmd = &cobra.Command{
+	Use:   "bundle",
+	Short: "Run tests and package the results in a signed bundle",
+	Long: `Run tests and package the results, the output of the audits, the hashes of the
+cfg files and metadata about kube-bench in a single signed tar file, to be moved
+out of air-gapped environments and checked with "kube-bench bundle verify".`,
+	Run: func(cmd *cobra.Command, args []string) {
+		targets, err := cmd.Flags().GetStringSlice("targets")
+		if err != nil {
+			exitWithError(fmt.Errorf("unable to get `targets` from command line :%v", err))
+		}
+		if bundleSigningKey == "" {
+			exitWithError(fmt.Errorf("--signing-key is required, create a key pair with `kube-bench bundle keygen`"))
+		}
+		key, err := loadBundlePrivateKey(bundleSigningKey)
+		if err != nil {
+			exitWithError(err)
+		}
+
+		benchmarkVersion := resolveBenchmark(targets)
+		yamlFiles, err := getTestYamlFiles(targets, benchmarkVersion)
+		if err != nil {
+			exitWithError(err)
+		}
+
+		var controls []*check.Controls
+		for _, r := range runTargets(yamlFiles, false) {
+			controls = append(controls, r.controls)
+		}
+
+		files, err := bundleFiles(controls)
+		if err != nil {
+			exitWithError(err)
+		}
+		cfgHashes, err := hashFiles(filepath.Join(cfgDir, benchmarkVersion))
+		if err != nil {
+			exitWithError(fmt.Errorf("failed to hash cfg files: %v", err))
+		}
+
+		manifest := &bundleManifest{
+			Tool:      "kube-bench",
+			Version:   KubeBenchVersion,
+			ScanID:    scanID,
+			Benchmark: benchmarkVersion,
+			Host:      nodeName(),
+			Created:   formatTime(time.Now()),
+			Cfg:       cfgHashes,
+		}
+
+		path := bundleOutput
+		if path == "" {
+			path = fmt.Sprintf("kube-bench-%s.tar.gz", scanID)
+		}
+		f, err := os.Create(path)
+		if err != nil {
+			exitWithError(fmt.Errorf("failed to create bundle: %v", err))
+		}
+		defer f.Close()
+
+		if err := writeBundle(f, files, manifest, key); err != nil {
+			exitWithError(fmt.Errorf("failed to write bundle: %v", err))
+		}
+		fmt.Printf("Bundle written to %s\n", path)
+	},
+}
+
+var bundleVerifyCmd = &cobra.Command{
+	Use:   "verify <bundle>",
+	Short: "Verify the signature and content of a bundle",
+	Args:  cobra.ExactArgs(1),
+	Run: func(cmd *cobra.Command, args []string) {
+		if bundlePublicKey == "" {
+			exitWithError(fmt.Errorf("--public-key is required"))
+		}
+		pub, err := loadBundlePublicKey(bundlePublicKey)
+		if err != nil {
+			exitWithError(err)
+		}
+
+		f, err := os.Open(args[0])
+		if err != nil {
+			exitWithError(fmt.Errorf("failed to open bundle: %v", err))
+		}
+		defer f.Close()
+
+		manifest, err := verifyBundle(f, pub)
+		if err != nil {
+			colorPrint(check.FAIL, fmt.Sprintf("Bundle %s is invalid: %v\n", args[0], err))
+			os.Exit(1)
+		}
+
+		colorPrint(check.PASS, fmt.Sprintf("Bundle %s is valid\n", args[0]))
+		fmt.Printf("Scan ID: %s\nBenchmark: %s\nHost: %s\nCreated: %s\nkube-bench version: %s\n",
+			manifest.ScanID, manifest.Benchmark, manifest.Host, manifest.Created, manifest.Version)
+	},
+}
+
+var bundleKeygenCmd = &cobra.Command{
+	Use:   "keygen",
+	Short: "Create a key pair to sign and verify bundles",
+	Run: func(cmd *cobra.Command, args []string) {
+		pub, priv, err := ed25519.GenerateKey(rand.Reader)
+		if err != nil {
+			exitWithError(fmt.Errorf("failed to generate key: %v", err))
+		}
+
+		privDER, err := x509.MarshalPKCS8PrivateKey(priv)
+		if err != nil {
+			exitWithError(err)
+		}
+		pubDER, err := x509.MarshalPKIXPublicKey(pub)
+		if err != nil {
+			exitWithError(err)
+		}
+
+		if err := ioutil.WriteFile(bundleKeyPrefix+".key", pem.EncodeToMemory(&pem.Block{Type: "PRIVATE KEY", Bytes: privDER}), 0600); err != nil {
+			exitWithError(err)
+		}
+		if err := ioutil.WriteFile(bundleKeyPrefix+".pub", pem.EncodeToMemory(&pem.Block{Type: "PUBLIC KEY", Bytes: pubDER}), 0644); err != nil {
+			exitWithError(err)
+		}
+		fmt.Printf("Keys written to %s.key and %s.pub\n", bundleKeyPrefix, bundleKeyPrefix)
+	},
+}
+
+// bundleFiles returns the files of the bundle: the JSON results of each target,
+// and the output of the audit of each check as evidence.
+func bundleFiles(controls []*check.Controls) (map[string][]byte, error) {
+	files := make(map[string][]byte)
+	for _, c := range controls {
+		out, err := c.JSON()
+		if err != nil {
+			return nil, fmt.Errorf("failed to output %s results in JSON format: %v", c.Type, err)
+		}
+		files[fmt.Sprintf("results/%s.json", c.Type)] = out
+
+		for _, g := range c.Groups {
+			for _, ch := range g.Checks {
+				if ch.Audit == "" && ch.ActualValue == "" {
+					continue
+				}
+				evidence := fmt.Sprintf("check: %s %s\nstatus: %s\naudit: %s\n\n%s\n", ch.ID, ch.Text, ch.State, ch.Audit, ch.ActualValue)
+				files[fmt.Sprintf("evidence/%s/%s.txt", c.Type, ch.ID)] = []byte(evidence)
+			}
+		}
+	}
+	return files, nil
+}
+
+// hashFiles returns the SHA-256 of every file under dir, keyed by relative path.
+func hashFiles(dir string) (map[string]string, error) {
+	hashes := make(map[string]string)
+	err := filepath.Walk(dir, func(path string, info os.FileInfo, err error) error {
+		if err != nil || info.IsDir() {
+			return err
+		}
+		data, err := ioutil.ReadFile(path)
+		if err != nil {
+			return err
+		}
+		rel, err := filepath.Rel(dir, path)
+		if err != nil {
+			return err
+		}
+		hashes[filepath.ToSlash(rel)] = sha256Hex(data)
+		return nil
+	})
+	return hashes, err
+}
+
+func sha256Hex(data []byte) string {
+	sum := sha256.Sum256(data)
+	return hex.EncodeToString(sum[:])
+}
+
+// writeBundle writes files as a gzipped tar together with the manifest,
+// completed with the hash of each file, and its signature.
+func writeBundle(w io.Writer, files map[string][]byte, manifest *bundleManifest, key ed25519.PrivateKey) error {
+	manifest.Files = make(map[string]string, len(files))
+	names := make([]string, 0, len(files))
+	for name, data := range files {
+		manifest.Files[name] = sha256Hex(data)
+		names = append(names, name)
+	}
+	sort.Strings(names)
+
+	m, err := json.MarshalIndent(manifest, "", "  ")
+	if err != nil {
+		return err
+	}
+
+	gz := gzip.NewWriter(w)
+	tw := tar.NewWriter(gz)
+	add := func(name string, data []byte) error {
+		hdr := &tar.Header{Name: name, Mode: 0644, Size: int64(len(data)), ModTime: time.Now()}
+		if err := tw.WriteHeader(hdr); err != nil {
+			return err
+		}
+		_, err := tw.Write(data)
+		return err
+	}
+
+	if err := add(bundleManifestFile, m); err != nil {
+		return err
+	}
+	if err := add(bundleSignatureFile, []byte(hex.EncodeToString(ed25519.Sign(key, m)))); err != nil {
+		return err
+	}
+	for _, name := range names {
+		if err := add(name, files[name]); err != nil {
+			return err
+		}
+	}
+
+	if err := tw.Close(); err != nil {
+		return err
+	}
+	return gz.Close()
+}
+
+// verifyBundle checks the signature of the manifest of a bundle, and that the
+// bundle holds exactly the files listed in the manifest, unmodified.
+func verifyBundle(r io.Reader, pub ed25519.PublicKey) (*bundleManifest, error) {
+	gz, err := gzip.NewReader(r)
+	if err != nil {
+		return nil, err
+	}
+	tr := tar.NewReader(gz)
+
+	files := make(map[string][]byte)
+	for {
+		hdr, err := tr.Next()
+		if err == io.EOF {
+			break
+		}
+		if err != nil {
+			return nil, err
+		}
+		var data bytes.Buffer
+		if _, err := io.Copy(&data, tr); err != nil {
+			return nil, err
+		}
+		files[hdr.Name] = data.Bytes()
+	}
+
+	m, ok := files[bundleManifestFile]
+	if !ok {
+		return nil, fmt.Errorf("%s is missing", bundleManifestFile)
+	}
+	sig, err := hex.DecodeString(strings.TrimSpace(string(files[bundleSignatureFile])))
+	if err != nil || !ed25519.Verify(pub, m, sig) {
+		return nil, fmt.Errorf("the signature of the manifest is invalid")
+	}
+
+	manifest := &bundleManifest{}
+	if err := json.Unmarshal(m, manifest); err != nil {
+		return nil, fmt.Errorf("failed to parse manifest: %v", err)
+	}
+
+	delete(files, bundleManifestFile)
+	delete(files, bundleSignatureFile)
+	for name, hash := range manifest.Files {
+		data, ok := files[name]
+		if !ok {
+			return nil, fmt.Errorf("%s is missing", name)
+		}
+		if sha256Hex(data) != hash {
+			return nil, fmt.Errorf("%s was modified", name)
+		}
+		delete(files, name)
+	}
+	for name := range files {
+		return nil, fmt.Errorf("%s is not listed in the manifest", name)
+	}
+
+	glog.V(1).Info(fmt.Sprintf("Verified bundle of scan %s with %d files", manifest.ScanID, len(manifest.Files)))
+	return manifest, nil
+}
+
+func loadBundlePrivateKey(path string) (ed25519.PrivateKey, error) {
+	block, err := readPEM(path)
+	if err != nil {
+		return nil, err
+	}
+	key, err := x509.ParsePKCS8PrivateKey(block.Bytes)
+	if err != nil {
+		return nil, fmt.Errorf("failed to parse private key %s: %v", path, err)
+	}
+	priv, ok := key.(ed25519.PrivateKey)
+	if !ok {
+		return nil, fmt.Errorf("%s is not an ed25519 private key", path)
+	}
+	return priv, nil
+}
+
+func loadBundlePublicKey(path string) (ed25519.PublicKey, error) {
+	block, err := readPEM(path)
+	if err != nil {
+		return nil, err
+	}
+	key, err := x509.ParsePKIXPublicKey(block.Bytes)
+	if err != nil {
+		return nil, fmt.Errorf("failed to parse public key %s: %v", path, err)
+	}
+	pub, ok := key.(ed25519.PublicKey)
+	if !ok {
+		return nil, fmt.Errorf("%s is not an ed25519 public key", path)
+	}
+	return pub, nil
+}
+
+func readPEM(path string) (*pem.Block, error) {
+	data, err := ioutil.ReadFile(path)
+	if err != nil {
+		return nil, fmt.Errorf("failed to read key: %v", err)
+	}
+	block, _ := pem.Decode(data)
+	if block == nil {
+		return nil, fmt.Errorf("%s is not a PEM file", path)
+	}
+	return block, nil
+}
@@ -0,0 +1,102 @@
+package cmd
+
+import (
+	"archive/tar"
+	"bytes"
+	"compress/gzip"
+	"crypto/ed25519"
+	"crypto/rand"
+	"io"
+	"io/ioutil"
+	"os"
+	"path/filepath"
+	"testing"
+
+	"github.com/aquasecurity/kube-bench/check"
+	"github.com/stretchr/testify/assert"
+)
+
+// rewriteBundle copies a bundle, passing the content of each file through edit.
+func rewriteBundle(t *testing.T, in []byte, edit func(name string, data []byte) []byte) []byte {
+	gzr, err := gzip.NewReader(bytes.NewReader(in))
+	if err != nil {
+		t.Fatal(err)
+	}
+	tr := tar.NewReader(gzr)
+
+	var out bytes.Buffer
+	gzw := gzip.NewWriter(&out)
+	tw := tar.NewWriter(gzw)
+	for {
+		hdr, err := tr.Next()
+		if err == io.EOF {
+			break
+		}
+		data, _ := ioutil.ReadAll(tr)
+		data = edit(hdr.Name, data)
+		hdr.Size = int64(len(data))
+		tw.WriteHeader(hdr)
+		tw.Write(data)
+	}
+	tw.Close()
+	gzw.Close()
+	return out.Bytes()
+}
+
+func TestBundle(t *testing.T) {
+	pub, priv, err := ed25519.GenerateKey(rand.Reader)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	files, err := bundleFiles([]*check.Controls{issueControls()})
+	assert.NoError(t, err)
+	assert.Contains(t, files, "results/node.json")
+
+	var b bytes.Buffer
+	manifest := &bundleManifest{Tool: "kube-bench", ScanID: "scan", Cfg: map[string]string{"node.yaml": "abc"}}
+	assert.NoError(t, writeBundle(&b, files, manifest, priv))
+
+	got, err := verifyBundle(bytes.NewReader(b.Bytes()), pub)
+	assert.NoError(t, err)
+	assert.Equal(t, "scan", got.ScanID)
+	assert.Equal(t, sha256Hex(files["results/node.json"]), got.Files["results/node.json"])
+
+	otherPub, _, _ := ed25519.GenerateKey(rand.Reader)
+	_, err = verifyBundle(bytes.NewReader(b.Bytes()), otherPub)
+	assert.Error(t, err, "signed with another key")
+
+	tampered := rewriteBundle(t, b.Bytes(), func(name string, data []byte) []byte {
+		if name == "results/node.json" {
+			return bytes.Replace(data, []byte("FAIL"), []byte("PASS"), -1)
+		}
+		return data
+	})
+	_, err = verifyBundle(bytes.NewReader(tampered), pub)
+	assert.EqualError(t, err, "results/node.json was modified")
+
+	tampered = rewriteBundle(t, b.Bytes(), func(name string, data []byte) []byte {
+		if name == bundleManifestFile {
+			return bytes.Replace(data, []byte(`"scan"`), []byte(`"other"`), -1)
+		}
+		return data
+	})
+	_, err = verifyBundle(bytes.NewReader(tampered), pub)
+	assert.Error(t, err, "modified manifest")
+}
+
+func TestHashFiles(t *testing.T) {
+	dir, err := ioutil.TempDir("", "kube-bench-bundle")
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer os.RemoveAll(dir)
+
+	os.MkdirAll(filepath.Join(dir, "sub"), 0755)
+	ioutil.WriteFile(filepath.Join(dir, "master.yaml"), []byte("a"), 0644)
+	ioutil.WriteFile(filepath.Join(dir, "sub", "node.yaml"), []byte("b"), 0644)
+
+	hashes, err := hashFiles(dir)
+	assert.NoError(t, err)
+	assert.Equal(t, map[string]string{"master.yaml": sha256Hex([]byte("a")), "sub/node.yaml": sha256Hex([]byte("b"))}, hashes)
+}
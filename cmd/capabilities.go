@@ -0,0 +1,89 @@
+package cmd
+
+import (
+	"fmt"
+	"io/ioutil"
+	"os"
+	"path/filepath"
+	"strings"
+	"sync"
+
+	"github.com/aquasecurity/kube-bench/check"
+	"github.com/golang/glog"
+)
+
+var (
+	procDir = "/proc"
+
+	// hostDirs are directories found on Kubernetes hosts, which are missing in
+	// the kube-bench container unless the host filesystem is mounted.
+	hostDirs = []string{"/etc/kubernetes", "/var/lib/kubelet", "/etc/systemd"}
+
+	// initProcesses are the names of the process with PID 1 on a host.
+	initProcesses = map[string]bool{"systemd": true, "init": true}
+
+	hostCaps     hostCapabilities
+	hostCapsOnce sync.Once
+)
+
+// hostCapabilities is what kube-bench can see of the host.
+type hostCapabilities struct {
+	pid        bool
+	filesystem bool
+	root       bool
+}
+
+// getHostCapabilities detects once whether kube-bench runs in the PID
+// namespace of the host, with its filesystem and as root.
+func getHostCapabilities() hostCapabilities {
+	hostCapsOnce.Do(func() {
+		hostCaps = detectHostCapabilities(procDir, hostDirs, os.Geteuid())
+		glog.V(1).Info(fmt.Sprintf("Host PID namespace: %t, host filesystem: %t, root: %t", hostCaps.pid, hostCaps.filesystem, hostCaps.root))
+	})
+	return hostCaps
+}
+
+func detectHostCapabilities(procDir string, hostDirs []string, euid int) hostCapabilities {
+	caps := hostCapabilities{root: euid == 0}
+
+	// In its own PID namespace, the process with PID 1 of a container is its
+	// entrypoint rather than the init system of the host.
+	if comm, err := ioutil.ReadFile(filepath.Join(procDir, "1", "comm")); err == nil {
+		caps.pid = initProcesses[strings.TrimSpace(string(comm))]
+	}
+
+	for _, dir := range hostDirs {
+		if info, err := os.Stat(dir); err == nil && info.IsDir() {
+			caps.filesystem = true
+			break
+		}
+	}
+	return caps
+}
+
+// setCapabilities records the capabilities kube-bench ran with in the results.
+func setCapabilities(controls *check.Controls) {
+	caps := getHostCapabilities()
+	controls.SetCapabilities(caps.pid, caps.filesystem, caps.root)
+}
+
+// printCapabilities warns about the groups whose checks couldn't be fully
+// evaluated, and what kube-bench was missing to evaluate them.
+func printCapabilities(caps *check.Capabilities) {
+	if caps == nil || len(caps.BlindGroups) == 0 {
+		return
+	}
+
+	var missing []string
+	if !caps.HostPID {
+		missing = append(missing, "the host PID namespace")
+	}
+	if !caps.HostFilesystem {
+		missing = append(missing, "the host filesystem")
+	}
+	if !caps.Root {
+		missing = append(missing, "root privileges")
+	}
+	colorPrint(check.WARN, fmt.Sprintf("The checks of groups %s could not be fully evaluated without %s\n",
+		strings.Join(caps.BlindGroups, ", "), strings.Join(missing, " and ")))
+}
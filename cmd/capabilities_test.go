@@ -0,0 +1,29 @@
+package cmd
+
+import (
+	"io/ioutil"
+	"os"
+	"path/filepath"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+)
+
+func TestDetectHostCapabilities(t *testing.T) {
+	dir, err := ioutil.TempDir("", "kube-bench-capabilities")
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer os.RemoveAll(dir)
+
+	proc := filepath.Join(dir, "proc")
+	os.MkdirAll(filepath.Join(proc, "1"), 0755)
+	host := filepath.Join(dir, "etc", "kubernetes")
+	os.MkdirAll(host, 0755)
+
+	ioutil.WriteFile(filepath.Join(proc, "1", "comm"), []byte("systemd\n"), 0644)
+	assert.Equal(t, hostCapabilities{pid: true, filesystem: true, root: true}, detectHostCapabilities(proc, []string{filepath.Join(dir, "missing"), host}, 0))
+
+	ioutil.WriteFile(filepath.Join(proc, "1", "comm"), []byte("kube-bench\n"), 0644)
+	assert.Equal(t, hostCapabilities{}, detectHostCapabilities(proc, []string{filepath.Join(dir, "missing")}, 1000))
+}
@@ -0,0 +1,230 @@
+package cmd
+
+import (
+	"bytes"
+	"crypto/sha256"
+	"encoding/hex"
+	"encoding/json"
+	"fmt"
+	"io/ioutil"
+	"os"
+	"path/filepath"
+	"sync"
+
+	"github.com/aquasecurity/kube-bench/check"
+	"github.com/golang/glog"
+)
+
+var (
+	checkpointFile string
+	scanCheckpoint *checkpoint
+)
+
+// checkpointHeader is the first line of a checkpoint file, identifying the
+// checks its results were recorded from.
+type checkpointHeader struct {
+	BenchmarkVersion string `json:"benchmark_version"`
+	// ControlsDigest is the digest of the controls files and their overlays.
+	ControlsDigest string `json:"controls_digest"`
+}
+
+// checkpointRecord is a line of a checkpoint file, holding its header or the
+// result of a completed check.
+type checkpointRecord struct {
+	Header   *checkpointHeader `json:"header,omitempty"`
+	NodeType check.NodeType    `json:"node_type,omitempty"`
+	Check    *check.Check      `json:"check,omitempty"`
+}
+
+// checkpoint records the results of the checks as they complete, so that an
+// interrupted scan can be resumed without running them again.
+type checkpoint struct {
+	mu   sync.Mutex
+	path string
+	file *os.File
+	done map[string]*check.Check
+}
+
+// openCheckpoint loads the results recorded in the checkpoint file at path,
+// if any, and opens it to record the results of the checks run from now on.
+// The results are only loaded if they were recorded with the same header, and
+// the file is started over otherwise: the checks, or what they're expected to
+// give, may have changed since.
+func openCheckpoint(path string, header checkpointHeader) (*checkpoint, error) {
+	cp := &checkpoint{path: path, done: make(map[string]*check.Check)}
+
+	data, err := ioutil.ReadFile(path)
+	if err != nil && !os.IsNotExist(err) {
+		return nil, fmt.Errorf("failed to read checkpoint file %s: %v", path, err)
+	}
+	lines := bytes.Split(data, []byte("\n"))
+	if len(data) > 0 {
+		var first checkpointRecord
+		if err := json.Unmarshal(lines[0], &first); err != nil || first.Header == nil || *first.Header != header {
+			glog.Warningf("Discarding checkpoint file %s, recorded with another benchmark version or other controls files", path)
+			data, lines = nil, nil
+		} else {
+			lines = lines[1:]
+		}
+	}
+	for _, line := range lines {
+		if len(bytes.TrimSpace(line)) == 0 {
+			continue
+		}
+		var r checkpointRecord
+		if err := json.Unmarshal(line, &r); err != nil || r.Check == nil {
+			// The last line may be truncated if the scan was killed while writing it.
+			glog.V(1).Info(fmt.Sprintf("Ignoring invalid record in checkpoint file %s", path))
+			continue
+		}
+		cp.done[findingKey(r.NodeType, r.Check.ID)] = r.Check
+	}
+	if len(cp.done) > 0 {
+		glog.V(1).Info(fmt.Sprintf("Resuming scan from checkpoint file %s: %d checks already completed", path, len(cp.done)))
+	}
+
+	flags := os.O_CREATE | os.O_WRONLY | os.O_APPEND
+	if len(data) == 0 {
+		flags |= os.O_TRUNC
+	}
+	f, err := os.OpenFile(path, flags, 0600)
+	if err != nil {
+		return nil, fmt.Errorf("failed to open checkpoint file %s: %v", path, err)
+	}
+	var start []byte
+	switch {
+	case len(data) == 0:
+		start, _ = json.Marshal(checkpointRecord{Header: &header})
+		start = append(start, '\n')
+	case data[len(data)-1] != '\n':
+		// Start a new line after a truncated record.
+		start = []byte("\n")
+	}
+	if _, err := f.Write(start); err != nil {
+		f.Close()
+		return nil, fmt.Errorf("failed to write checkpoint file %s: %v", path, err)
+	}
+	cp.file = f
+	return cp, nil
+}
+
+// controlsDigest returns the digest of the controls files of benchmarkVersion,
+// with its config.yaml, and of their overlays.
+func controlsDigest(benchmarkVersion string) (string, error) {
+	dir := filepath.Join(cfgDir, benchmarkVersion)
+	files, err := getYamlFilesFromDir(dir)
+	if err != nil {
+		return "", err
+	}
+
+	h := sha256.New()
+	for _, file := range append([]string{filepath.Join(dir, "config.yaml")}, files...) {
+		for _, f := range append([]string{file}, overlayFiles(file)...) {
+			in, err := ioutil.ReadFile(f)
+			if os.IsNotExist(err) {
+				continue
+			}
+			if err != nil {
+				return "", err
+			}
+			fmt.Fprintf(h, "%s\x00%d\x00", f, len(in))
+			h.Write(in)
+		}
+	}
+	return hex.EncodeToString(h.Sum(nil)), nil
+}
+
+// restore copies the recorded result of a check, and reports whether there was one.
+func (cp *checkpoint) restore(nodetype check.NodeType, c *check.Check) bool {
+	cp.mu.Lock()
+	defer cp.mu.Unlock()
+
+	saved, ok := cp.done[findingKey(nodetype, c.ID)]
+	if !ok {
+		return false
+	}
+	c.State = saved.State
+	c.ActualValue = saved.ActualValue
+	c.ExpectedResult = saved.ExpectedResult
+	c.Expected = saved.Expected
+	c.TestResults = saved.TestResults
+	c.Reason = saved.Reason
+	c.ReasonCode = saved.ReasonCode
+	return true
+}
+
+func (cp *checkpoint) record(nodetype check.NodeType, c *check.Check) error {
+	line, err := json.Marshal(checkpointRecord{NodeType: nodetype, Check: c})
+	if err != nil {
+		return err
+	}
+
+	cp.mu.Lock()
+	defer cp.mu.Unlock()
+	cp.done[findingKey(nodetype, c.ID)] = c
+	_, err = cp.file.Write(append(line, '\n'))
+	return err
+}
+
+// remove deletes the checkpoint file once the scan completed.
+func (cp *checkpoint) remove() error {
+	cp.file.Close()
+	return os.Remove(cp.path)
+}
+
+// runner returns a Runner that restores the checks of nodetype recorded in the
+// checkpoint, and runs the others with r, recording their results.
+func (cp *checkpoint) runner(nodetype check.NodeType, r check.Runner) check.Runner {
+	return checkpointRunner{Runner: r, checkpoint: cp, nodetype: nodetype}
+}
+
+type checkpointRunner struct {
+	check.Runner
+	checkpoint *checkpoint
+	nodetype   check.NodeType
+}
+
+func (r checkpointRunner) Run(c *check.Check) check.State {
+	if r.checkpoint.restore(r.nodetype, c) {
+		glog.V(2).Info(fmt.Sprintf("Check %s restored from checkpoint", c.ID))
+		return c.State
+	}
+
+	state := r.Runner.Run(c)
+	if state != check.INCOMPLETE {
+		if err := r.checkpoint.record(r.nodetype, c); err != nil {
+			glog.Warningf("failed to record check %s in checkpoint file: %v", c.ID, err)
+		}
+	}
+	return state
+}
+
+// startCheckpoint opens the checkpoint file given with --checkpoint, if any,
+// for a scan of benchmarkVersion.
+func startCheckpoint(benchmarkVersion string) {
+	if checkpointFile == "" {
+		return
+	}
+
+	digest, err := controlsDigest(benchmarkVersion)
+	if err != nil {
+		exitWithError(fmt.Errorf("failed to compute the digest of the controls files for checkpoint file %s: %v", checkpointFile, err))
+	}
+	cp, err := openCheckpoint(checkpointFile, checkpointHeader{BenchmarkVersion: benchmarkVersion, ControlsDigest: digest})
+	if err != nil {
+		exitWithError(err)
+	}
+	scanCheckpoint = cp
+}
+
+// finishCheckpoint removes the checkpoint file after a complete scan, and keeps
+// it to resume from after an interrupted one.
+func finishCheckpoint() {
+	if scanCheckpoint == nil || isInterrupted() {
+		return
+	}
+
+	if err := scanCheckpoint.remove(); err != nil {
+		glog.Warningf("failed to remove checkpoint file: %v", err)
+	}
+}
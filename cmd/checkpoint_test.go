@@ -0,0 +1,87 @@
+package cmd
+
+import (
+	"io/ioutil"
+	"os"
+	"path/filepath"
+	"testing"
+
+	"github.com/aquasecurity/kube-bench/check"
+	"github.com/stretchr/testify/assert"
+)
+
+func TestCheckpoint(t *testing.T) {
+	dir, err := ioutil.TempDir("", "kube-bench-checkpoint")
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer os.RemoveAll(dir)
+	path := filepath.Join(dir, "scan.checkpoint")
+	header := checkpointHeader{BenchmarkVersion: "cis-1.5", ControlsDigest: "abc"}
+
+	cp, err := openCheckpoint(path, header)
+	assert.NoError(t, err)
+	runner := cp.runner(check.NODE, stateRunner(check.FAIL))
+	assert.Equal(t, check.FAIL, runner.Run(&check.Check{ID: "4.2.1", ActualValue: "--anonymous-auth=true"}))
+	assert.Equal(t, check.INCOMPLETE, cp.runner(check.NODE, stateRunner(check.INCOMPLETE)).Run(&check.Check{ID: "4.2.2"}))
+	cp.file.Close()
+
+	// A scan killed while writing a record leaves a truncated line.
+	f, _ := os.OpenFile(path, os.O_WRONLY|os.O_APPEND, 0600)
+	f.WriteString(`{"node_type":"node","check":{"test_num`)
+	f.Close()
+
+	cp, err = openCheckpoint(path, header)
+	assert.NoError(t, err)
+	runner = cp.runner(check.NODE, stateRunner(check.PASS))
+
+	c := &check.Check{ID: "4.2.1"}
+	assert.Equal(t, check.FAIL, runner.Run(c), "completed checks are restored")
+	assert.Equal(t, "--anonymous-auth=true", c.ActualValue)
+	assert.Equal(t, check.PASS, runner.Run(&check.Check{ID: "4.2.2"}), "incomplete checks run again")
+	assert.Equal(t, check.PASS, cp.runner(check.MASTER, stateRunner(check.PASS)).Run(&check.Check{ID: "4.2.1"}))
+	cp.file.Close()
+
+	cp, err = openCheckpoint(path, header)
+	assert.NoError(t, err)
+	assert.Len(t, cp.done, 3, "records are appended after the truncated line")
+	cp.file.Close()
+
+	for _, other := range []checkpointHeader{{BenchmarkVersion: "cis-1.4", ControlsDigest: "abc"}, {BenchmarkVersion: "cis-1.5", ControlsDigest: "def"}} {
+		cp, err = openCheckpoint(path, other)
+		assert.NoError(t, err)
+		assert.Empty(t, cp.done, "a checkpoint of other checks is discarded")
+		cp.file.Close()
+	}
+	cp, err = openCheckpoint(path, header)
+	assert.NoError(t, err)
+	assert.Empty(t, cp.done, "the discarded checkpoint was started over")
+
+	assert.NoError(t, cp.remove())
+	_, err = os.Stat(path)
+	assert.True(t, os.IsNotExist(err))
+}
+
+func TestControlsDigest(t *testing.T) {
+	dir, err := ioutil.TempDir("", "kube-bench-checkpoint")
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer os.RemoveAll(dir)
+	defer func(dir string) { cfgDir = dir }(cfgDir)
+	cfgDir = dir
+
+	benchmark := filepath.Join(dir, "cis-1.5")
+	assert.NoError(t, os.Mkdir(benchmark, 0700))
+	assert.NoError(t, ioutil.WriteFile(filepath.Join(benchmark, "node.yaml"), []byte("groups: []\n"), 0600))
+	digest, err := controlsDigest("cis-1.5")
+	assert.NoError(t, err)
+
+	assert.NoError(t, ioutil.WriteFile(filepath.Join(benchmark, "node.yaml"), []byte("groups: [{id: 4.1}]\n"), 0600))
+	changed, err := controlsDigest("cis-1.5")
+	assert.NoError(t, err)
+	assert.NotEqual(t, digest, changed)
+
+	_, err = controlsDigest("cis-1.6")
+	assert.Error(t, err)
+}
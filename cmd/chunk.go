@@ -0,0 +1,159 @@
+package cmd
+
+import (
+	"bytes"
+	"compress/gzip"
+	"crypto/sha256"
+	"encoding/hex"
+	"encoding/json"
+	"fmt"
+	"io/ioutil"
+	"sort"
+)
+
+const chunkEncodingGzip = "gzip"
+
+// payloadChunk is a part of a payload compressed or split to fit the size
+// limit of a sink. The payload is the concatenation of the data of its
+// chunks in order, gunzipped if their encoding is gzip.
+type payloadChunk struct {
+	ID       string `json:"kube_bench_chunk"`
+	Index    int    `json:"index"`
+	Total    int    `json:"total"`
+	Encoding string `json:"encoding,omitempty"`
+	Data     []byte `json:"data"`
+}
+
+// chunkPayload returns the bodies to send payload as to a sink whose bodies
+// can't exceed maxSize bytes, if maxSize is set. A payload that fits is sent
+// as is, unless compress is set, in which case it is gzipped. Otherwise, it is
+// split into chunks, reassembled by loadReports. The ID of the chunks is id
+// followed by a digest of the payload, so that the chunks of payloads sent
+// with the same id, such as the drift events of a daemon, aren't mixed up.
+func chunkPayload(id string, payload []byte, maxSize int, compress bool) ([][]byte, error) {
+	if !compress && (maxSize <= 0 || len(payload) <= maxSize) {
+		return [][]byte{payload}, nil
+	}
+
+	sum := sha256.Sum256(payload)
+	id = id + "/" + hex.EncodeToString(sum[:])[:16]
+
+	encoding := ""
+	if compress {
+		var buf bytes.Buffer
+		w := gzip.NewWriter(&buf)
+		if _, err := w.Write(payload); err != nil {
+			return nil, err
+		}
+		if err := w.Close(); err != nil {
+			return nil, err
+		}
+		payload, encoding = buf.Bytes(), chunkEncodingGzip
+	}
+
+	// The data of the chunks is base64-encoded, which takes 4 bytes for every 3,
+	// besides the fields of the chunk.
+	size := len(payload)
+	if maxSize > 0 {
+		envelope, err := json.Marshal(payloadChunk{ID: id, Index: len(payload), Total: len(payload), Encoding: encoding})
+		if err != nil {
+			return nil, err
+		}
+		if size = (maxSize - len(envelope)) / 4 * 3; size <= 0 {
+			return nil, fmt.Errorf("max payload size of %d bytes is too small for chunks of %s", maxSize, id)
+		}
+	}
+
+	total := (len(payload) + size - 1) / size
+	if total == 0 {
+		total = 1
+	}
+	bodies := make([][]byte, 0, total)
+	for i := 0; i < total; i++ {
+		end := (i + 1) * size
+		if end > len(payload) {
+			end = len(payload)
+		}
+		body, err := json.Marshal(payloadChunk{ID: id, Index: i, Total: total, Encoding: encoding, Data: payload[i*size : end]})
+		if err != nil {
+			return nil, err
+		}
+		bodies = append(bodies, body)
+	}
+	return bodies, nil
+}
+
+// decodePayloadChunk returns the chunk in raw, if it holds one.
+func decodePayloadChunk(raw []byte) (*payloadChunk, bool) {
+	var probe struct {
+		ID *string `json:"kube_bench_chunk"`
+	}
+	if json.Unmarshal(raw, &probe) != nil || probe.ID == nil {
+		return nil, false
+	}
+
+	chunk := new(payloadChunk)
+	if err := json.Unmarshal(raw, chunk); err != nil {
+		return nil, false
+	}
+	return chunk, true
+}
+
+// reassemblePayloads returns the payloads split into chunks, in the order of
+// their IDs. Chunks may be repeated, as sinks may have received them more than
+// once, but a payload with missing chunks is an error.
+func reassemblePayloads(chunks []*payloadChunk) ([][]byte, error) {
+	byID := make(map[string]map[int]*payloadChunk)
+	var ids []string
+	for _, c := range chunks {
+		if _, ok := byID[c.ID]; !ok {
+			byID[c.ID] = make(map[int]*payloadChunk)
+			ids = append(ids, c.ID)
+		}
+		for _, seen := range byID[c.ID] {
+			if c.Total != seen.Total || c.Encoding != seen.Encoding {
+				return nil, fmt.Errorf("payload %s has chunks of different payloads, with %d and %d chunks encoded as %q and %q", c.ID, seen.Total, c.Total, seen.Encoding, c.Encoding)
+			}
+			break
+		}
+		if seen, ok := byID[c.ID][c.Index]; ok && !bytes.Equal(seen.Data, c.Data) {
+			return nil, fmt.Errorf("payload %s has different chunks with index %d", c.ID, c.Index)
+		}
+		byID[c.ID][c.Index] = c
+	}
+	sort.Strings(ids)
+
+	var payloads [][]byte
+	for _, id := range ids {
+		parts := byID[id]
+		var first *payloadChunk
+		for _, c := range parts {
+			first = c
+			break
+		}
+
+		var buf bytes.Buffer
+		for i := 0; i < first.Total; i++ {
+			c, ok := parts[i]
+			if !ok {
+				return nil, fmt.Errorf("payload %s is incomplete, %d of its %d chunks are missing", id, first.Total-len(parts), first.Total)
+			}
+			buf.Write(c.Data)
+		}
+
+		payload := buf.Bytes()
+		if first.Encoding == chunkEncodingGzip {
+			r, err := gzip.NewReader(&buf)
+			if err != nil {
+				return nil, fmt.Errorf("failed to decompress payload %s: %v", id, err)
+			}
+			if payload, err = ioutil.ReadAll(r); err != nil {
+				return nil, fmt.Errorf("failed to decompress payload %s: %v", id, err)
+			}
+		} else if first.Encoding != "" {
+			return nil, fmt.Errorf("payload %s has unknown encoding %q", id, first.Encoding)
+		}
+		payloads = append(payloads, payload)
+	}
+	return payloads, nil
+}
@@ -0,0 +1,97 @@
+package cmd
+
+import (
+	"bytes"
+	"encoding/json"
+	"strings"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+)
+
+func TestChunkPayload(t *testing.T) {
+	payload := []byte(`{"node_type":"node","tests":[]}`)
+	bodies, err := chunkPayload("scan/node", payload, 0, false)
+	assert.NoError(t, err)
+	assert.Equal(t, [][]byte{payload}, bodies, "a payload that fits is sent as is")
+
+	large := []byte(strings.Repeat(`{"test_number":"4.2.1","status":"PASS"},`, 500))
+	cases := []struct {
+		maxSize  int
+		compress bool
+		chunks   int
+	}{
+		{maxSize: 0, compress: true, chunks: 1},
+		{maxSize: 1000, compress: false, chunks: 30},
+		{maxSize: 200, compress: true},
+	}
+	for _, c := range cases {
+		bodies, err := chunkPayload("scan/node", large, c.maxSize, c.compress)
+		assert.NoError(t, err)
+		if c.chunks > 0 {
+			assert.Len(t, bodies, c.chunks)
+		}
+
+		// Sinks may receive the chunks in any order, and more than once.
+		var chunks []*payloadChunk
+		for i := len(bodies) - 1; i >= 0; i-- {
+			if c.maxSize > 0 {
+				assert.True(t, len(bodies[i]) <= c.maxSize, "chunk of %d bytes", len(bodies[i]))
+			}
+			chunk, ok := decodePayloadChunk(bodies[i])
+			assert.True(t, ok)
+			chunks = append(chunks, chunk, chunk)
+		}
+		payloads, err := reassemblePayloads(chunks)
+		assert.NoError(t, err)
+		assert.Equal(t, [][]byte{large}, payloads)
+	}
+
+	_, err = chunkPayload("scan/node", large, 50, false)
+	assert.Error(t, err)
+	assert.Contains(t, err.Error(), "max payload size of 50 bytes is too small for chunks of scan/node/")
+}
+
+func TestReassemblePayloads(t *testing.T) {
+	bodies, err := chunkPayload("scan/node", bytes.Repeat([]byte("x"), 1000), 300, false)
+	assert.NoError(t, err)
+
+	var chunks []*payloadChunk
+	for _, body := range bodies[1:] {
+		chunk, _ := decodePayloadChunk(body)
+		chunks = append(chunks, chunk)
+	}
+	_, err = reassemblePayloads(chunks)
+	assert.Error(t, err)
+	assert.Contains(t, err.Error(), "is incomplete, 1 of its 7 chunks are missing")
+
+	// Payloads sent with the same ID, such as the drift events of a daemon,
+	// get chunks of their own.
+	other, err := chunkPayload("scan/node", bytes.Repeat([]byte("y"), 1000), 300, false)
+	assert.NoError(t, err)
+	chunks = nil
+	for _, body := range append(bodies, other...) {
+		chunk, _ := decodePayloadChunk(body)
+		chunks = append(chunks, chunk)
+	}
+	payloads, err := reassemblePayloads(chunks)
+	assert.NoError(t, err)
+	assert.Len(t, payloads, 2)
+
+	// Chunk sets which disagree are rejected rather than merged.
+	first, _ := decodePayloadChunk(bodies[0])
+	mixed := *first
+	mixed.Total++
+	_, err = reassemblePayloads([]*payloadChunk{first, &mixed})
+	assert.Error(t, err)
+	assert.Contains(t, err.Error(), "has chunks of different payloads")
+
+	mixed = *first
+	mixed.Data = []byte("z")
+	_, err = reassemblePayloads([]*payloadChunk{first, &mixed})
+	assert.Error(t, err)
+	assert.Contains(t, err.Error(), "has different chunks with index 0")
+
+	_, ok := decodePayloadChunk(json.RawMessage(`{"node_type":"node","tests":[]}`))
+	assert.False(t, ok)
+}
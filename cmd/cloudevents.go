@@ -0,0 +1,149 @@
+package cmd
+
+import (
+	"encoding/json"
+	"fmt"
+	"net/http"
+	"time"
+
+	"github.com/aquasecurity/kube-bench/check"
+	"github.com/golang/glog"
+	"github.com/spf13/viper"
+)
+
+const (
+	cloudEventsSpecVersion = "1.0"
+	cloudEventsContentType = "application/cloudevents+json"
+	cloudEventTypeRun      = "com.github.aquasecurity.kube-bench.run"
+	cloudEventTypeCheck    = "com.github.aquasecurity.kube-bench.check"
+
+	cloudEventsPerRun   = "run"
+	cloudEventsPerCheck = "check"
+)
+
+func init() {
+	exporterFactories["cloudevents"] = newCloudEventsExporter
+
+	if err := check.RegisterRenderer("cloudevents", check.RendererFunc(renderCloudEvent)); err != nil {
+		panic(err)
+	}
+}
+
+// cloudEvent is a CloudEvents envelope in the structured content mode.
+type cloudEvent struct {
+	SpecVersion     string      `json:"specversion"`
+	ID              string      `json:"id"`
+	Source          string      `json:"source"`
+	Type            string      `json:"type"`
+	Subject         string      `json:"subject,omitempty"`
+	Time            string      `json:"time"`
+	DataContentType string      `json:"datacontenttype"`
+	ScanID          string      `json:"kubebenchscanid,omitempty"`
+	Data            interface{} `json:"data"`
+}
+
+// cloudEventSource returns the source of the events, identifying the node
+// kube-bench runs on unless one is configured.
+func cloudEventSource(source string) string {
+	if source != "" {
+		return source
+	}
+	return "kube-bench/" + nodeName()
+}
+
+func newCloudEvent(source, eventType, subject, scanID string, data interface{}) (*cloudEvent, error) {
+	id, err := newUUID()
+	if err != nil {
+		return nil, err
+	}
+
+	return &cloudEvent{
+		SpecVersion:     cloudEventsSpecVersion,
+		ID:              id,
+		Source:          source,
+		Type:            eventType,
+		Subject:         subject,
+		Time:            formatTime(time.Now()),
+		DataContentType: "application/json",
+		ScanID:          scanID,
+		Data:            data,
+	}, nil
+}
+
+// cloudEvents wraps the results of a target in a single event, or in an event per check.
+func cloudEvents(controls *check.Controls, source, mode string) ([]*cloudEvent, error) {
+	if mode != cloudEventsPerCheck {
+		ev, err := newCloudEvent(source, cloudEventTypeRun, string(controls.Type), controls.ScanID, controls)
+		if err != nil {
+			return nil, err
+		}
+		return []*cloudEvent{ev}, nil
+	}
+
+	var events []*cloudEvent
+	for _, g := range controls.Groups {
+		for _, c := range g.Checks {
+			ev, err := newCloudEvent(source, cloudEventTypeCheck, c.ID, controls.ScanID, c)
+			if err != nil {
+				return nil, err
+			}
+			events = append(events, ev)
+		}
+	}
+	return events, nil
+}
+
+// renderCloudEvent renders the results of a target as a single CloudEvent.
+func renderCloudEvent(controls *check.Controls) ([]byte, error) {
+	events, err := cloudEvents(controls, cloudEventSource(""), cloudEventsPerRun)
+	if err != nil {
+		return nil, err
+	}
+	return json.Marshal(events[0])
+}
+
+// cloudEventsExporter posts the results of a target as CloudEvents to a sink,
+// such as a Knative broker or an Argo Events webhook.
+type cloudEventsExporter struct {
+	url    string
+	source string
+	mode   string
+}
+
+func newCloudEventsExporter(v *viper.Viper) (Exporter, error) {
+	e := &cloudEventsExporter{
+		url:    v.GetString("url"),
+		source: cloudEventSource(v.GetString("source")),
+		mode:   v.GetString("mode"),
+	}
+	if e.url == "" {
+		return nil, fmt.Errorf("url is not set")
+	}
+	switch e.mode {
+	case "":
+		e.mode = cloudEventsPerRun
+	case cloudEventsPerRun, cloudEventsPerCheck:
+	default:
+		return nil, fmt.Errorf("invalid mode %q, valid modes are %q and %q", e.mode, cloudEventsPerRun, cloudEventsPerCheck)
+	}
+	return e, nil
+}
+
+func (e *cloudEventsExporter) Name() string { return "cloudevents" }
+
+func (e *cloudEventsExporter) Export(controls *check.Controls) error {
+	events, err := cloudEvents(controls, e.source, e.mode)
+	if err != nil {
+		return err
+	}
+
+	header := http.Header{}
+	header.Set("Content-Type", cloudEventsContentType)
+	glog.V(2).Info(fmt.Sprintf("Sending %d CloudEvents to %s", len(events), e.url))
+	for _, ev := range events {
+		if err := doJSON(http.MethodPost, e.url, header, ev, nil); err != nil {
+			return err
+		}
+	}
+	return nil
+}
@@ -0,0 +1,75 @@
+package cmd
+
+import (
+	"encoding/json"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+
+	"github.com/aquasecurity/kube-bench/check"
+	"github.com/spf13/viper"
+	"github.com/stretchr/testify/assert"
+)
+
+func TestCloudEventsExporter(t *testing.T) {
+	var events []map[string]interface{}
+	ts := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		assert.Equal(t, cloudEventsContentType, r.Header.Get("Content-Type"))
+		var ev map[string]interface{}
+		json.NewDecoder(r.Body).Decode(&ev)
+		events = append(events, ev)
+		w.WriteHeader(http.StatusAccepted)
+	}))
+	defer ts.Close()
+
+	cases := []struct {
+		mode    string
+		exp     int
+		evType  string
+		subject string
+	}{
+		{mode: "", exp: 1, evType: cloudEventTypeRun, subject: "node"},
+		{mode: cloudEventsPerCheck, exp: 4, evType: cloudEventTypeCheck, subject: "4.2.1"},
+	}
+
+	for _, c := range cases {
+		events = nil
+		v := viper.New()
+		v.Set("url", ts.URL)
+		v.Set("source", "/clusters/prod")
+		v.Set("mode", c.mode)
+		e, err := newCloudEventsExporter(v)
+		assert.NoError(t, err)
+
+		assert.NoError(t, e.Export(issueControls()))
+		assert.Len(t, events, c.exp)
+		assert.Equal(t, "1.0", events[0]["specversion"])
+		assert.Equal(t, "/clusters/prod", events[0]["source"])
+		assert.Equal(t, c.evType, events[0]["type"])
+		assert.Equal(t, c.subject, events[0]["subject"])
+		assert.Equal(t, "scan", events[0]["kubebenchscanid"])
+		assert.NotEmpty(t, events[0]["id"])
+	}
+
+	v := viper.New()
+	v.Set("url", ts.URL)
+	v.Set("mode", "group")
+	_, err := newCloudEventsExporter(v)
+	assert.Error(t, err)
+}
+
+func TestRenderCloudEvent(t *testing.T) {
+	r, err := check.GetRenderer("cloudevents")
+	assert.NoError(t, err)
+
+	out, err := r.Render(issueControls())
+	assert.NoError(t, err)
+
+	var ev struct {
+		Type string          `json:"type"`
+		Data *check.Controls `json:"data"`
+	}
+	assert.NoError(t, json.Unmarshal(out, &ev))
+	assert.Equal(t, cloudEventTypeRun, ev.Type)
+	assert.Equal(t, check.NODE, ev.Data.Type)
+}
@@ -21,6 +21,7 @@ import (
 	"os"
 	"path/filepath"
 	"strings"
+	"time"
 
 	"github.com/aquasecurity/kube-bench/check"
 	"github.com/golang/glog"
@@ -63,17 +64,29 @@ func NewRunFilter(opts FilterOpts) (check.Predicate, error) {
 }
 
 func runChecks(nodetype check.NodeType, testYamlFile string) {
-	var summary check.Summary
+	if !sidecar.runs(nodetype) {
+		glog.V(1).Info(fmt.Sprintf("Skipping the %s checks, which aren't those of the sidecar target", nodetype))
+		return
+	}
+	controls, summary, err := runTarget(nodetype, testYamlFile, ioutil.ReadFile)
+	if err != nil {
+		exitWithError(err)
+	}
+	outputResults(controls, summary)
+}
 
+// runTarget loads the controls in testYamlFile, returned by read, and runs their checks.
+// It doesn't write to any output, so it can safely run concurrently for several targets.
+// Errors are returned rather than exiting, so that the long-running modes survive them.
+func runTarget(nodetype check.NodeType, testYamlFile string, read func(string) ([]byte, error)) (*check.Controls, check.Summary, error) {
 	// Verify config file was loaded into Viper during Cobra sub-command initialization.
 	if configFileError != nil {
-		colorPrint(check.FAIL, fmt.Sprintf("Failed to read config file: %v\n", configFileError))
-		os.Exit(1)
+		return nil, check.Summary{}, configError{fmt.Errorf("failed to read config file: %v", configFileError)}
 	}
 
-	in, err := ioutil.ReadFile(testYamlFile)
+	in, err := read(testYamlFile)
 	if err != nil {
-		exitWithError(fmt.Errorf("error opening %s test file: %v", testYamlFile, err))
+		return nil, check.Summary{}, configError{fmt.Errorf("error opening %s test file: %v", testYamlFile, err)}
 	}
 
 	glog.V(1).Info(fmt.Sprintf("Using test file: %s\n", testYamlFile))
@@ -81,8 +94,7 @@ func runChecks(nodetype check.NodeType, testYamlFile string) {
 	// Get the viper config for this section of tests
 	typeConf := viper.Sub(string(nodetype))
 	if typeConf == nil {
-		colorPrint(check.FAIL, fmt.Sprintf("No config settings for %s\n", string(nodetype)))
-		os.Exit(1)
+		return nil, check.Summary{}, configError{fmt.Errorf("no config settings for %s", nodetype)}
 	}
 
 	// Get the set of executables we need for this section of the tests
@@ -90,63 +102,169 @@ func runChecks(nodetype check.NodeType, testYamlFile string) {
 
 	// Checks that the executables we need for the section are running.
 	if err != nil {
-		exitWithError(fmt.Errorf("failed to get a set of executables needed for tests: %v", err))
+		return nil, check.Summary{}, environmentError{fmt.Errorf("failed to get a set of executables needed for tests: %v", err)}
 	}
 
 	confmap := getFiles(typeConf, "config")
 	svcmap := getFiles(typeConf, "service")
 	kubeconfmap := getFiles(typeConf, "kubeconfig")
 	cafilemap := getFiles(typeConf, "ca")
+	sidecar.processFiles(confmap, svcmap, kubeconfmap, cafilemap)
 
 	// Variable substitutions. Replace all occurrences of variables in controls files.
-	s := string(in)
-	s = makeSubstitutions(s, "bin", binmap)
-	s = makeSubstitutions(s, "conf", confmap)
-	s = makeSubstitutions(s, "svc", svcmap)
-	s = makeSubstitutions(s, "kubeconfig", kubeconfmap)
-	s = makeSubstitutions(s, "cafile", cafilemap)
-
-	controls, err := check.NewControls(nodetype, []byte(s))
+	substitute := func(s string) string {
+		s = makeSubstitutions(s, "bin", binmap)
+		s = makeSubstitutions(s, "conf", confmap)
+		s = makeSubstitutions(s, "svc", svcmap)
+		s = makeSubstitutions(s, "kubeconfig", kubeconfmap)
+		s = makeSubstitutions(s, "cafile", cafilemap)
+		s = substituteFacts(s)
+		return s
+	}
+
+	controls, err := check.LoadControls(nodetype, []byte(substitute(string(in))), check.LoadOptions{File: testYamlFile, AllowUnknownFields: allowUnknownFields, Strict: strictControls})
 	if err != nil {
-		exitWithError(fmt.Errorf("error setting up %s controls: %v", nodetype, err))
+		return nil, check.Summary{}, configError{fmt.Errorf("error setting up %s controls: %v", nodetype, err)}
+	}
+	if err := applyOverlays(controls, nodetype, testYamlFile, read, substitute); err != nil {
+		return nil, check.Summary{}, configError{fmt.Errorf("error setting up %s controls: %v", nodetype, err)}
 	}
+	controls.SetOwners(viper.GetStringMapString("owners"))
+	controls.OverrideExpectedValues(getExpectedValues(viper.GetViper()), expectedValuesSource)
+	controls.CheckRequirements(scanFacts(binmap))
 
-	runner := check.NewRunner()
-	filter, err := NewRunFilter(filterOpts)
+	var runner check.Runner = interruptibleRunner{newRunner()}
+	if scanCheckpoint != nil {
+		runner = scanCheckpoint.runner(nodetype, runner)
+	}
+	opts := filterOpts
+	if traceCheck != "" {
+		if !controls.TraceCheck(traceCheck, os.Stderr) {
+			glog.V(1).Info(fmt.Sprintf("No check %s to trace in %s\n", traceCheck, testYamlFile))
+		}
+		// Only run the traced check unless others were selected.
+		if opts.CheckList == "" && opts.GroupList == "" {
+			opts.CheckList = traceCheck
+		}
+	}
+	filter, err := NewRunFilter(opts)
 	if err != nil {
-		exitWithError(fmt.Errorf("error setting up run filter: %v", err))
+		return nil, check.Summary{}, configError{fmt.Errorf("error setting up run filter: %v", err)}
+	}
+	filter = sidecar.filter(filter, binmap, confmap, svcmap, kubeconfmap, cafilemap)
+
+	controls.Timestamp = formatTime(time.Now())
+	summary := controls.RunChecks(runner, filter)
+	controls.ScanID = scanID
+	controls.Annotate(annotations)
+	setCapabilities(controls)
+	if anonymize {
+		getAnonymizer().anonymizeControls(controls)
 	}
 
-	summary = controls.RunChecks(runner, filter)
+	return controls, summary, nil
+}
 
-	if (summary.Fail > 0 || summary.Warn > 0 || summary.Pass > 0 || summary.Info > 0) && junitFmt {
-		out, err := controls.JUnit()
+// expectedValuesSource is the source recorded in the results of the tests
+// whose value is overridden in the config.
+const expectedValuesSource = "expected_values in config.yaml"
+
+// getExpectedValues returns the values overriding those the tests of checks
+// compare to, from the expected_values section of the config, keyed by check
+// ID then by the flag or path of the test.
+func getExpectedValues(v *viper.Viper) map[string]map[string]string {
+	values := make(map[string]map[string]string)
+	for id, tests := range v.GetStringMap("expected_values") {
+		overrides := make(map[string]string)
+		switch tests := tests.(type) {
+		case map[string]interface{}:
+			for key, value := range tests {
+				overrides[key] = fmt.Sprint(value)
+			}
+		case map[interface{}]interface{}:
+			for key, value := range tests {
+				overrides[fmt.Sprint(key)] = fmt.Sprint(value)
+			}
+		default:
+			glog.Warningf("ignoring the expected values of check %s, which aren't a map of flags or paths to values", id)
+			continue
+		}
+		values[id] = overrides
+	}
+	return values
+}
+
+// getAuditLimits returns the resources each audit command may use, from the
+// audit_limits section of the config.
+func getAuditLimits(v *viper.Viper) check.Limits {
+	return check.Limits{
+		Timeout: v.GetDuration("audit_limits.timeout"),
+		CPU:     v.GetDuration("audit_limits.cpu"),
+		Memory:  v.GetInt64("audit_limits.memory_mb") * 1024 * 1024,
+	}
+}
+
+// outputResults writes the results of a target to the selected output formats and sinks.
+func outputResults(controls *check.Controls, summary check.Summary) {
+	if err := sendResults(controls); err != nil {
+		exitWithError(err)
+	}
+	recordResults(summary)
+	pipeline.writeOutputs(controls)
+
+	hasResults := summary.Fail > 0 || summary.Warn > 0 || summary.Pass > 0 || summary.Info > 0 || summary.Incomplete > 0 || summary.Error > 0
+
+	// if we successfully ran some tests and it's not text format, ignore the warnings
+	if format := getOutputFormat(); hasResults && format != "" {
+		renderer, err := check.GetRenderer(format)
 		if err != nil {
-			exitWithError(fmt.Errorf("failed to output in JUnit format: %v", err))
+			exitWithError(err)
+		}
+
+		out, err := renderer.Render(controls)
+		if err != nil {
+			exitWithError(fmt.Errorf("failed to output in %s format: %v", format, err))
 		}
 
 		PrintOutput(string(out), outputFile)
-		// if we successfully ran some tests and it's json format, ignore the warnings
-	} else if (summary.Fail > 0 || summary.Warn > 0 || summary.Pass > 0 || summary.Info > 0) && jsonFmt {
+	} else if hasResults && pgSQL {
+		// if we want to store in PostgreSQL, convert to JSON and save it
 		out, err := controls.JSON()
 		if err != nil {
 			exitWithError(fmt.Errorf("failed to output in JSON format: %v", err))
 		}
 
-		PrintOutput(string(out), outputFile)
+		savePgsql(string(out), scanID)
 	} else {
-		// if we want to store in PostgreSQL, convert to JSON and save it
-		if (summary.Fail > 0 || summary.Warn > 0 || summary.Pass > 0 || summary.Info > 0) && pgSQL {
-			out, err := controls.JSON()
-			if err != nil {
-				exitWithError(fmt.Errorf("failed to output in JSON format: %v", err))
-			}
+		prettyPrint(controls, summary)
+	}
+}
 
-			savePgsql(string(out))
-		} else {
-			prettyPrint(controls, summary)
-		}
+// sendResults sends the results of a target to the selected notifiers and exporters.
+// Failing notifiers and exporters are only reported, the error is about setting them up.
+func sendResults(controls *check.Controls) error {
+	if notify {
+		notifyResults(controls)
 	}
+
+	if len(exportNames) > 0 || exporterDir != "" {
+		return exportResults(controls)
+	}
+	return nil
+}
+
+// getOutputFormat returns the name of the renderer selected by the output flags,
+// or an empty string for the human-readable output.
+func getOutputFormat() string {
+	switch {
+	case outputFormat != "":
+		return outputFormat
+	case junitFmt:
+		return "junit"
+	case jsonFmt:
+		return "json"
+	}
+	return ""
 }
 
 // colorPrint outputs the state in a specific colour, along with a message string
@@ -160,13 +278,28 @@ func prettyPrint(r *check.Controls, summary check.Summary) {
 	// Print check results.
 	if !noResults {
 		colorPrint(check.INFO, fmt.Sprintf("%s %s\n", r.ID, r.Text))
+		printCapabilities(r.Capabilities)
 		for _, g := range r.Groups {
 			colorPrint(check.INFO, fmt.Sprintf("%s %s\n", g.ID, g.Text))
 			for _, c := range g.Checks {
-				colorPrint(c.State, fmt.Sprintf("%s %s\n", c.ID, c.Text))
+				if c.Regression {
+					colorPrint(c.State, fmt.Sprintf("%s %s (regression)\n", c.ID, c.Text))
+				} else {
+					colorPrint(c.State, fmt.Sprintf("%s %s\n", c.ID, c.Text))
+				}
 
-				if includeTestOutput && c.State == check.FAIL && len(c.ActualValue) > 0 {
-					printRawOutput(c.ActualValue)
+				if includeTestOutput && c.State == check.FAIL {
+					if c.Expected != "" {
+						printRawOutput(fmt.Sprintf("expected: %s", c.Expected))
+					}
+					for _, tr := range c.TestResults {
+						if !tr.Pass {
+							printRawOutput(fmt.Sprintf("failed test: %s", tr.ExpectedResult))
+						}
+					}
+					if len(c.ActualValue) > 0 {
+						printRawOutput(c.ActualValue)
+					}
 				}
 			}
 		}
@@ -176,12 +309,13 @@ func prettyPrint(r *check.Controls, summary check.Summary) {
 
 	// Print remediations.
 	if !noRemediations {
-		if summary.Fail > 0 || summary.Warn > 0 {
+		if summary.Fail > 0 || summary.Warn > 0 || summary.Error > 0 {
 			colors[check.WARN].Printf("== Remediations ==\n")
 			for _, g := range r.Groups {
 				for _, c := range g.Checks {
 					if c.State == check.FAIL {
 						fmt.Printf("%s %s\n", c.ID, c.Remediation)
+						printRemediationNotes(c)
 					}
 					if c.State == check.WARN {
 						// Print the error if test failed due to problem with the audit command
@@ -189,8 +323,12 @@ func prettyPrint(r *check.Controls, summary check.Summary) {
 							fmt.Printf("%s audit test did not run: %s\n", c.ID, c.Reason)
 						} else {
 							fmt.Printf("%s %s\n", c.ID, c.Remediation)
+							printRemediationNotes(c)
 						}
 					}
+					if c.State == check.ERROR {
+						fmt.Printf("%s audit test did not complete: %s\n", c.ID, c.Reason)
+					}
 				}
 			}
 			fmt.Println()
@@ -199,19 +337,66 @@ func prettyPrint(r *check.Controls, summary check.Summary) {
 
 	// Print summary setting output color to highest severity.
 	if !noSummary {
-		var res check.State
-		if summary.Fail > 0 {
-			res = check.FAIL
-		} else if summary.Warn > 0 {
-			res = check.WARN
-		} else {
-			res = check.PASS
+		printSummary("== Summary ==", summary)
+		printCoverage(r.Coverage)
+		if r.Timestamp != "" {
+			fmt.Printf("Checked at %s\n", displayTime(r.Timestamp))
 		}
+	}
+}
 
-		colors[res].Printf("== Summary ==\n")
-		fmt.Printf("%d checks PASS\n%d checks FAIL\n%d checks WARN\n%d checks INFO\n",
-			summary.Pass, summary.Fail, summary.Warn, summary.Info,
-		)
+// printRemediationNotes prints the impact of applying the remediation of a
+// check and the default value of the setting, when the benchmark gives them.
+func printRemediationNotes(c *check.Check) {
+	if c.Impact != "" {
+		fmt.Printf("Impact: %s\n", strings.TrimSpace(c.Impact))
+	}
+	if c.DefaultValue != "" {
+		fmt.Printf("Default Value: %s\n", strings.TrimSpace(c.DefaultValue))
+	}
+}
+
+// printSummary outputs the summary counts under the given title.
+func printSummary(title string, summary check.Summary) {
+	var res check.State
+	if summary.Fail > 0 || summary.Error > 0 {
+		res = check.FAIL
+	} else if summary.Warn > 0 {
+		res = check.WARN
+	} else {
+		res = check.PASS
+	}
+
+	colors[res].Printf("%s\n", title)
+	fmt.Printf("%d checks PASS\n%d checks FAIL\n%d checks WARN\n%d checks INFO\n",
+		summary.Pass, summary.Fail, summary.Warn, summary.Info,
+	)
+	if summary.Incomplete > 0 {
+		fmt.Printf("%d checks INCOMPLETE\n", summary.Incomplete)
+	}
+	if summary.Error > 0 {
+		fmt.Printf("%d checks ERROR\n", summary.Error)
+	}
+}
+
+// printCoverage outputs how many of the checks were executed, overall and
+// per section, so that the summary can't hide the checks that never ran.
+func printCoverage(coverage check.Coverage) {
+	if coverage.Total == 0 {
+		return
+	}
+	res := check.PASS
+	if coverage.Executed < coverage.Total {
+		res = check.WARN
+	}
+
+	colors[res].Printf("== Coverage ==\n")
+	fmt.Printf("%d of %d checks executed (%.1f%%)\n", coverage.Executed, coverage.Total, coverage.Percent())
+	fmt.Printf("%d checks MANUAL\n%d checks SKIPPED\n%d checks NOT EVALUATED\n%d checks FILTERED\n",
+		coverage.Manual, coverage.Skipped, coverage.NotEvaluated, coverage.Filtered,
+	)
+	for _, s := range coverage.Sections {
+		fmt.Printf("%s %s: %d of %d executed (%.1f%%)\n", s.Section, s.Text, s.Executed, s.Total, s.Percent())
 	}
 }
 
@@ -239,12 +424,12 @@ func loadConfig(nodetype check.NodeType) string {
 
 	benchmarkVersion, err := getBenchmarkVersion(kubeVersion, benchmarkVersion, viper.GetViper())
 	if err != nil {
-		exitWithError(fmt.Errorf("failed to get benchMark version: %v", err))
+		exitWithError(fmt.Errorf("failed to get benchMark version: %w", err))
 	}
 
 	path, err := getConfigFilePath(benchmarkVersion, file)
 	if err != nil {
-		exitWithError(fmt.Errorf("can't find %s controls file in %s: %v", nodetype, cfgDir, err))
+		exitWithError(configError{fmt.Errorf("can't find %s controls file in %s: %v", nodetype, cfgDir, err)})
 	}
 
 	// Merge version-specific config if any.
@@ -288,36 +473,27 @@ func mapToBenchmarkVersion(kubeToBenchmarkMap map[string]string, kv string) (str
 	return cisVersion, nil
 }
 
-func loadVersionMapping(v *viper.Viper) (map[string]string, error) {
-	kubeToBenchmarkMap := v.GetStringMapString("version_mapping")
-	if kubeToBenchmarkMap == nil || (len(kubeToBenchmarkMap) == 0) {
-		return nil, fmt.Errorf("config file is missing 'version_mapping' section")
-	}
-
-	return kubeToBenchmarkMap, nil
-}
-
 func getBenchmarkVersion(kubeVersion, benchmarkVersion string, v *viper.Viper) (bv string, err error) {
 	if !isEmpty(kubeVersion) && !isEmpty(benchmarkVersion) {
-		return "", fmt.Errorf("It is an error to specify both --version and --benchmark flags")
+		return "", configError{fmt.Errorf("It is an error to specify both --version and --benchmark flags")}
 	}
 
 	if isEmpty(benchmarkVersion) {
 		if isEmpty(kubeVersion) {
 			kubeVersion, err = getKubeVersion()
 			if err != nil {
-				return "", fmt.Errorf("Version check failed: %s\nAlternatively, you can specify the version with --version", err)
+				return "", environmentError{fmt.Errorf("Version check failed: %s\nAlternatively, you can specify the version with --version", err)}
 			}
 		}
 
 		kubeToBenchmarkMap, err := loadVersionMapping(v)
 		if err != nil {
-			return "", err
+			return "", configError{err}
 		}
 
 		benchmarkVersion, err = mapToBenchmarkVersion(kubeToBenchmarkMap, kubeVersion)
 		if err != nil {
-			return "", err
+			return "", configError{err}
 		}
 
 		glog.V(2).Info(fmt.Sprintf("Mapped Kubernetes version: %s to Benchmark version: %s", kubeVersion, benchmarkVersion))
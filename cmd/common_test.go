@@ -20,6 +20,7 @@ import (
 	"io/ioutil"
 	"os"
 	"path/filepath"
+	"strings"
 	"testing"
 
 	"github.com/aquasecurity/kube-bench/check"
@@ -537,3 +538,46 @@ func prunePath() (restoreFn, error) {
 	}
 	return restorePath, nil
 }
+
+func TestGetOutputFormat(t *testing.T) {
+	cases := []struct {
+		format string
+		json   bool
+		junit  bool
+		exp    string
+	}{
+		{exp: ""},
+		{json: true, exp: "json"},
+		{junit: true, exp: "junit"},
+		{json: true, junit: true, exp: "junit"},
+		{format: "json", junit: true, exp: "json"},
+	}
+
+	defer func() {
+		outputFormat, jsonFmt, junitFmt = "", false, false
+	}()
+
+	for _, c := range cases {
+		outputFormat, jsonFmt, junitFmt = c.format, c.json, c.junit
+		assert.Equal(t, c.exp, getOutputFormat())
+	}
+}
+
+func TestGetExpectedValues(t *testing.T) {
+	v := viper.New()
+	v.SetConfigType("yaml")
+	assert.NoError(t, v.ReadConfig(strings.NewReader(`
+expected_values:
+  "1.2.35":
+    "--tls-cipher-suites": "TLS_A,TLS_B"
+  "4.2.4":
+    "{.readOnlyPort}": 10255
+  "4.2.5": invalid
+`)))
+
+	assert.Equal(t, map[string]map[string]string{
+		"1.2.35": {"--tls-cipher-suites": "TLS_A,TLS_B"},
+		"4.2.4":  {"{.readonlyport}": "10255"},
+	}, getExpectedValues(v))
+	assert.Empty(t, getExpectedValues(viper.New()))
+}
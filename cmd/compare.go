@@ -0,0 +1,275 @@
+package cmd
+
+import (
+	"bytes"
+	"encoding/json"
+	"fmt"
+	"io"
+	"os"
+	"path/filepath"
+	"sort"
+	"strings"
+
+	"github.com/aquasecurity/kube-bench/check"
+	"github.com/spf13/cobra"
+)
+
+var compareAll bool
+
+func init() {
+	RootCmd.AddCommand(compareCmd)
+	compareCmd.Flags().BoolVar(&compareAll, "all", false, "Also list the checks whose state differs without passing on one side and failing on the other")
+}
+
+// compareCmd represents the compare command
+var compareCmd = &cobra.Command{
+	Use:   "compare <left> <right>",
+	Short: "Compare the JSON results of two clusters",
+	Long: `Compare the JSON results of two clusters, e.g. staging and production, to catch
+configuration drift between them. Each side is a JSON results file, or a directory
+of such files holding the results of several nodes. The results of the nodes of a
+side are aggregated, a check being in the worst state it has on any node, and the
+checks that pass on one side but fail on the other are listed.`,
+	Args: cobra.ExactArgs(2),
+	Run: func(cmd *cobra.Command, args []string) {
+		left, err := loadReports(args[0])
+		if err != nil {
+			exitWithError(err)
+		}
+		right, err := loadReports(args[1])
+		if err != nil {
+			exitWithError(err)
+		}
+
+		diffs := compareReports(aggregateReports(left), aggregateReports(right))
+		if jsonFmt {
+			out, err := json.Marshal(diffs)
+			if err != nil {
+				exitWithError(fmt.Errorf("failed to output in JSON format: %v", err))
+			}
+			PrintOutput(string(out), outputFile)
+			return
+		}
+
+		printComparison(args[0], args[1], diffs, compareAll)
+	},
+}
+
+// aggregateCheck is the state of a check on all the nodes of a report.
+type aggregateCheck struct {
+	NodeType check.NodeType
+	ID       string
+	Text     string
+	Scored   bool
+	State    check.State
+	Nodes    int
+}
+
+// reportDiff is a check whose aggregated state differs between two reports.
+type reportDiff struct {
+	NodeType check.NodeType `json:"node_type"`
+	ID       string         `json:"test_number"`
+	Text     string         `json:"test_desc"`
+	Scored   bool           `json:"scored"`
+	Left     check.State    `json:"left"`
+	Right    check.State    `json:"right"`
+	Drift    bool           `json:"drift"`
+}
+
+// stateSeverity orders states from best to worst. Checks which couldn't be
+// evaluated on a node, because the scan was interrupted or their audit
+// failed, rank worse than passing ones so that they aren't hidden by the
+// nodes on which they passed.
+var stateSeverity = map[check.State]int{
+	check.PASS:       1,
+	check.INFO:       2,
+	check.WARN:       3,
+	check.INCOMPLETE: 4,
+	check.ERROR:      5,
+	check.FAIL:       6,
+}
+
+// loadReports reads the results in a JSON file, or in all the JSON files of a
+// directory. A file may hold several results objects, as written by kube-bench
+// --json for several targets, or an array of them. Results sent in chunks to
+// a sink with a size limit are reassembled from the files of their chunks.
+func loadReports(path string) ([]*check.Controls, error) {
+	info, err := os.Stat(path)
+	if err != nil {
+		return nil, err
+	}
+
+	files := []string{path}
+	if info.IsDir() {
+		files, err = filepath.Glob(filepath.Join(path, "*.json"))
+		if err != nil {
+			return nil, err
+		}
+		if len(files) == 0 {
+			return nil, fmt.Errorf("no JSON results found in %s", path)
+		}
+	}
+
+	var reports []*check.Controls
+	var chunks []*payloadChunk
+	for _, file := range files {
+		f, err := os.Open(file)
+		if err != nil {
+			return nil, fmt.Errorf("failed to read results from %s: %v", file, err)
+		}
+		r, c, err := decodeReports(f)
+		f.Close()
+		if err != nil {
+			return nil, fmt.Errorf("failed to read results from %s: %v", file, err)
+		}
+		reports = append(reports, r...)
+		chunks = append(chunks, c...)
+	}
+
+	payloads, err := reassemblePayloads(chunks)
+	if err != nil {
+		return nil, fmt.Errorf("failed to reassemble results from %s: %v", path, err)
+	}
+	for _, payload := range payloads {
+		r, _, err := decodeReports(bytes.NewReader(payload))
+		if err != nil {
+			return nil, fmt.Errorf("failed to read reassembled results from %s: %v", path, err)
+		}
+		reports = append(reports, r...)
+	}
+	return reports, nil
+}
+
+// decodeReports decodes the results objects, or arrays of them, of in, along
+// with the chunks of results it holds.
+func decodeReports(in io.Reader) ([]*check.Controls, []*payloadChunk, error) {
+	var reports []*check.Controls
+	var chunks []*payloadChunk
+	dec := json.NewDecoder(in)
+	for {
+		var raw json.RawMessage
+		if err := dec.Decode(&raw); err == io.EOF {
+			break
+		} else if err != nil {
+			return nil, nil, err
+		}
+
+		if strings.HasPrefix(strings.TrimSpace(string(raw)), "[") {
+			var list []*check.Controls
+			if err := json.Unmarshal(raw, &list); err != nil {
+				return nil, nil, err
+			}
+			reports = append(reports, list...)
+			continue
+		}
+
+		if chunk, ok := decodePayloadChunk(raw); ok {
+			chunks = append(chunks, chunk)
+			continue
+		}
+
+		c := new(check.Controls)
+		if err := json.Unmarshal(raw, c); err != nil {
+			return nil, nil, err
+		}
+		reports = append(reports, c)
+	}
+	return reports, chunks, nil
+}
+
+// aggregateReports merges the results of several nodes, keeping the worst state
+// of each check, keyed by node type and check ID.
+func aggregateReports(reports []*check.Controls) map[string]*aggregateCheck {
+	checks := make(map[string]*aggregateCheck)
+	for _, r := range reports {
+		for _, g := range r.Groups {
+			for _, c := range g.Checks {
+				key := fmt.Sprintf("%s/%s", r.Type, c.ID)
+				a, ok := checks[key]
+				if !ok {
+					a = &aggregateCheck{NodeType: r.Type, ID: c.ID, Text: c.Text, Scored: c.Scored, State: c.State}
+					checks[key] = a
+				}
+				if stateSeverity[c.State] > stateSeverity[a.State] {
+					a.State = c.State
+				}
+				a.Nodes++
+			}
+		}
+	}
+	return checks
+}
+
+// compareReports returns the checks whose state differs between two aggregated
+// reports, sorted by node type and check ID. A check that is missing on one side
+// has an empty state there.
+func compareReports(left, right map[string]*aggregateCheck) []reportDiff {
+	keys := make(map[string]bool)
+	for k := range left {
+		keys[k] = true
+	}
+	for k := range right {
+		keys[k] = true
+	}
+
+	var diffs []reportDiff
+	for k := range keys {
+		l, r := left[k], right[k]
+		d := reportDiff{}
+		for _, a := range []*aggregateCheck{l, r} {
+			if a != nil {
+				d.NodeType, d.ID, d.Text, d.Scored = a.NodeType, a.ID, a.Text, a.Scored
+			}
+		}
+		if l != nil {
+			d.Left = l.State
+		}
+		if r != nil {
+			d.Right = r.State
+		}
+		if d.Left == d.Right {
+			continue
+		}
+		d.Drift = (d.Left == check.PASS && d.Right == check.FAIL) || (d.Left == check.FAIL && d.Right == check.PASS)
+		diffs = append(diffs, d)
+	}
+
+	sort.Slice(diffs, func(i, j int) bool {
+		if diffs[i].NodeType != diffs[j].NodeType {
+			return diffs[i].NodeType < diffs[j].NodeType
+		}
+		return diffs[i].ID < diffs[j].ID
+	})
+	return diffs
+}
+
+func printComparison(leftName, rightName string, diffs []reportDiff, all bool) {
+	state := func(s check.State) string {
+		if s == "" {
+			return "-"
+		}
+		return string(s)
+	}
+
+	drift := 0
+	colorPrint(check.INFO, fmt.Sprintf("Comparing %s (left) and %s (right)\n", leftName, rightName))
+	for _, d := range diffs {
+		if d.Drift {
+			drift++
+		} else if !all {
+			continue
+		}
+
+		res := check.WARN
+		if d.Drift {
+			res = check.FAIL
+		}
+		colorPrint(res, fmt.Sprintf("%s %s %s: %s / %s\n", d.NodeType, d.ID, d.Text, state(d.Left), state(d.Right)))
+	}
+
+	fmt.Println()
+	fmt.Printf("%d checks pass on one side and fail on the other\n", drift)
+	if all {
+		fmt.Printf("%d checks differ in total\n", len(diffs))
+	}
+}
@@ -0,0 +1,81 @@
+package cmd
+
+import (
+	"io/ioutil"
+	"os"
+	"path/filepath"
+	"testing"
+
+	"github.com/aquasecurity/kube-bench/check"
+	"github.com/stretchr/testify/assert"
+)
+
+func compareControls(states ...check.State) *check.Controls {
+	g := &check.Group{ID: "4.2"}
+	for i, s := range states {
+		g.Checks = append(g.Checks, &check.Check{ID: "4.2." + string(rune('1'+i)), State: s})
+	}
+	return &check.Controls{Type: check.NODE, Groups: []*check.Group{g}}
+}
+
+func TestAggregateReports(t *testing.T) {
+	agg := aggregateReports([]*check.Controls{
+		compareControls(check.PASS, check.PASS, check.WARN),
+		compareControls(check.PASS, check.FAIL, check.INFO),
+	})
+
+	assert.Equal(t, check.PASS, agg["node/4.2.1"].State)
+	assert.Equal(t, check.FAIL, agg["node/4.2.2"].State, "a check fails if it fails on any node")
+	assert.Equal(t, check.WARN, agg["node/4.2.3"].State)
+	assert.Equal(t, 2, agg["node/4.2.1"].Nodes)
+
+	// Checks which couldn't be evaluated on a node aren't reported as passing.
+	agg = aggregateReports([]*check.Controls{
+		compareControls(check.PASS, check.PASS, check.INCOMPLETE, check.FAIL),
+		compareControls(check.INCOMPLETE, check.ERROR, check.ERROR, check.ERROR),
+		compareControls(check.PASS, check.PASS, check.PASS, check.PASS),
+	})
+	assert.Equal(t, check.INCOMPLETE, agg["node/4.2.1"].State)
+	assert.Equal(t, check.ERROR, agg["node/4.2.2"].State)
+	assert.Equal(t, check.ERROR, agg["node/4.2.3"].State)
+	assert.Equal(t, check.FAIL, agg["node/4.2.4"].State)
+}
+
+func TestCompareReports(t *testing.T) {
+	left := aggregateReports([]*check.Controls{compareControls(check.PASS, check.FAIL, check.WARN, check.PASS)})
+	right := aggregateReports([]*check.Controls{compareControls(check.FAIL, check.PASS, check.PASS)})
+
+	diffs := compareReports(left, right)
+	assert.Equal(t, []reportDiff{
+		{NodeType: check.NODE, ID: "4.2.1", Left: check.PASS, Right: check.FAIL, Drift: true},
+		{NodeType: check.NODE, ID: "4.2.2", Left: check.FAIL, Right: check.PASS, Drift: true},
+		{NodeType: check.NODE, ID: "4.2.3", Left: check.WARN, Right: check.PASS},
+		{NodeType: check.NODE, ID: "4.2.4", Left: check.PASS, Right: ""},
+	}, diffs)
+}
+
+func TestLoadReports(t *testing.T) {
+	dir, err := ioutil.TempDir("", "kube-bench-compare")
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer os.RemoveAll(dir)
+
+	// Output of --json for two targets, and an array as served on /results.
+	ioutil.WriteFile(filepath.Join(dir, "node-1.json"), []byte(`{"node_type":"master","tests":[]}
+{"node_type":"node","tests":[{"section":"4.2","results":[{"test_number":"4.2.1","status":"FAIL"}]}]}`), 0644)
+	ioutil.WriteFile(filepath.Join(dir, "node-2.json"), []byte(`[{"node_type":"node","tests":[]}]`), 0644)
+	ioutil.WriteFile(filepath.Join(dir, "notes.txt"), []byte("ignored"), 0644)
+
+	reports, err := loadReports(dir)
+	assert.NoError(t, err)
+	assert.Len(t, reports, 3)
+	assert.Equal(t, check.FAIL, reports[1].Groups[0].Checks[0].State)
+
+	reports, err = loadReports(filepath.Join(dir, "node-2.json"))
+	assert.NoError(t, err)
+	assert.Len(t, reports, 1)
+
+	_, err = loadReports(filepath.Join(dir, "missing"))
+	assert.Error(t, err)
+}
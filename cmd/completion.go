@@ -0,0 +1,386 @@
+package cmd
+
+import (
+	"bytes"
+	"fmt"
+	"io"
+	"io/ioutil"
+	"os"
+	"path/filepath"
+	"sort"
+	"strings"
+
+	"github.com/aquasecurity/kube-bench/check"
+	"github.com/spf13/cobra"
+	"github.com/spf13/pflag"
+	"github.com/spf13/viper"
+	"gopkg.in/yaml.v2"
+)
+
+// bashCompletionFunctions complete --check and --group with the IDs of the
+// benchmark selected on the command line, after the last comma of the value.
+const bashCompletionFunctions = `
+__kube-bench_complete_ids()
+{
+    local ids
+    ids=$("${words[0]}" __complete-ids "$1" "${words[@]:1}" 2>/dev/null | cut -f1)
+    local prefix=""
+    if [[ ${cur} == *,* ]]; then
+        prefix="${cur%,*},"
+    fi
+    COMPREPLY=( $(compgen -P "${prefix}" -W "${ids}" -- "${cur##*,}") )
+}
+
+__kube-bench_complete_checks()
+{
+    __kube-bench_complete_ids checks
+}
+
+__kube-bench_complete_groups()
+{
+    __kube-bench_complete_ids groups
+}
+`
+
+func init() {
+	RootCmd.AddCommand(completionCmd)
+	RootCmd.AddCommand(completeIDsCmd)
+}
+
+// completionCmd represents the completion command
+var completionCmd = &cobra.Command{
+	Use:   "completion [bash|zsh|fish]",
+	Short: "Output shell completion code",
+	Long: `Output shell completion code for bash, zsh or fish. Besides commands and flags,
+--check and --group complete with the IDs of the checks and groups of the
+benchmark selected by --benchmark or --version, or of every benchmark of the
+config directory, along with their descriptions in zsh and fish.
+
+  source <(kube-bench completion bash)
+  kube-bench completion zsh > "${fpath[1]}/_kube-bench"
+  kube-bench completion fish > ~/.config/fish/completions/kube-bench.fish`,
+	Args:      cobra.ExactArgs(1),
+	ValidArgs: []string{"bash", "zsh", "fish"},
+	Run: func(cmd *cobra.Command, args []string) {
+		// The root command is named after the path kube-bench was run with,
+		// while completion applies to the name of the program.
+		RootCmd.Use = filepath.Base(RootCmd.Use)
+
+		var err error
+		switch args[0] {
+		case "bash":
+			RootCmd.BashCompletionFunction = bashCompletionFunctions
+			cobra.MarkFlagCustom(RootCmd.PersistentFlags(), "check", "__kube-bench_complete_checks")
+			cobra.MarkFlagCustom(RootCmd.PersistentFlags(), "trace-check", "__kube-bench_complete_checks")
+			cobra.MarkFlagCustom(RootCmd.PersistentFlags(), "group", "__kube-bench_complete_groups")
+			err = RootCmd.GenBashCompletion(os.Stdout)
+		case "zsh":
+			err = genZshCompletion(os.Stdout, RootCmd)
+		case "fish":
+			err = genFishCompletion(os.Stdout, RootCmd)
+		default:
+			err = fmt.Errorf("unsupported shell %q, expected one of %v", args[0], cmd.ValidArgs)
+		}
+		if err != nil {
+			exitWithError(err)
+		}
+	},
+}
+
+// completeIDsCmd lists the IDs of the checks or groups for the completion
+// scripts, which pass the words of the command line being completed.
+var completeIDsCmd = &cobra.Command{
+	Use:                "__complete-ids [checks|groups] [words...]",
+	Hidden:             true,
+	DisableFlagParsing: true,
+	Args:               cobra.MinimumNArgs(1),
+	Run: func(cmd *cobra.Command, args []string) {
+		for _, id := range completionIDs(args[0], args[1:]) {
+			fmt.Printf("%s\t%s\n", id.ID, id.Text)
+		}
+	},
+}
+
+type completionID struct {
+	ID   string
+	Text string
+}
+
+// completionIDs returns the IDs of the checks or groups of the benchmark and
+// targets given in words, in order. Without a benchmark or a version, the
+// Kubernetes version isn't detected, which would be slow, and the IDs of all
+// benchmarks are returned.
+func completionIDs(kind string, words []string) []completionID {
+	fs := pflag.NewFlagSet("completion", pflag.ContinueOnError)
+	fs.ParseErrorsWhitelist.UnknownFlags = true
+	fs.SetOutput(ioutil.Discard)
+	benchmark := fs.String("benchmark", benchmarkVersion, "")
+	version := fs.String("version", kubeVersion, "")
+	dir := fs.StringP("config-dir", "D", cfgDir, "")
+	targets := fs.StringSliceP("targets", "s", nil, "")
+	fs.Parse(words)
+
+	var benchmarks []string
+	switch {
+	case *benchmark != "":
+		benchmarks = []string{*benchmark}
+	case *version != "":
+		v := viper.New()
+		v.SetConfigFile(filepath.Join(*dir, "config.yaml"))
+		if err := v.ReadInConfig(); err != nil {
+			return nil
+		}
+		mapping, err := mergeVersionMappings(versionMappingProviders(v, *dir))
+		if err != nil {
+			return nil
+		}
+		b, err := mapToBenchmarkVersion(mapping, *version)
+		if err != nil {
+			return nil
+		}
+		benchmarks = []string{b}
+	default:
+		entries, err := ioutil.ReadDir(*dir)
+		if err != nil {
+			return nil
+		}
+		for _, e := range entries {
+			if e.IsDir() && e.Name() != "mock" && e.Name() != versionMappingDir {
+				benchmarks = append(benchmarks, e.Name())
+			}
+		}
+	}
+
+	var ids []completionID
+	seen := make(map[string]bool)
+	add := func(id, text string) {
+		if id != "" && !seen[id] {
+			seen[id] = true
+			ids = append(ids, completionID{ID: id, Text: text})
+		}
+	}
+	for _, b := range benchmarks {
+		var files []string
+		for _, target := range *targets {
+			files = append(files, filepath.Join(*dir, b, translate(target)+".yaml"))
+		}
+		if len(files) == 0 {
+			files, _ = getYamlFilesFromDir(filepath.Join(*dir, b))
+		}
+
+		for _, file := range files {
+			in, err := ioutil.ReadFile(file)
+			if err != nil {
+				continue
+			}
+			controls := new(check.Controls)
+			if err := yaml.Unmarshal(in, controls); err != nil {
+				continue
+			}
+			for _, g := range controls.Groups {
+				if g == nil {
+					continue
+				}
+				if kind == "groups" {
+					add(g.ID, g.Text)
+					continue
+				}
+				for _, c := range g.Checks {
+					if c != nil {
+						add(c.ID, c.Text)
+					}
+				}
+			}
+		}
+	}
+	return ids
+}
+
+// completionCommands returns cmd and its available subcommands, depth first.
+func completionCommands(cmd *cobra.Command) []*cobra.Command {
+	commands := []*cobra.Command{cmd}
+	for _, c := range cmd.Commands() {
+		if c.IsAvailableCommand() && c.Name() != "help" {
+			commands = append(commands, completionCommands(c)...)
+		}
+	}
+	return commands
+}
+
+// completionPath returns the names of the commands from the root to cmd.
+func completionPath(cmd *cobra.Command, sep string) string {
+	if !cmd.HasParent() {
+		return cmd.Name()
+	}
+	return completionPath(cmd.Parent(), sep) + sep + cmd.Name()
+}
+
+// completionFlags returns the flags to complete for cmd, other than those of
+// the root, which apply everywhere.
+func completionFlags(cmd *cobra.Command) []*pflag.Flag {
+	var flags []*pflag.Flag
+	add := func(f *pflag.Flag) {
+		if !f.Hidden && f.Name != "help" {
+			flags = append(flags, f)
+		}
+	}
+	if !cmd.HasParent() {
+		cmd.PersistentFlags().VisitAll(add)
+		return flags
+	}
+
+	cmd.LocalFlags().VisitAll(add)
+	cmd.InheritedFlags().VisitAll(func(f *pflag.Flag) {
+		if cmd.Root().PersistentFlags().Lookup(f.Name) == nil {
+			add(f)
+		}
+	})
+	return flags
+}
+
+func isBoolFlag(f *pflag.Flag) bool {
+	return f.NoOptDefVal != ""
+}
+
+// flagUsage returns the first line of the usage of a flag.
+func flagUsage(f *pflag.Flag) string {
+	return strings.TrimSpace(strings.SplitN(f.Usage, "\n", 2)[0])
+}
+
+func genZshCompletion(w io.Writer, root *cobra.Command) error {
+	name := root.Name()
+	// Parameter names can't have dashes, unlike function names.
+	flagsVar := "__" + strings.Replace(name, "-", "_", -1) + "_flags"
+	desc := strings.NewReplacer(`\`, `\\`, `[`, `\[`, `]`, `\]`, `'`, `'\''`, ":", `\:`)
+	spec := func(f *pflag.Flag) []string {
+		usage := desc.Replace(flagUsage(f))
+		if isBoolFlag(f) {
+			specs := []string{fmt.Sprintf("'--%s[%s]'", f.Name, usage)}
+			if f.Shorthand != "" {
+				specs = append(specs, fmt.Sprintf("'-%s[%s]'", f.Shorthand, usage))
+			}
+			return specs
+		}
+
+		action := ""
+		switch f.Name {
+		case "check", "trace-check":
+			action = "__" + name + "_ids checks"
+		case "group":
+			action = "__" + name + "_ids groups"
+		case "config", "outputfile", "checkpoint", "file", "facts", "baseline", "lock-file":
+			action = "_files"
+		case "config-dir", "exporter-dir", "config-overlay", "output-dir":
+			action = "_files -/"
+		}
+		specs := []string{fmt.Sprintf("'--%s=[%s]:%s:%s'", f.Name, usage, f.Name, action)}
+		if f.Shorthand != "" {
+			specs = append(specs, fmt.Sprintf("'-%s+[%s]:%s:%s'", f.Shorthand, usage, f.Name, action))
+		}
+		return specs
+	}
+
+	var buf bytes.Buffer
+	fmt.Fprintf(&buf, "#compdef %s\n\n", name)
+	fmt.Fprintf(&buf, `__%[1]s_ids() {
+  local -a ids
+  ids=(${(f)"$($words[1] __complete-ids $1 ${words[2,-1]} 2>/dev/null | sed -e 's/:/\\:/g' -e 's/	/:/')"})
+  compset -P '*,'
+  _describe -t $1 $1 ids
+}
+
+`, name)
+
+	commands := completionCommands(root)
+	fmt.Fprintf(&buf, "%s=(\n", flagsVar)
+	for _, f := range completionFlags(root) {
+		for _, s := range spec(f) {
+			fmt.Fprintf(&buf, "  %s\n", s)
+		}
+	}
+	buf.WriteString(")\n\n")
+
+	fmt.Fprintf(&buf, "_%s() {\n  local cmd=%s word\n  for word in ${words[2,CURRENT-1]}; do\n    case \"$cmd/$word\" in\n", name, name)
+	var paths []string
+	for _, c := range commands[1:] {
+		paths = append(paths, completionPath(c, "/"))
+	}
+	sort.Strings(paths)
+	fmt.Fprintf(&buf, "      %s) cmd=\"$cmd/$word\" ;;\n    esac\n  done\n\n  case $cmd in\n", strings.Join(paths, "|"))
+	for _, c := range commands {
+		fmt.Fprintf(&buf, "    %s)\n      _arguments -s \"${%s[@]}\"", completionPath(c, "/"), flagsVar)
+		if c.HasParent() {
+			for _, f := range completionFlags(c) {
+				for _, s := range spec(f) {
+					fmt.Fprintf(&buf, " \\\n        %s", s)
+				}
+			}
+		}
+		var subs []string
+		for _, sub := range c.Commands() {
+			if sub.IsAvailableCommand() && sub.Name() != "help" {
+				subs = append(subs, fmt.Sprintf(`%s\:"%s"`, sub.Name(), strings.Replace(desc.Replace(sub.Short), `"`, `\"`, -1)))
+			}
+		}
+		if len(subs) > 0 {
+			fmt.Fprintf(&buf, " \\\n        '1:command:((%s))'", strings.Join(subs, " "))
+		}
+		buf.WriteString(" \\\n        '*: :'\n      ;;\n")
+	}
+	fmt.Fprintf(&buf, "  esac\n}\n\n_%s \"$@\"\n", name)
+
+	_, err := buf.WriteTo(w)
+	return err
+}
+
+func genFishCompletion(w io.Writer, root *cobra.Command) error {
+	name := root.Name()
+	quote := strings.NewReplacer(`\`, `\\`, `'`, `\'`)
+
+	var buf bytes.Buffer
+	commands := completionCommands(root)
+	fmt.Fprintf(&buf, "function __%s_command\n    set -l cmd %s\n    for word in (commandline -opc)[2..-1]\n        switch \"$cmd $word\"\n            case", name, name)
+	for _, c := range commands[1:] {
+		fmt.Fprintf(&buf, " '%s'", completionPath(c, " "))
+	}
+	buf.WriteString("\n                set cmd \"$cmd $word\"\n        end\n    end\n    echo $cmd\nend\n\n")
+	fmt.Fprintf(&buf, `function __%[1]s_ids
+    set -l words (commandline -opc)
+    set -l token (string replace -r '^-[^=]*=' '' -- (commandline -ct))
+    set -l prefix (string match -r '.*,' -- $token)
+    $words[1] __complete-ids $argv[1] $words[2..-1] 2>/dev/null | string replace -r '^' "$prefix"
+end
+
+`, name)
+
+	for _, c := range commands {
+		condition := fmt.Sprintf("-n 'test (__%s_command) = \"%s\"' ", name, completionPath(c, " "))
+		for _, sub := range c.Commands() {
+			if sub.IsAvailableCommand() && sub.Name() != "help" {
+				fmt.Fprintf(&buf, "complete -c %s -f %s-a %s -d '%s'\n", name, condition, sub.Name(), quote.Replace(sub.Short))
+			}
+		}
+		if !c.HasParent() {
+			condition = ""
+		}
+		for _, f := range completionFlags(c) {
+			fmt.Fprintf(&buf, "complete -c %s %s-l %s", name, condition, f.Name)
+			if f.Shorthand != "" {
+				fmt.Fprintf(&buf, " -s %s", f.Shorthand)
+			}
+			switch {
+			case isBoolFlag(f):
+			case f.Name == "check" || f.Name == "trace-check":
+				fmt.Fprintf(&buf, " -x -a '(__%s_ids checks)'", name)
+			case f.Name == "group":
+				fmt.Fprintf(&buf, " -x -a '(__%s_ids groups)'", name)
+			default:
+				buf.WriteString(" -r")
+			}
+			fmt.Fprintf(&buf, " -d '%s'\n", quote.Replace(flagUsage(f)))
+		}
+	}
+
+	_, err := buf.WriteTo(w)
+	return err
+}
@@ -0,0 +1,41 @@
+package cmd
+
+import (
+	"bytes"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+)
+
+func TestCompletionIDs(t *testing.T) {
+	groups := completionIDs("groups", []string{"run", "--config-dir", "../cfg", "--benchmark", "cis-1.5", "-s", "node", "--json"})
+	assert.Equal(t, []completionID{{ID: "4.1", Text: "Worker Node Configuration Files"}, {ID: "4.2", Text: "Kubelet"}, {ID: "proxy", Text: "kube-proxy (not part of the CIS Benchmark)"}}, groups)
+
+	checks := completionIDs("checks", []string{"-D", "../cfg", "--version", "1.15", "--targets=node", "--check=4.1"})
+	assert.Equal(t, "4.1.1", checks[0].ID)
+	assert.Contains(t, checks[0].Text, "kubelet service file permissions")
+
+	// Without a benchmark, the IDs of every benchmark are listed once.
+	all := completionIDs("groups", []string{"-D", "../cfg", "-s", "node"})
+	seen := make(map[string]bool)
+	for _, id := range all {
+		assert.False(t, seen[id.ID], id.ID)
+		seen[id.ID] = true
+	}
+	assert.True(t, seen["4.1"])
+	assert.True(t, seen["7"], "groups of the cis-1.3 and cis-1.4 node benchmark")
+}
+
+func TestCompletionScripts(t *testing.T) {
+	var zsh bytes.Buffer
+	assert.NoError(t, genZshCompletion(&zsh, RootCmd))
+	assert.Contains(t, zsh.String(), "#compdef "+RootCmd.Name())
+	assert.Contains(t, zsh.String(), "_ids checks'")
+	assert.Contains(t, zsh.String(), `run\:"Run tests"`)
+
+	var fish bytes.Buffer
+	assert.NoError(t, genFishCompletion(&fish, RootCmd))
+	assert.Contains(t, fish.String(), "-l check -s c -x -a '(__"+RootCmd.Name()+"_ids checks)'")
+	assert.Contains(t, fish.String(), "-l parallel-targets -d 'Run the checks of the different targets concurrently'")
+	assert.NotContains(t, fish.String(), "__complete-ids -d", "hidden commands aren't completed")
+}
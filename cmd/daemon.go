@@ -0,0 +1,126 @@
+package cmd
+
+import (
+	"fmt"
+	"time"
+
+	"github.com/aquasecurity/kube-bench/check"
+	"github.com/golang/glog"
+	"github.com/spf13/cobra"
+	"github.com/spf13/viper"
+)
+
+var daemonInterval time.Duration
+
+func init() {
+	RootCmd.AddCommand(daemonCmd)
+	daemonCmd.Flags().StringSliceP("targets", "s", []string{}, "Specify targets of the benchmark to run, as with the run command")
+	daemonCmd.Flags().DurationVar(&daemonInterval, "interval", 5*time.Minute, "Interval between runs of the checks")
+	daemonCmd.Flags().DurationVar(&reloadInterval, "reload-interval", time.Minute, "Interval between checks for changes to the controls files, which are reloaded without restarting. Never reloaded if 0")
+}
+
+// daemonCmd represents the daemon command
+var daemonCmd = &cobra.Command{
+	Use:   "daemon",
+	Short: "Run tests continuously and notify state changes",
+	Long: `Run tests continuously and notify state changes. The result of each check is
+kept in memory, the checks are run again every interval, and only the checks
+whose state changed since the previous run are sent to the notification sinks.
+Use --check and --group to run a subset of the checks.`,
+	Run: func(cmd *cobra.Command, args []string) {
+		targets, err := cmd.Flags().GetStringSlice("targets")
+		if err != nil {
+			exitWithError(fmt.Errorf("unable to get `targets` from command line :%v", err))
+		}
+
+		notifiers := getNotifiers(viper.Sub("notifications"))
+		if len(notifiers) == 0 {
+			exitWithError(fmt.Errorf("no notification sinks are configured for drift events"))
+		}
+
+		w := watchDefinitions(targets)
+		d := newDriftDetector()
+		flag := cmd.Flag("scan-id")
+		baseScanID, explicit := scanID, flag != nil && flag.Changed
+		for run := 1; ; run++ {
+			scanID = daemonScanID(baseScanID, explicit, run)
+			results := w.get().runTargets(false)
+			if err := targetsError(results); err != nil {
+				// The last known states are kept, to be compared with the next run.
+				glog.Warningf("Failed to run the checks: %v", err)
+				w.wait(daemonInterval)
+				continue
+			}
+
+			var controls []*check.Controls
+			for _, r := range results {
+				controls = append(controls, r.controls)
+			}
+
+			if drift := d.detect(controls); len(drift) > 0 {
+				glog.V(1).Info(fmt.Sprintf("%d checks changed state", len(drift)))
+				batch := notifyBatch{ScanID: scanID, Event: eventDrift, Key: "drift", Findings: drift}
+				for _, n := range notifiers {
+					if err := n.Notify(batch); err != nil {
+						glog.Warningf("%s drift notification failed: %v", n.Name(), err)
+					}
+				}
+			}
+
+			w.wait(daemonInterval)
+		}
+	},
+}
+
+// daemonScanID returns the scan ID of a run of the daemon, so that every run,
+// and the drift events it sends, can be told apart downstream. Runs get a new
+// ID, or the one of --scan-id followed by the number of the run.
+func daemonScanID(base string, explicit bool, run int) string {
+	if explicit {
+		return fmt.Sprintf("%s-%d", base, run)
+	}
+	return newScanID()
+}
+
+// driftDetector records the last known state of each check, to find the
+// checks whose state changed from one run to the next.
+type driftDetector struct {
+	last map[string]check.State
+}
+
+func newDriftDetector() *driftDetector {
+	return &driftDetector{last: make(map[string]check.State)}
+}
+
+// detect returns the checks whose state differs from their last known state,
+// and records the new states. Checks that weren't run before only set the
+// baseline, so the first run doesn't emit any event.
+func (d *driftDetector) detect(controls []*check.Controls) []notifyFinding {
+	var drift []notifyFinding
+	for _, c := range controls {
+		for _, g := range c.Groups {
+			for _, ch := range g.Checks {
+				key := findingKey(c.Type, ch.ID)
+				prev, seen := d.last[key]
+				d.last[key] = ch.State
+				if !seen || prev == ch.State {
+					continue
+				}
+
+				drift = append(drift, notifyFinding{
+					NodeType:    c.Type,
+					Section:     fmt.Sprintf("%s %s", c.ID, c.Text),
+					Group:       fmt.Sprintf("%s %s", g.ID, g.Text),
+					ID:          ch.ID,
+					Text:        ch.Text,
+					Remediation: ch.Remediation,
+					State:       ch.State,
+					Owner:       ch.Owner,
+					Annotations: ch.Annotations,
+					Previous:    prev,
+				})
+			}
+		}
+	}
+	return drift
+}
@@ -0,0 +1,29 @@
+package cmd
+
+import (
+	"testing"
+
+	"github.com/aquasecurity/kube-bench/check"
+	"github.com/stretchr/testify/assert"
+)
+
+func TestDriftDetector(t *testing.T) {
+	d := newDriftDetector()
+	assert.Empty(t, d.detect([]*check.Controls{notifyControls(check.PASS, check.FAIL)}), "the first run sets the baseline")
+	assert.Empty(t, d.detect([]*check.Controls{notifyControls(check.PASS, check.FAIL)}))
+
+	drift := d.detect([]*check.Controls{notifyControls(check.FAIL, check.PASS, check.WARN)})
+	assert.Len(t, drift, 2, "new checks are not drift")
+	assert.Equal(t, "1.1.1", drift[0].ID)
+	assert.Equal(t, check.PASS, drift[0].Previous)
+	assert.Equal(t, check.FAIL, drift[0].State)
+	assert.Equal(t, check.FAIL, drift[1].Previous)
+	assert.Equal(t, check.PASS, drift[1].State)
+
+	assert.Empty(t, d.detect([]*check.Controls{notifyControls(check.FAIL, check.PASS, check.WARN)}))
+}
+
+func TestDaemonScanID(t *testing.T) {
+	assert.NotEqual(t, daemonScanID("", false, 1), daemonScanID("", false, 2))
+	assert.Equal(t, "nightly-2", daemonScanID("nightly", true, 2))
+}
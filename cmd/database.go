@@ -11,7 +11,7 @@ import (
 	"github.com/spf13/viper"
 )
 
-func savePgsql(jsonInfo string) {
+func savePgsql(jsonInfo string, scanID string) {
 	envVars := map[string]string{
 		"PGSQL_HOST":     viper.GetString("PGSQL_HOST"),
 		"PGSQL_USER":     viper.GetString("PGSQL_USER"),
@@ -38,12 +38,16 @@ func savePgsql(jsonInfo string) {
 	if err != nil {
 		exitWithError(fmt.Errorf("received error looking up hostname: %s", err))
 	}
+	if anonymize {
+		hostname = getAnonymizer().anonymize(hostname)
+	}
 
-	timestamp := time.Now()
+	timestamp := time.Now().UTC()
 
 	type ScanResult struct {
 		gorm.Model
 		ScanHost string    `gorm:"type:varchar(63) not null"` // https://www.ietf.org/rfc/rfc1035.txt
+		ScanID   string    `gorm:"type:varchar(64)"`
 		ScanTime time.Time `gorm:"not null"`
 		ScanInfo string    `gorm:"type:jsonb not null"`
 	}
@@ -55,6 +59,6 @@ func savePgsql(jsonInfo string) {
 	defer db.Close()
 	
 	db.Debug().AutoMigrate(&ScanResult{})
-	db.Save(&ScanResult{ScanHost: hostname, ScanTime: timestamp, ScanInfo: jsonInfo})
+	db.Save(&ScanResult{ScanHost: hostname, ScanID: scanID, ScanTime: timestamp, ScanInfo: jsonInfo})
 	glog.V(2).Info(fmt.Sprintf("successfully stored result to: %s", envVars["PGSQL_HOST"]))
 }
@@ -0,0 +1,120 @@
+package cmd
+
+import (
+	"fmt"
+	"io/ioutil"
+	"os"
+	"path/filepath"
+	"strings"
+
+	"github.com/aquasecurity/kube-bench/check"
+	"github.com/spf13/cobra"
+	"github.com/spf13/viper"
+)
+
+var (
+	docFormat    string
+	docOutputDir string
+)
+
+// docFormats are the documentation formats of docs generate, with the
+// extension of their files.
+var docFormats = map[string]struct {
+	ext    string
+	render func(*check.Controls) ([]byte, error)
+}{
+	"markdown": {".md", (*check.Controls).Markdown},
+	"html":     {".html", (*check.Controls).HTML},
+}
+
+func init() {
+	RootCmd.AddCommand(docsCmd)
+	docsCmd.AddCommand(docsGenerateCmd)
+	docsGenerateCmd.Flags().StringSliceP("targets", "s", []string{}, "Specify targets of the benchmark to document, as with the run command")
+	docsGenerateCmd.Flags().StringVar(&docFormat, "doc-format", "markdown", "Format of the documentation, markdown or html")
+	docsGenerateCmd.Flags().StringVar(&docOutputDir, "output-dir", "", "Write the documentation of each target to <target>.md or <target>.html in this directory instead of the standard output")
+}
+
+// docsCmd represents the docs command
+var docsCmd = &cobra.Command{
+	Use:   "docs",
+	Short: "Document the benchmarks",
+}
+
+// docsGenerateCmd represents the docs generate command
+var docsGenerateCmd = &cobra.Command{
+	Use:   "generate",
+	Short: "Render the checks of a benchmark as a control document",
+	Long: `Render the checks of the benchmark selected like with the run command as a
+human-readable control document, with the audit, expected result and remediation of
+every check, one section per target. The document is generated from the controls
+files and overlays, so it can't drift from what kube-bench checks. Variables such as
+$apiserverbin are kept as they are, facts are substituted with --facts.`,
+	Run: func(cmd *cobra.Command, args []string) {
+		format, ok := docFormats[docFormat]
+		if !ok {
+			exitWithError(fmt.Errorf("unknown documentation format %q, valid formats are html and markdown", docFormat))
+		}
+		targets, err := cmd.Flags().GetStringSlice("targets")
+		if err != nil {
+			exitWithError(fmt.Errorf("unable to get `targets` from command line :%v", err))
+		}
+
+		benchmarkVersion := resolveBenchmark(targets)
+		yamlFiles, err := getTestYamlFiles(targets, benchmarkVersion)
+		if err != nil {
+			exitWithError(err)
+		}
+
+		if docOutputDir != "" {
+			if err := os.MkdirAll(docOutputDir, 0755); err != nil {
+				exitWithError(fmt.Errorf("failed to create documentation directory: %v", err))
+			}
+		}
+		for _, yamlFile := range yamlFiles {
+			controls, err := loadDocControls(yamlFile)
+			if err != nil {
+				exitWithError(err)
+			}
+			out, err := format.render(controls)
+			if err != nil {
+				exitWithError(fmt.Errorf("failed to document %s: %v", yamlFile, err))
+			}
+
+			if docOutputDir == "" {
+				fmt.Println(string(out))
+				continue
+			}
+			file := filepath.Join(docOutputDir, strings.TrimSuffix(filepath.Base(yamlFile), ".yaml")+format.ext)
+			if err := ioutil.WriteFile(file, out, 0644); err != nil {
+				exitWithError(fmt.Errorf("failed to write documentation: %v", err))
+			}
+			fmt.Printf("Documentation written to %s\n", file)
+		}
+	},
+}
+
+// loadDocControls loads the controls of yamlFile for documentation, with its
+// overlays, without looking for the components of the host to substitute.
+func loadDocControls(yamlFile string) (*check.Controls, error) {
+	in, err := ioutil.ReadFile(yamlFile)
+	if err != nil {
+		return nil, fmt.Errorf("error opening %s test file: %v", yamlFile, err)
+	}
+
+	substitute := func(s string) string { return s }
+	if factsFile != "" {
+		substitute = substituteFacts
+	}
+
+	nodetype := targetType(yamlFile)
+	controls, err := check.LoadControls(nodetype, []byte(substitute(string(in))), check.LoadOptions{File: yamlFile, AllowUnknownFields: allowUnknownFields, Strict: strictControls})
+	if err != nil {
+		return nil, err
+	}
+	if err := applyOverlays(controls, nodetype, yamlFile, ioutil.ReadFile, substitute); err != nil {
+		return nil, err
+	}
+	controls.SetOwners(viper.GetStringMapString("owners"))
+	return controls, nil
+}
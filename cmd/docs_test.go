@@ -0,0 +1,18 @@
+package cmd
+
+import (
+	"strings"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+)
+
+func TestLoadDocControls(t *testing.T) {
+	controls, err := loadDocControls("../cfg/cis-1.5/node.yaml")
+	assert.NoError(t, err)
+	assert.Equal(t, "4.1.1", controls.Groups[0].Checks[0].ID)
+	assert.True(t, strings.Contains(controls.Groups[0].Checks[0].Audit, "$kubeletsvc"), "variables are documented as they are")
+
+	_, err = loadDocControls("../cfg/cis-1.5/missing.yaml")
+	assert.Error(t, err)
+}
@@ -0,0 +1,211 @@
+package cmd
+
+import (
+	"bytes"
+	"crypto/sha256"
+	"encoding/hex"
+	"fmt"
+	"io"
+	"io/fs"
+	"io/ioutil"
+	"os"
+	"path/filepath"
+
+	"github.com/golang/glog"
+)
+
+// EmbeddedCfg is the default config directory built into the binary. It is
+// used when the config directory isn't found on the host and --config-dir
+// wasn't set, so that a lone binary can run the benchmarks.
+var EmbeddedCfg fs.FS
+
+// useEmbeddedCfg points cfgDir at an extracted copy of EmbeddedCfg when the
+// default config directory doesn't exist. A config directory on the host, or
+// given with --config-dir, always takes precedence, and --config-overlay still
+// applies over the built-in one.
+func useEmbeddedCfg() {
+	if EmbeddedCfg == nil || RootCmd.PersistentFlags().Changed("config-dir") {
+		return
+	}
+	if _, err := os.Stat(cfgDir); !os.IsNotExist(err) {
+		return
+	}
+
+	dir, err := extractCfg(EmbeddedCfg, cacheDir())
+	if err != nil {
+		exitWithError(fmt.Errorf("failed to extract the built-in config directory: %v", err))
+	}
+	glog.V(1).Info(fmt.Sprintf("Config directory %s not found, using the built-in one in %s\n", cfgDir, dir))
+	cfgDir = dir
+}
+
+// rootCacheDir holds the cache directory of root when it has none of its own.
+var rootCacheDir = "/var/cache"
+
+// cacheDir returns the directory to extract the built-in config directory to.
+// Without a cache directory of its own, such as in a container without HOME,
+// root uses one under /var/cache, and other users one of their own under the
+// temporary directory, which extractCfg rejects if another user created it
+// first.
+func cacheDir() string {
+	if root, err := os.UserCacheDir(); err == nil {
+		return filepath.Join(root, "kube-bench")
+	}
+	if os.Geteuid() == 0 {
+		return filepath.Join(rootCacheDir, "kube-bench")
+	}
+	return filepath.Join(os.TempDir(), fmt.Sprintf("kube-bench-%d", os.Geteuid()))
+}
+
+// extractCfg writes the files of fsys to a directory under root named after
+// their digest, unless an earlier run already did, and returns that directory.
+// As the audits of the files run as root, the files of an earlier run are only
+// reused when root and the files are private to the user and hold the same
+// files as fsys.
+func extractCfg(fsys fs.FS, root string) (string, error) {
+	h := sha256.New()
+	err := fs.WalkDir(fsys, ".", func(path string, d fs.DirEntry, err error) error {
+		if err != nil || d.IsDir() {
+			return err
+		}
+		in, err := fs.ReadFile(fsys, path)
+		if err != nil {
+			return err
+		}
+		fmt.Fprintf(h, "%s\x00%d\x00", path, len(in))
+		h.Write(in)
+		return nil
+	})
+	if err != nil {
+		return "", err
+	}
+
+	if err := os.MkdirAll(root, 0700); err != nil {
+		return "", err
+	}
+	if err := checkPrivate(root, true); err != nil {
+		return "", err
+	}
+
+	dir := filepath.Join(root, "cfg-"+hex.EncodeToString(h.Sum(nil))[:16])
+	if _, err := os.Lstat(dir); err == nil {
+		verifyErr := verifyCfg(fsys, dir)
+		if verifyErr == nil {
+			return dir, nil
+		}
+		glog.Warningf("Extracting the built-in config directory again, %v", verifyErr)
+		if err := os.RemoveAll(dir); err != nil {
+			return "", err
+		}
+	}
+
+	// Extract to a temporary directory renamed into place once complete, so that
+	// concurrent runs never see a partial config directory.
+	tmp, err := ioutil.TempDir(root, ".cfg-")
+	if err != nil {
+		return "", err
+	}
+	defer os.RemoveAll(tmp)
+
+	err = fs.WalkDir(fsys, ".", func(path string, d fs.DirEntry, err error) error {
+		if err != nil {
+			return err
+		}
+		target := filepath.Join(tmp, filepath.FromSlash(path))
+		if d.IsDir() {
+			return os.MkdirAll(target, 0755)
+		}
+		return copyFSFile(fsys, path, target)
+	})
+	if err != nil {
+		return "", err
+	}
+
+	if err := os.Rename(tmp, dir); err != nil {
+		// Another run may have extracted the same files first.
+		if verifyErr := verifyCfg(fsys, dir); verifyErr == nil {
+			return dir, nil
+		}
+		return "", err
+	}
+	return dir, nil
+}
+
+// verifyCfg checks that dir holds the files of fsys and nothing else, and that
+// they are private to the user.
+func verifyCfg(fsys fs.FS, dir string) error {
+	seen := make(map[string]bool)
+	err := filepath.Walk(dir, func(path string, fi os.FileInfo, err error) error {
+		if err != nil {
+			return err
+		}
+		if err := checkPrivate(path, fi.IsDir()); err != nil {
+			return err
+		}
+		rel, err := filepath.Rel(dir, path)
+		if err != nil {
+			return err
+		}
+		name := filepath.ToSlash(rel)
+		seen[name] = true
+		if fi.IsDir() {
+			return nil
+		}
+		want, err := fs.ReadFile(fsys, name)
+		if err != nil {
+			return fmt.Errorf("%s is not a built-in file", path)
+		}
+		got, err := ioutil.ReadFile(path)
+		if err != nil {
+			return err
+		}
+		if !bytes.Equal(got, want) {
+			return fmt.Errorf("%s differs from the built-in file", path)
+		}
+		return nil
+	})
+	if err != nil {
+		return err
+	}
+
+	return fs.WalkDir(fsys, ".", func(path string, d fs.DirEntry, err error) error {
+		if err != nil {
+			return err
+		}
+		if !seen[path] {
+			return fmt.Errorf("%s is missing from %s", path, dir)
+		}
+		return nil
+	})
+}
+
+// checkPrivate checks that path is a directory, or a regular file, owned by
+// the user and which other users can't write to.
+func checkPrivate(path string, isDir bool) error {
+	fi, err := os.Lstat(path)
+	if err != nil {
+		return err
+	}
+	if fi.IsDir() != isDir || !(fi.IsDir() || fi.Mode().IsRegular()) {
+		return fmt.Errorf("%s is not a regular file or directory", path)
+	}
+	return checkOwner(path, fi)
+}
+
+func copyFSFile(fsys fs.FS, path, target string) error {
+	in, err := fsys.Open(path)
+	if err != nil {
+		return err
+	}
+	defer in.Close()
+
+	out, err := os.OpenFile(target, os.O_WRONLY|os.O_CREATE|os.O_EXCL, 0644)
+	if err != nil {
+		return err
+	}
+	if _, err := io.Copy(out, in); err != nil {
+		out.Close()
+		return err
+	}
+	return out.Close()
+}
@@ -0,0 +1,116 @@
+package cmd
+
+import (
+	"fmt"
+	"io/fs"
+	"io/ioutil"
+	"os"
+	"path/filepath"
+	"testing"
+	"testing/fstest"
+
+	"github.com/stretchr/testify/assert"
+)
+
+func TestExtractCfg(t *testing.T) {
+	root, err := ioutil.TempDir("", "kube-bench-embedded")
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer os.RemoveAll(root)
+
+	fsys := fstest.MapFS{
+		"config.yaml":       {Data: []byte("version_mapping:\n  \"1.15\": \"cis-1.5\"\n")},
+		"cis-1.5/node.yaml": {Data: []byte("controls:\n")},
+	}
+	dir, err := extractCfg(fsys, root)
+	assert.NoError(t, err)
+	in, err := ioutil.ReadFile(filepath.Join(dir, "cis-1.5", "node.yaml"))
+	assert.NoError(t, err)
+	assert.Equal(t, "controls:\n", string(in))
+
+	again, err := extractCfg(fsys, root)
+	assert.NoError(t, err)
+	assert.Equal(t, dir, again, "the files are extracted once")
+
+	fsys["cis-1.5/node.yaml"] = &fstest.MapFile{Data: []byte("controls:\ngroups:\n")}
+	changed, err := extractCfg(fsys, root)
+	assert.NoError(t, err)
+	assert.NotEqual(t, dir, changed, "other files are extracted to another directory")
+
+	entries, err := ioutil.ReadDir(root)
+	assert.NoError(t, err)
+	assert.Len(t, entries, 2, "no temporary directory is left behind")
+
+	// Files changed or added since they were extracted aren't reused.
+	node := filepath.Join(changed, "cis-1.5", "node.yaml")
+	assert.NoError(t, ioutil.WriteFile(node, []byte("controls:\ngroups: []\n"), 0644))
+	again, err = extractCfg(fsys, root)
+	assert.NoError(t, err)
+	assert.Equal(t, changed, again)
+	in, err = ioutil.ReadFile(node)
+	assert.NoError(t, err)
+	assert.Equal(t, "controls:\ngroups:\n", string(in))
+
+	extra := filepath.Join(changed, "cis-1.5", "master.yaml")
+	assert.NoError(t, ioutil.WriteFile(extra, []byte("controls:\n"), 0644))
+	_, err = extractCfg(fsys, root)
+	assert.NoError(t, err)
+	_, err = os.Stat(extra)
+	assert.True(t, os.IsNotExist(err))
+
+	assert.NoError(t, os.Chmod(node, 0666))
+	_, err = extractCfg(fsys, root)
+	assert.NoError(t, err)
+	fi, err := os.Stat(node)
+	assert.NoError(t, err)
+	assert.Equal(t, os.FileMode(0644), fi.Mode().Perm())
+
+	// A directory other users can write to isn't used at all.
+	assert.NoError(t, os.Chmod(root, 0777))
+	_, err = extractCfg(fsys, root)
+	assert.Error(t, err)
+}
+
+func TestUseEmbeddedCfg(t *testing.T) {
+	cache, err := ioutil.TempDir("", "kube-bench-cache")
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer os.RemoveAll(cache)
+	defer os.Setenv("XDG_CACHE_HOME", os.Getenv("XDG_CACHE_HOME"))
+	os.Setenv("XDG_CACHE_HOME", cache)
+	defer func(fsys fs.FS, dir string) { EmbeddedCfg, cfgDir = fsys, dir }(EmbeddedCfg, cfgDir)
+	EmbeddedCfg = fstest.MapFS{"config.yaml": {Data: []byte("{}\n")}}
+
+	cfgDir = "../cfg"
+	useEmbeddedCfg()
+	assert.Equal(t, "../cfg", cfgDir, "an existing config directory takes precedence")
+
+	cfgDir = filepath.Join(cache, "missing")
+	useEmbeddedCfg()
+	assert.Equal(t, filepath.Join(cache, "kube-bench"), filepath.Dir(cfgDir))
+	assert.FileExists(t, filepath.Join(cfgDir, "config.yaml"))
+}
+
+func TestCacheDirWithoutHome(t *testing.T) {
+	tmp, err := ioutil.TempDir("", "kube-bench-cache")
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer os.RemoveAll(tmp)
+	for _, env := range []string{"HOME", "XDG_CACHE_HOME", "TMPDIR"} {
+		defer os.Setenv(env, os.Getenv(env))
+	}
+	os.Unsetenv("HOME")
+	os.Unsetenv("XDG_CACHE_HOME")
+	os.Setenv("TMPDIR", tmp)
+	defer func(dir string) { rootCacheDir = dir }(rootCacheDir)
+	rootCacheDir = tmp
+
+	expected := filepath.Join(tmp, fmt.Sprintf("kube-bench-%d", os.Geteuid()))
+	if os.Geteuid() == 0 {
+		expected = filepath.Join(tmp, "kube-bench")
+	}
+	assert.Equal(t, expected, cacheDir(), "every run uses the same directory")
+}
@@ -0,0 +1,21 @@
+//go:build !windows
+// +build !windows
+
+package cmd
+
+import (
+	"fmt"
+	"os"
+)
+
+// checkOwner checks that the file at path is owned by the user and that
+// other users can't write to it.
+func checkOwner(path string, fi os.FileInfo) error {
+	if fi.Mode().Perm()&0022 != 0 {
+		return fmt.Errorf("%s is writable by other users", path)
+	}
+	if uid, _, ok := fileIDs(fi); !ok || int(uid) != os.Geteuid() {
+		return fmt.Errorf("%s is not owned by the user", path)
+	}
+	return nil
+}
@@ -0,0 +1,135 @@
+package cmd
+
+import (
+	"errors"
+	"fmt"
+	"os"
+	"sync"
+
+	"github.com/aquasecurity/kube-bench/check"
+	"github.com/golang/glog"
+)
+
+const (
+	// exitCodePolicyLegacy exits with 0 whatever the results, and with 1 on
+	// any error.
+	exitCodePolicyLegacy = "legacy"
+	// exitCodePolicyDetailed exits with a code telling what happened, so that
+	// wrapper scripts don't have to parse the output.
+	exitCodePolicyDetailed = "detailed"
+)
+
+// Exit codes of the detailed policy. regressionExitCode and lockedExitCode
+// apply with both policies.
+const (
+	exitSuccess          = 0
+	exitError            = 1
+	exitScoredFailures   = 2
+	exitWarnings         = 5
+	exitConfigError      = 6
+	exitEnvironmentError = 7
+	exitPartialRun       = 8
+)
+
+var (
+	exitCodePolicy = exitCodePolicyLegacy
+
+	// runSummary adds up the results of the targets of the run.
+	runSummary   check.Summary
+	runSummaryMu sync.Mutex
+)
+
+// configError is an error in the config, controls files or flags of the run.
+type configError struct{ error }
+
+// environmentError is an error in the host or cluster kube-bench runs against,
+// such as components which aren't running.
+type environmentError struct{ error }
+
+// partialRunError is the error of a run interrupted before all its checks ran.
+type partialRunError struct{ error }
+
+func (e configError) Unwrap() error      { return e.error }
+func (e environmentError) Unwrap() error { return e.error }
+func (e partialRunError) Unwrap() error  { return e.error }
+
+// validateExitCodePolicy checks the value of --exit-code-policy.
+func validateExitCodePolicy() error {
+	if exitCodePolicy != exitCodePolicyLegacy && exitCodePolicy != exitCodePolicyDetailed {
+		policy := exitCodePolicy
+		exitCodePolicy = exitCodePolicyLegacy
+		return configError{fmt.Errorf("unknown --exit-code-policy %q, valid policies are detailed and legacy", policy)}
+	}
+	return nil
+}
+
+// errorExitCode returns the exit code of a run which failed with err.
+func errorExitCode(err error) int {
+	if exitCodePolicy != exitCodePolicyDetailed {
+		return exitError
+	}
+	var ce configError
+	var ee environmentError
+	var pe partialRunError
+	switch {
+	case errors.As(err, &ce):
+		return exitConfigError
+	case errors.As(err, &ee):
+		return exitEnvironmentError
+	case errors.As(err, &pe):
+		return exitPartialRun
+	}
+	return exitError
+}
+
+// resultsExitCode returns the exit code of a run with the results of summary.
+// A run with checks which errored or weren't run is partial, whatever the
+// results of the others.
+func resultsExitCode(summary check.Summary) int {
+	if exitCodePolicy != exitCodePolicyDetailed {
+		return exitSuccess
+	}
+	switch {
+	case summary.Incomplete > 0 || summary.Error > 0:
+		return exitPartialRun
+	case summary.Fail > 0:
+		return exitScoredFailures
+	case summary.Warn > 0:
+		return exitWarnings
+	}
+	return exitSuccess
+}
+
+// recordResults adds the results of a target to those of the run.
+func recordResults(summary check.Summary) {
+	runSummaryMu.Lock()
+	defer runSummaryMu.Unlock()
+	runSummary.Pass += summary.Pass
+	runSummary.Fail += summary.Fail
+	runSummary.Warn += summary.Warn
+	runSummary.Info += summary.Info
+	runSummary.Incomplete += summary.Incomplete
+	runSummary.Error += summary.Error
+}
+
+// exitWithResults exits with the code of the results of the run, unless it is
+// 0.
+func exitWithResults() {
+	runSummaryMu.Lock()
+	code := resultsExitCode(runSummary)
+	runSummaryMu.Unlock()
+	if code != exitSuccess {
+		glog.Flush()
+		os.Exit(code)
+	}
+}
+
+// exitIfRunFailed exits with exitConfigError when the targets of the run
+// couldn't be found, with the detailed policy. The legacy policy only reports
+// the error.
+func exitIfRunFailed(err error) {
+	if err != nil && exitCodePolicy == exitCodePolicyDetailed {
+		glog.Flush()
+		os.Exit(exitConfigError)
+	}
+}
@@ -0,0 +1,80 @@
+package cmd
+
+import (
+	"fmt"
+	"testing"
+
+	"github.com/aquasecurity/kube-bench/check"
+	"github.com/stretchr/testify/assert"
+)
+
+func TestErrorExitCode(t *testing.T) {
+	defer func(policy string) { exitCodePolicy = policy }(exitCodePolicy)
+
+	cases := []struct {
+		err      error
+		legacy   int
+		detailed int
+	}{
+		{err: fmt.Errorf("failed to output in JSON format"), legacy: 1, detailed: exitError},
+		{err: configError{fmt.Errorf("invalid --timezone")}, legacy: 1, detailed: exitConfigError},
+		{err: fmt.Errorf("unable to get benchmark version: %w", configError{fmt.Errorf("no mapping")}), legacy: 1, detailed: exitConfigError},
+		{err: fmt.Errorf("unable to get benchmark version: %w", environmentError{fmt.Errorf("version check failed")}), legacy: 1, detailed: exitEnvironmentError},
+		{err: partialRunError{fmt.Errorf("scan interrupted")}, legacy: 1, detailed: exitPartialRun},
+	}
+	for _, c := range cases {
+		exitCodePolicy = exitCodePolicyLegacy
+		assert.Equal(t, c.legacy, errorExitCode(c.err), c.err.Error())
+		exitCodePolicy = exitCodePolicyDetailed
+		assert.Equal(t, c.detailed, errorExitCode(c.err), c.err.Error())
+	}
+
+	// The message of the error is kept.
+	assert.EqualError(t, configError{fmt.Errorf("invalid --timezone")}, "invalid --timezone")
+}
+
+func TestResultsExitCode(t *testing.T) {
+	defer func(policy string) { exitCodePolicy = policy }(exitCodePolicy)
+
+	cases := []struct {
+		summary  check.Summary
+		expected int
+	}{
+		{summary: check.Summary{}, expected: exitSuccess},
+		{summary: check.Summary{Pass: 10, Info: 2}, expected: exitSuccess},
+		{summary: check.Summary{Pass: 10, Warn: 2}, expected: exitWarnings},
+		{summary: check.Summary{Pass: 10, Warn: 2, Fail: 1}, expected: exitScoredFailures},
+		{summary: check.Summary{Pass: 10, Fail: 1, Incomplete: 3}, expected: exitPartialRun},
+		{summary: check.Summary{Pass: 10, Error: 1}, expected: exitPartialRun},
+	}
+	for _, c := range cases {
+		exitCodePolicy = exitCodePolicyDetailed
+		assert.Equal(t, c.expected, resultsExitCode(c.summary), "%+v", c.summary)
+		exitCodePolicy = exitCodePolicyLegacy
+		assert.Equal(t, exitSuccess, resultsExitCode(c.summary), "%+v", c.summary)
+	}
+}
+
+func TestRecordResults(t *testing.T) {
+	defer func(s check.Summary) { runSummary = s }(runSummary)
+	runSummary = check.Summary{}
+
+	recordResults(check.Summary{Pass: 2, Fail: 1})
+	recordResults(check.Summary{Warn: 3, Info: 1, Error: 1})
+	assert.Equal(t, check.Summary{Pass: 2, Fail: 1, Warn: 3, Info: 1, Error: 1}, runSummary)
+}
+
+func TestValidateExitCodePolicy(t *testing.T) {
+	defer func(policy string) { exitCodePolicy = policy }(exitCodePolicy)
+
+	for _, policy := range []string{exitCodePolicyLegacy, exitCodePolicyDetailed} {
+		exitCodePolicy = policy
+		assert.NoError(t, validateExitCodePolicy())
+	}
+
+	exitCodePolicy = "strict"
+	err := validateExitCodePolicy()
+	assert.EqualError(t, err, `unknown --exit-code-policy "strict", valid policies are detailed and legacy`)
+	assert.Equal(t, exitCodePolicyLegacy, exitCodePolicy)
+	assert.Equal(t, exitError, errorExitCode(err))
+}
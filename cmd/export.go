@@ -0,0 +1,231 @@
+package cmd
+
+import (
+	"bytes"
+	"context"
+	"encoding/base64"
+	"encoding/json"
+	"fmt"
+	"io/ioutil"
+	"net/http"
+	"os"
+	"os/exec"
+	"path/filepath"
+	"sort"
+	"strings"
+	"time"
+
+	"github.com/aquasecurity/kube-bench/check"
+	"github.com/golang/glog"
+	"github.com/spf13/viper"
+)
+
+const (
+	exporterPluginPrefix  = "kube-bench-exporter-"
+	exporterPluginTimeout = time.Minute
+)
+
+// exportClient is used by the built-in exporters that talk to HTTP APIs.
+var exportClient = &http.Client{Timeout: 30 * time.Second}
+
+// Exporter sends the results of a target to an external destination.
+type Exporter interface {
+	Name() string
+	Export(controls *check.Controls) error
+}
+
+// exporterFactory builds an Exporter from its section of the exporters configuration.
+type exporterFactory func(v *viper.Viper) (Exporter, error)
+
+// exporterFactories holds the exporters built into kube-bench, by name.
+var exporterFactories = map[string]exporterFactory{}
+
+// getExporters resolves the names selected with --export to exporters, first looking
+// at the built-in exporters and then at the plugins found in the exporter directory.
+// If no names are selected, every plugin discovered in the directory is used.
+func getExporters(names []string, pluginDir string, v *viper.Viper) ([]Exporter, error) {
+	plugins, err := discoverExporterPlugins(pluginDir)
+	if err != nil {
+		return nil, err
+	}
+
+	if len(names) == 0 {
+		for name := range plugins {
+			names = append(names, name)
+		}
+		sort.Strings(names)
+	}
+
+	var exporters []Exporter
+	for _, name := range names {
+		conf := exporterConfig(v, name)
+		if factory, ok := exporterFactories[name]; ok {
+			e, err := factory(conf)
+			if err != nil {
+				return nil, fmt.Errorf("failed to set up %s exporter: %v", name, err)
+			}
+			exporters = append(exporters, e)
+			continue
+		}
+
+		path, ok := plugins[name]
+		if !ok {
+			return nil, fmt.Errorf("unknown exporter %q", name)
+		}
+		exporters = append(exporters, &pluginExporter{name: name, path: path, conf: conf})
+	}
+
+	return exporters, nil
+}
+
+// exporterConfig returns the exporters.<name> section of the configuration, never nil.
+func exporterConfig(v *viper.Viper, name string) *viper.Viper {
+	if v != nil {
+		if sub := v.Sub("exporters." + name); sub != nil {
+			return sub
+		}
+	}
+	return viper.New()
+}
+
+// discoverExporterPlugins returns the executables named kube-bench-exporter-<name>
+// found in dir, keyed by name.
+func discoverExporterPlugins(dir string) (map[string]string, error) {
+	plugins := make(map[string]string)
+	if dir == "" {
+		return plugins, nil
+	}
+
+	files, err := ioutil.ReadDir(dir)
+	if err != nil {
+		return nil, fmt.Errorf("unable to read exporter directory %s: %v", dir, err)
+	}
+
+	for _, f := range files {
+		if f.IsDir() || !strings.HasPrefix(f.Name(), exporterPluginPrefix) {
+			continue
+		}
+		if f.Mode()&0111 == 0 {
+			glog.V(1).Info(fmt.Sprintf("Ignoring exporter plugin %s: not executable", f.Name()))
+			continue
+		}
+
+		name := strings.TrimPrefix(f.Name(), exporterPluginPrefix)
+		plugins[name] = filepath.Join(dir, f.Name())
+		glog.V(2).Info(fmt.Sprintf("Found exporter plugin %q: %s", name, plugins[name]))
+	}
+
+	return plugins, nil
+}
+
+// pluginExporter runs an external binary with the JSON results on its stdin.
+// Its configuration is passed through KUBE_BENCH_EXPORTER_<KEY> environment variables.
+type pluginExporter struct {
+	name string
+	path string
+	conf *viper.Viper
+}
+
+func (p *pluginExporter) Name() string { return p.name }
+
+func (p *pluginExporter) Export(controls *check.Controls) error {
+	out, err := controls.JSON()
+	if err != nil {
+		return err
+	}
+
+	ctx, cancel := context.WithTimeout(context.Background(), exporterPluginTimeout)
+	defer cancel()
+
+	cmd := exec.CommandContext(ctx, p.path)
+	cmd.Stdin = bytes.NewReader(out)
+	cmd.Env = append(os.Environ(), p.env(controls)...)
+
+	var stderr bytes.Buffer
+	cmd.Stderr = &stderr
+	if err := cmd.Run(); err != nil {
+		return fmt.Errorf("%s: %v: %s", p.path, err, strings.TrimSpace(stderr.String()))
+	}
+	return nil
+}
+
+func (p *pluginExporter) env(controls *check.Controls) []string {
+	env := []string{
+		fmt.Sprintf("%s_EXPORTER_NAME=%s", envVarsPrefix, p.name),
+		fmt.Sprintf("%s_SCAN_ID=%s", envVarsPrefix, controls.ScanID),
+		fmt.Sprintf("%s_NODE_TYPE=%s", envVarsPrefix, controls.Type),
+	}
+
+	keys := p.conf.AllKeys()
+	sort.Strings(keys)
+	for _, k := range keys {
+		name := strings.ToUpper(strings.NewReplacer(".", "_", "-", "_").Replace(k))
+		env = append(env, fmt.Sprintf("%s_EXPORTER_%s=%s", envVarsPrefix, name, p.conf.GetString(k)))
+	}
+	return env
+}
+
+// exportResults sends the results of a target to every selected exporter.
+// Failing exporters are reported but don't stop the run, only an error setting
+// them up is returned.
+func exportResults(controls *check.Controls) error {
+	exporters, err := getExporters(exportNames, exporterDir, viper.GetViper())
+	if err != nil {
+		return fmt.Errorf("failed to set up exporters: %v", err)
+	}
+
+	for _, e := range exporters {
+		glog.V(1).Info(fmt.Sprintf("Exporting %s results with %s", controls.Type, e.Name()))
+		if err := e.Export(controls); err != nil {
+			glog.Warningf("%s exporter failed: %v", e.Name(), err)
+		}
+	}
+	return nil
+}
+
+// doJSON sends in as the JSON body of a request to url, and decodes the JSON
+// response into out unless it is nil. A []byte in is sent as is, for bodies
+// that are already encoded. Any non-2xx response is an error.
+func doJSON(method, url string, header http.Header, in, out interface{}) error {
+	var body bytes.Buffer
+	switch b := in.(type) {
+	case nil:
+	case []byte:
+		body.Write(b)
+	default:
+		if err := json.NewEncoder(&body).Encode(in); err != nil {
+			return err
+		}
+	}
+
+	req, err := http.NewRequest(method, url, &body)
+	if err != nil {
+		return err
+	}
+	req.Header.Set("Content-Type", "application/json")
+	req.Header.Set("Accept", "application/json")
+	for k, v := range header {
+		req.Header[k] = v
+	}
+
+	resp, err := exportClient.Do(req)
+	if err != nil {
+		return err
+	}
+	defer resp.Body.Close()
+
+	if resp.StatusCode < 200 || resp.StatusCode > 299 {
+		msg, _ := ioutil.ReadAll(resp.Body)
+		return fmt.Errorf("%s %s: StatusCode:[%d]: %s", method, url, resp.StatusCode, strings.TrimSpace(string(msg)))
+	}
+
+	if out == nil {
+		return nil
+	}
+	return json.NewDecoder(resp.Body).Decode(out)
+}
+
+// basicAuth returns the value of the Authorization header for HTTP basic authentication.
+func basicAuth(user, password string) string {
+	return "Basic " + base64.StdEncoding.EncodeToString([]byte(user+":"+password))
+}
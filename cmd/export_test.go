@@ -0,0 +1,95 @@
+package cmd
+
+import (
+	"io/ioutil"
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+
+	"github.com/aquasecurity/kube-bench/check"
+	"github.com/spf13/viper"
+	"github.com/stretchr/testify/assert"
+)
+
+func writeExporterPlugin(t *testing.T, dir, name, script string, mode os.FileMode) {
+	path := filepath.Join(dir, name)
+	if err := ioutil.WriteFile(path, []byte(script), mode); err != nil {
+		t.Fatalf("error writing plugin %s: %v", path, err)
+	}
+}
+
+func TestDiscoverExporterPlugins(t *testing.T) {
+	dir, err := ioutil.TempDir("", "kube-bench-exporters")
+	if err != nil {
+		t.Fatalf("Failed to create temp directory")
+	}
+	defer os.RemoveAll(dir)
+
+	writeExporterPlugin(t, dir, "kube-bench-exporter-one", "#!/bin/sh\n", 0755)
+	writeExporterPlugin(t, dir, "kube-bench-exporter-noexec", "#!/bin/sh\n", 0644)
+	writeExporterPlugin(t, dir, "something-else", "#!/bin/sh\n", 0755)
+
+	plugins, err := discoverExporterPlugins(dir)
+	assert.NoError(t, err)
+	assert.Equal(t, map[string]string{"one": filepath.Join(dir, "kube-bench-exporter-one")}, plugins)
+
+	plugins, err = discoverExporterPlugins("")
+	assert.NoError(t, err)
+	assert.Empty(t, plugins)
+
+	_, err = discoverExporterPlugins(filepath.Join(dir, "missing"))
+	assert.Error(t, err)
+}
+
+func TestGetExporters(t *testing.T) {
+	dir, err := ioutil.TempDir("", "kube-bench-exporters")
+	if err != nil {
+		t.Fatalf("Failed to create temp directory")
+	}
+	defer os.RemoveAll(dir)
+	writeExporterPlugin(t, dir, "kube-bench-exporter-one", "#!/bin/sh\n", 0755)
+	writeExporterPlugin(t, dir, "kube-bench-exporter-two", "#!/bin/sh\n", 0755)
+
+	exporters, err := getExporters(nil, dir, viper.New())
+	assert.NoError(t, err)
+	assert.Len(t, exporters, 2, "all discovered plugins are used by default")
+
+	exporters, err = getExporters([]string{"two"}, dir, viper.New())
+	assert.NoError(t, err)
+	assert.Len(t, exporters, 1)
+	assert.Equal(t, "two", exporters[0].Name())
+
+	_, err = getExporters([]string{"three"}, dir, viper.New())
+	assert.Error(t, err)
+}
+
+func TestPluginExporter(t *testing.T) {
+	dir, err := ioutil.TempDir("", "kube-bench-exporters")
+	if err != nil {
+		t.Fatalf("Failed to create temp directory")
+	}
+	defer os.RemoveAll(dir)
+
+	out := filepath.Join(dir, "out")
+	writeExporterPlugin(t, dir, "kube-bench-exporter-file",
+		"#!/bin/sh\ncat > "+out+"\necho \"$KUBE_BENCH_SCAN_ID $KUBE_BENCH_EXPORTER_TARGET_URL\" >> "+out+"\n", 0755)
+	writeExporterPlugin(t, dir, "kube-bench-exporter-fail", "#!/bin/sh\necho broken >&2\nexit 3\n", 0755)
+
+	v := viper.New()
+	v.Set("exporters.file.target_url", "https://example.com")
+	exporters, err := getExporters([]string{"file", "fail"}, dir, v)
+	assert.NoError(t, err)
+
+	controls := &check.Controls{ID: "1", Type: check.MASTER, ScanID: "scan-1"}
+	assert.NoError(t, exporters[0].Export(controls))
+
+	data, err := ioutil.ReadFile(out)
+	assert.NoError(t, err)
+	assert.True(t, strings.HasPrefix(string(data), `{"id":"1"`), string(data))
+	assert.Contains(t, string(data), "scan-1 https://example.com")
+
+	err = exporters[1].Export(controls)
+	assert.Error(t, err)
+	assert.Contains(t, err.Error(), "broken")
+}
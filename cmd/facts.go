@@ -0,0 +1,95 @@
+package cmd
+
+import (
+	"fmt"
+	"io/ioutil"
+	"regexp"
+	"strconv"
+	"strings"
+
+	"github.com/golang/glog"
+	"gopkg.in/yaml.v2"
+)
+
+var (
+	// factsFile is a YAML file of site-specific variables, substituted in the
+	// controls files as $<name>fact.
+	factsFile string
+	facts     map[string]string
+
+	unresolvedFactRe = regexp.MustCompile(`\$(\w+)fact\b`)
+)
+
+// loadFacts reads a YAML map of fact names to values. Lists are joined with
+// commas, so that they can be compared to with has or valid_elements.
+func loadFacts(file string) (map[string]string, error) {
+	in, err := ioutil.ReadFile(file)
+	if err != nil {
+		return nil, fmt.Errorf("failed to read facts file: %v", err)
+	}
+
+	var raw map[string]interface{}
+	if err := yaml.Unmarshal(in, &raw); err != nil {
+		return nil, fmt.Errorf("failed to parse facts file %s: %v", file, err)
+	}
+
+	m := make(map[string]string)
+	for name, value := range raw {
+		switch value := value.(type) {
+		case []interface{}:
+			var items []string
+			for _, item := range value {
+				items = append(items, fmt.Sprint(item))
+			}
+			m[name] = strings.Join(items, ",")
+		case map[interface{}]interface{}:
+			return nil, fmt.Errorf("fact %s in %s is a map, facts must be values or lists", name, file)
+		case nil:
+			m[name] = ""
+		default:
+			m[name] = fmt.Sprint(value)
+		}
+	}
+	return m, nil
+}
+
+// substituteFacts replaces the facts in s, warning about those that aren't
+// defined.
+func substituteFacts(s string) string {
+	s = makeSubstitutions(s, "fact", facts)
+	warned := make(map[string]bool)
+	for _, m := range unresolvedFactRe.FindAllStringSubmatch(s, -1) {
+		if !warned[m[1]] {
+			glog.Warningf("fact %s used in the controls files is not set, see --facts", m[1])
+			warned[m[1]] = true
+		}
+	}
+	return s
+}
+
+// scanFacts returns the facts the requirements of the checks are matched
+// against: those of --facts, and those detected on the host for the running
+// components of binmap, unless --facts sets them.
+func scanFacts(binmap map[string]string) map[string]string {
+	m := make(map[string]string)
+	if bin, ok := binmap["proxy"]; ok {
+		if mode := detectProxyMode(bin); mode != "" {
+			m["proxymode"] = mode
+		}
+	}
+	// The cloud controller manager of clusters which moved off the in-tree cloud
+	// providers runs on the master nodes, usually in a pod.
+	if bin, ok := binmap["cloudcontrollermanager"]; ok {
+		m["cloudcontrollermanager"] = "not running"
+		if verifyBin(bin) {
+			m["cloudcontrollermanager"] = "running"
+		}
+	}
+	for name, enabled := range detectFeatureGates(binmap) {
+		m["featuregate"+name] = strconv.FormatBool(enabled)
+	}
+	for name, value := range facts {
+		m[name] = value
+	}
+	return m
+}
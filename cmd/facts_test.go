@@ -0,0 +1,54 @@
+package cmd
+
+import (
+	"io/ioutil"
+	"os"
+	"path/filepath"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+)
+
+func TestLoadFacts(t *testing.T) {
+	dir, err := ioutil.TempDir("", "kube-bench-facts")
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer os.RemoveAll(dir)
+
+	file := filepath.Join(dir, "facts.yaml")
+	assert.NoError(t, ioutil.WriteFile(file, []byte(`
+registry: registry.example.com
+auditlogpath: /var/log/kubernetes/audit.log
+maxage: 30
+imageprefixes:
+- registry.example.com/
+- gcr.io/example/
+`), 0644))
+	m, err := loadFacts(file)
+	assert.NoError(t, err)
+	assert.Equal(t, map[string]string{
+		"registry":      "registry.example.com",
+		"auditlogpath":  "/var/log/kubernetes/audit.log",
+		"maxage":        "30",
+		"imageprefixes": "registry.example.com/,gcr.io/example/",
+	}, m)
+
+	assert.NoError(t, ioutil.WriteFile(file, []byte("site:\n  name: a\n"), 0644))
+	_, err = loadFacts(file)
+	assert.Error(t, err)
+
+	_, err = loadFacts(filepath.Join(dir, "missing.yaml"))
+	assert.Error(t, err)
+}
+
+func TestSubstituteFacts(t *testing.T) {
+	defer func(m map[string]string) { facts = m }(facts)
+	facts = map[string]string{"auditlogpath": "/var/log/audit.log", "maxage": "30"}
+
+	assert.Equal(t, `audit: "ps -ef | grep /var/log/audit.log"
+value: 30
+value: $registryfact`, substituteFacts(`audit: "ps -ef | grep $auditlogpathfact"
+value: $maxagefact
+value: $registryfact`))
+}
@@ -0,0 +1,92 @@
+package cmd
+
+import (
+	"fmt"
+	"regexp"
+	"sort"
+	"strconv"
+	"strings"
+
+	"github.com/golang/glog"
+	"gopkg.in/yaml.v2"
+)
+
+var featureGatesFlagRe = regexp.MustCompile(`--feature-gates[= ](\S+)`)
+
+// detectFeatureGates returns the feature gates set on the running components
+// of binmap, from their --feature-gates flags and the featureGates of their
+// config files. A feature gate is enabled when any of the components enables
+// it. Feature gates which aren't set are left out, as their defaults depend on
+// the version of Kubernetes.
+func detectFeatureGates(binmap map[string]string) map[string]bool {
+	components := make([]string, 0, len(binmap))
+	for component := range binmap {
+		components = append(components, component)
+	}
+	sort.Strings(components)
+
+	gates := make(map[string]bool)
+	set := func(name string, enabled bool) {
+		gates[name] = gates[name] || enabled
+	}
+	for _, component := range components {
+		bin := strings.Fields(binmap[component])
+		if len(bin) == 0 {
+			continue
+		}
+		for _, cmdline := range strings.Split(psFunc(bin[0]), "\n") {
+			for _, m := range featureGatesFlagRe.FindAllStringSubmatch(cmdline, -1) {
+				for name, enabled := range parseFeatureGates(m[1]) {
+					set(name, enabled)
+				}
+			}
+			if m := configFlagRe.FindStringSubmatch(cmdline); m != nil {
+				configGates, err := configFeatureGates(m[1], strings.TrimSpace(cmdline))
+				if err != nil {
+					glog.V(1).Info(fmt.Sprintf("Failed to read the feature gates of %s from its config file: %v", component, err))
+				}
+				for name, enabled := range configGates {
+					set(name, enabled)
+				}
+			}
+		}
+	}
+	return gates
+}
+
+// parseFeatureGates parses the value of a --feature-gates flag, a list of
+// name=true|false pairs separated by commas.
+func parseFeatureGates(s string) map[string]bool {
+	gates := make(map[string]bool)
+	for _, pair := range strings.Split(strings.Trim(s, `'"`), ",") {
+		kv := strings.SplitN(pair, "=", 2)
+		if len(kv) != 2 {
+			continue
+		}
+		enabled, err := strconv.ParseBool(strings.TrimSpace(kv[1]))
+		if err != nil {
+			glog.V(1).Info(fmt.Sprintf("Ignoring feature gate %q with invalid value %q", kv[0], kv[1]))
+			continue
+		}
+		gates[strings.TrimSpace(kv[0])] = enabled
+	}
+	return gates
+}
+
+// configFeatureGates reads the featureGates of the config file of the
+// component running with cmdline, such as a KubeletConfiguration or
+// KubeProxyConfiguration.
+func configFeatureGates(file, cmdline string) (map[string]bool, error) {
+	in, err := readComponentConfig(file, cmdline)
+	if err != nil {
+		return nil, err
+	}
+
+	var config struct {
+		FeatureGates map[string]bool `yaml:"featureGates"`
+	}
+	if err := yaml.Unmarshal(in, &config); err != nil {
+		return nil, fmt.Errorf("failed to parse %s: %v", file, err)
+	}
+	return config.FeatureGates, nil
+}
@@ -0,0 +1,40 @@
+package cmd
+
+import (
+	"io/ioutil"
+	"os"
+	"path/filepath"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+)
+
+func TestParseFeatureGates(t *testing.T) {
+	assert.Equal(t, map[string]bool{}, parseFeatureGates(""))
+	assert.Equal(t, map[string]bool{"PodSecurity": true, "EphemeralContainers": false}, parseFeatureGates("PodSecurity=true,EphemeralContainers=false"))
+	assert.Equal(t, map[string]bool{"PodSecurity": true}, parseFeatureGates(`"PodSecurity=true,Broken=maybe,Invalid"`))
+}
+
+func TestDetectFeatureGates(t *testing.T) {
+	dir, err := ioutil.TempDir("", "kube-bench-featuregates-")
+	assert.NoError(t, err)
+	defer os.RemoveAll(dir)
+
+	conf := filepath.Join(dir, "config.yaml")
+	assert.NoError(t, ioutil.WriteFile(conf, []byte("kind: KubeletConfiguration\nfeatureGates:\n  RotateKubeletServerCertificate: true\n  PodSecurity: false\n"), 0644))
+
+	defer func(ps func(string) string) { psFunc = ps }(psFunc)
+	processes := map[string]string{
+		"kube-apiserver": "kube-apiserver --feature-gates=PodSecurity=true,CSIMigration=false --secure-port=6443",
+		"kubelet":        "/usr/bin/kubelet --config=" + conf + " --feature-gates=CSIMigration=false",
+		"kube-proxy":     "kube-proxy --config=/nonexistent/config.conf",
+	}
+	psFunc = func(proc string) string { return processes[proc] }
+
+	assert.Equal(t, map[string]bool{}, detectFeatureGates(map[string]string{"scheduler": "kube-scheduler"}))
+	assert.Equal(t, map[string]bool{
+		"PodSecurity":                    true,
+		"CSIMigration":                   false,
+		"RotateKubeletServerCertificate": true,
+	}, detectFeatureGates(map[string]string{"apiserver": "kube-apiserver", "kubelet": "kubelet", "proxy": "kube-proxy"}))
+}
@@ -0,0 +1,139 @@
+package cmd
+
+import (
+	"crypto/tls"
+	"fmt"
+	"io/ioutil"
+	"os"
+	"sort"
+	"strings"
+
+	"github.com/golang/glog"
+	"github.com/spf13/viper"
+)
+
+const serviceAccountDir = "/var/run/secrets/kubernetes.io/serviceaccount"
+
+var (
+	flagzMode bool
+	flagz     *mockHost
+
+	// flagzComponents are the components whose flags may be retrieved from
+	// their /flagz endpoint, with the section of the config they belong to.
+	flagzComponents = map[string]string{
+		"apiserver":         "master",
+		"controllermanager": "master",
+		"scheduler":         "master",
+		"kubelet":           "node",
+	}
+)
+
+// setupFlagz replaces the processes of the host with the command lines built
+// from the /flagz endpoints of the components, so that their checks can run
+// from a pod which can't see the processes or manifests of the control plane.
+// Audits other than listing the processes can't be evaluated.
+func setupFlagz() {
+	if mockMode {
+		exitWithError(fmt.Errorf("--flagz can't be used with --mock"))
+	}
+
+	token, cert, err := loadServiceAccount(serviceAccountDir)
+	if err != nil {
+		exitWithError(fmt.Errorf("failed to load the service account for --flagz: %v", err))
+	}
+
+	endpoints := getFlagzEndpoints(viper.GetViper())
+	flagz = &mockHost{}
+	for _, component := range sortedKeys(endpoints) {
+		data, err := getWebData(endpoints[component], token, cert)
+		if err != nil {
+			glog.Warningf("Failed to get the flags of %s from %s: %v", component, endpoints[component], err)
+			continue
+		}
+		bin := component
+		if bins := viper.GetStringSlice(flagzComponents[component] + "." + component + ".bins"); len(bins) > 0 {
+			bin = bins[0]
+		}
+		flagz.Processes = append(flagz.Processes, flagzCommandLine(bin, string(data)))
+	}
+	glog.V(1).Info(fmt.Sprintf("Running checks against the flags of %d components from their /flagz endpoints", len(flagz.Processes)))
+
+	psFunc = flagz.ps
+	hostCapsOnce.Do(func() {
+		hostCaps = hostCapabilities{pid: true, root: os.Geteuid() == 0}
+	})
+}
+
+// getFlagzEndpoints returns the /flagz endpoint of each component set in the
+// flagz section of the config. The API server defaults to the endpoint of the
+// Kubernetes service.
+func getFlagzEndpoints(v *viper.Viper) map[string]string {
+	endpoints := map[string]string{
+		"apiserver": strings.TrimSuffix(getKubernetesURL(), "/version") + "/flagz",
+	}
+	for component, url := range v.GetStringMapString("flagz") {
+		if _, ok := flagzComponents[component]; !ok {
+			glog.Warningf("Ignoring the flagz endpoint of unknown component %q", component)
+			continue
+		}
+		if url == "" {
+			delete(endpoints, component)
+			continue
+		}
+		endpoints[component] = url
+	}
+	return endpoints
+}
+
+// flagzCommandLine rebuilds the command line of bin from the output of its
+// /flagz endpoint, which lists a flag=value pair per line under a header.
+// Flags without a value are left out, as /flagz lists every flag of the
+// component, set or not.
+func flagzCommandLine(bin, flagz string) string {
+	args := []string{bin}
+	for _, line := range strings.Split(flagz, "\n") {
+		line = strings.TrimSpace(line)
+		i := strings.Index(line, "=")
+		if i <= 0 || strings.ContainsAny(line[:i], " \t") {
+			continue
+		}
+		if value := line[i+1:]; value == "" || value == "[]" {
+			continue
+		}
+		args = append(args, "--"+line)
+	}
+	return strings.Join(args, " ")
+}
+
+// flagzAudit returns the output of the ps audits of the checks from the flags
+// of the components. Other audits need access to the host.
+func flagzAudit(audit string) (string, error) {
+	if !mockPsAudit.MatchString(audit) {
+		return "", fmt.Errorf("audit %q can't be evaluated from the /flagz endpoints", audit)
+	}
+	return flagz.audit(audit)
+}
+
+// loadServiceAccount reads the token and CA certificate of the service
+// account of the pod.
+func loadServiceAccount(dir string) (string, *tls.Certificate, error) {
+	cert, err := loadCertficate(dir + "/ca.crt")
+	if err != nil {
+		return "", nil, err
+	}
+
+	tb, err := ioutil.ReadFile(dir + "/token")
+	if err != nil {
+		return "", nil, err
+	}
+	return strings.TrimSpace(string(tb)), cert, nil
+}
+
+func sortedKeys(m map[string]string) []string {
+	keys := make([]string, 0, len(m))
+	for k := range m {
+		keys = append(keys, k)
+	}
+	sort.Strings(keys)
+	return keys
+}
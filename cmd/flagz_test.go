@@ -0,0 +1,64 @@
+package cmd
+
+import (
+	"fmt"
+	"net/http"
+	"net/http/httptest"
+	"os"
+	"testing"
+
+	"github.com/spf13/viper"
+	"github.com/stretchr/testify/assert"
+)
+
+const apiserverFlagz = `kube-apiserver flags
+Warning: This endpoint is not meant to be machine parseable, has no formatting compatibility guarantees and is for debugging purposes only.
+
+admission-control-config-file=
+anonymous-auth=false
+authorization-mode=[Node,RBAC]
+enable-admission-plugins=NodeRestriction
+tls-cipher-suites=[]
+`
+
+func TestFlagzCommandLine(t *testing.T) {
+	assert.Equal(t, "kube-apiserver --anonymous-auth=false --authorization-mode=[Node,RBAC] --enable-admission-plugins=NodeRestriction",
+		flagzCommandLine("kube-apiserver", apiserverFlagz))
+}
+
+func TestGetFlagzEndpoints(t *testing.T) {
+	defer func(host string) { os.Setenv("KUBERNETES_SERVICE_HOST", host) }(os.Getenv("KUBERNETES_SERVICE_HOST"))
+	os.Unsetenv("KUBERNETES_SERVICE_HOST")
+
+	v := viper.New()
+	v.Set("flagz", map[string]string{"scheduler": "https://10.0.0.1:10259/flagz", "etcd": "https://10.0.0.1:2379/flagz"})
+	assert.Equal(t, map[string]string{
+		"apiserver": "https://kubernetes.default.svc/flagz",
+		"scheduler": "https://10.0.0.1:10259/flagz",
+	}, getFlagzEndpoints(v))
+
+	v.Set("flagz", map[string]string{"apiserver": ""})
+	assert.Empty(t, getFlagzEndpoints(v))
+}
+
+func TestFlagzAudit(t *testing.T) {
+	srv := httptest.NewTLSServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		assert.Equal(t, "Bearer token", r.Header.Get("Authorization"))
+		fmt.Fprint(w, apiserverFlagz)
+	}))
+	defer srv.Close()
+
+	data, err := getWebData(srv.URL+"/flagz", "token", &srv.TLS.Certificates[0])
+	assert.NoError(t, err)
+
+	defer func() { flagz = nil }()
+	flagz = &mockHost{Processes: []string{flagzCommandLine("kube-apiserver", string(data))}}
+
+	out, err := flagzAudit("/bin/ps -ef | grep kube-apiserver | grep -v grep")
+	assert.NoError(t, err)
+	assert.Contains(t, out, "kube-apiserver --anonymous-auth=false")
+	assert.Equal(t, flagz.Processes[0], flagz.ps("kube-apiserver"))
+
+	_, err = flagzAudit("stat -c %a /etc/kubernetes/manifests/kube-apiserver.yaml")
+	assert.EqualError(t, err, `audit "stat -c %a /etc/kubernetes/manifests/kube-apiserver.yaml" can't be evaluated from the /flagz endpoints`)
+}
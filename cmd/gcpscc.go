@@ -0,0 +1,178 @@
+package cmd
+
+import (
+	"crypto/sha256"
+	"encoding/hex"
+	"fmt"
+	"net/http"
+	"net/url"
+	"strings"
+	"time"
+
+	"github.com/aquasecurity/kube-bench/check"
+	"github.com/golang/glog"
+	"github.com/spf13/viper"
+)
+
+const (
+	defaultSCCAPIURL    = "https://securitycenter.googleapis.com/v1"
+	gcpMetadataTokenURL = "http://metadata.google.internal/computeMetadata/v1/instance/service-accounts/default/token"
+)
+
+func init() {
+	exporterFactories["gcpscc"] = newSCCExporter
+}
+
+// sccFinding is a finding of the Security Command Center API.
+type sccFinding struct {
+	State            string            `json:"state"`
+	ResourceName     string            `json:"resourceName"`
+	Category         string            `json:"category"`
+	Severity         string            `json:"severity,omitempty"`
+	EventTime        string            `json:"eventTime"`
+	SourceProperties map[string]string `json:"sourceProperties"`
+}
+
+// sccExporter reports the results of checks as findings of a Google Cloud
+// Security Command Center source, attributed to the GKE cluster resource.
+// Failed checks are ACTIVE findings, and passing checks mark the finding of
+// an earlier failure INACTIVE.
+type sccExporter struct {
+	apiURL       string
+	source       string
+	resourceName string
+	category     string
+	token        string
+	tokenURL     string
+	node         string
+	now          func() time.Time
+}
+
+func newSCCExporter(v *viper.Viper) (Exporter, error) {
+	e := &sccExporter{
+		apiURL:       strings.TrimSuffix(v.GetString("api_url"), "/"),
+		source:       v.GetString("source"),
+		resourceName: v.GetString("resource_name"),
+		category:     v.GetString("category"),
+		token:        v.GetString("token"),
+		tokenURL:     v.GetString("token_url"),
+		node:         v.GetString("node"),
+		now:          time.Now,
+	}
+	if e.source == "" {
+		return nil, fmt.Errorf("source is not set")
+	}
+	if e.resourceName == "" {
+		project, location, cluster := v.GetString("project"), v.GetString("location"), v.GetString("cluster")
+		if project == "" || location == "" || cluster == "" {
+			return nil, fmt.Errorf("either resource_name or project, location and cluster must be set")
+		}
+		e.resourceName = fmt.Sprintf("//container.googleapis.com/projects/%s/locations/%s/clusters/%s", project, location, cluster)
+	}
+	if e.apiURL == "" {
+		e.apiURL = defaultSCCAPIURL
+	}
+	if e.category == "" {
+		e.category = "KUBE_BENCH"
+	}
+	if e.tokenURL == "" {
+		e.tokenURL = gcpMetadataTokenURL
+	}
+	if e.node == "" {
+		e.node = hostName()
+	}
+	return e, nil
+}
+
+func (e *sccExporter) Name() string { return "gcpscc" }
+
+func (e *sccExporter) Export(controls *check.Controls) error {
+	token, err := e.accessToken()
+	if err != nil {
+		return fmt.Errorf("unable to get an access token: %v", err)
+	}
+	header := http.Header{}
+	header.Set("Authorization", "Bearer "+token)
+
+	var failed []string
+	for _, g := range controls.Groups {
+		for _, c := range g.Checks {
+			var state string
+			switch c.State {
+			case check.FAIL:
+				state = "ACTIVE"
+			case check.PASS:
+				state = "INACTIVE"
+			default:
+				continue
+			}
+
+			// Patching a finding creates it when it doesn't exist yet.
+			endpoint := fmt.Sprintf("%s/%s/findings/%s", e.apiURL, e.source, e.findingID(c))
+			glog.V(2).Info(fmt.Sprintf("Reporting %s as %s finding %s", c.ID, state, endpoint))
+			if err := doJSON(http.MethodPatch, endpoint, header, e.finding(controls, c, state), nil); err != nil {
+				glog.V(1).Info(fmt.Sprintf("Security Command Center finding for %s failed: %v", c.ID, err))
+				failed = append(failed, c.ID)
+			}
+		}
+	}
+
+	if len(failed) > 0 {
+		return fmt.Errorf("unable to report findings for checks %s", strings.Join(failed, ", "))
+	}
+	return nil
+}
+
+// findingID returns a stable ID of up to 32 alphanumeric characters for the
+// finding of a check on this node, so that later runs update the same finding.
+func (e *sccExporter) findingID(c *check.Check) string {
+	sum := sha256.Sum256([]byte(strings.Join([]string{e.resourceName, e.node, c.ID}, "/")))
+	return hex.EncodeToString(sum[:])[:32]
+}
+
+func (e *sccExporter) finding(controls *check.Controls, c *check.Check, state string) sccFinding {
+	severity := "LOW"
+	if c.Scored {
+		severity = "MEDIUM"
+	}
+
+	properties := map[string]string{
+		"benchmark":   controls.Version,
+		"node":        displayNode(e.node),
+		"node_type":   string(controls.Type),
+		"test_number": c.ID,
+		"test_desc":   c.Text,
+		"remediation": c.Remediation,
+		"scan_id":     controls.ScanID,
+	}
+	for k, v := range c.Annotations {
+		properties["annotation_"+k] = v
+	}
+
+	return sccFinding{
+		State:            state,
+		ResourceName:     e.resourceName,
+		Category:         e.category,
+		Severity:         severity,
+		EventTime:        formatTime(e.now()),
+		SourceProperties: properties,
+	}
+}
+
+// accessToken returns the configured token, or one of the default service
+// account from the metadata server, e.g. with GKE Workload Identity.
+func (e *sccExporter) accessToken() (string, error) {
+	if e.token != "" {
+		return e.token, nil
+	}
+
+	var tok struct {
+		AccessToken string `json:"access_token"`
+	}
+	header := http.Header{}
+	header.Set("Metadata-Flavor", "Google")
+	if err := doJSON(http.MethodGet, e.tokenURL+"?scopes="+url.QueryEscape("https://www.googleapis.com/auth/cloud-platform"), header, nil, &tok); err != nil {
+		return "", err
+	}
+	return tok.AccessToken, nil
+}
@@ -0,0 +1,57 @@
+package cmd
+
+import (
+	"encoding/json"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+
+	"github.com/spf13/viper"
+	"github.com/stretchr/testify/assert"
+)
+
+func TestSCCExporter(t *testing.T) {
+	findings := make(map[string]sccFinding)
+	ts := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		if r.URL.Path == "/token" {
+			assert.Equal(t, "Google", r.Header.Get("Metadata-Flavor"))
+			w.Write([]byte(`{"access_token":"ya29.token","expires_in":3599}`))
+			return
+		}
+
+		assert.Equal(t, http.MethodPatch, r.Method)
+		assert.Equal(t, "Bearer ya29.token", r.Header.Get("Authorization"))
+		assert.True(t, strings.HasPrefix(r.URL.Path, "/organizations/1/sources/2/findings/"))
+
+		var f sccFinding
+		json.NewDecoder(r.Body).Decode(&f)
+		findings[r.URL.Path] = f
+	}))
+	defer ts.Close()
+
+	v := viper.New()
+	v.Set("api_url", ts.URL)
+	v.Set("token_url", ts.URL+"/token")
+	v.Set("source", "organizations/1/sources/2")
+	v.Set("project", "acme")
+	v.Set("location", "europe-west1")
+	v.Set("cluster", "prod")
+	v.Set("node", "node-1")
+	e, err := newSCCExporter(v)
+	assert.NoError(t, err)
+
+	assert.NoError(t, e.Export(issueControls()))
+	assert.Len(t, findings, 4, "failed and passed checks are reported")
+
+	states := make(map[string]int)
+	for _, f := range findings {
+		assert.Equal(t, "//container.googleapis.com/projects/acme/locations/europe-west1/clusters/prod", f.ResourceName)
+		assert.Equal(t, "node-1", f.SourceProperties["node"])
+		states[f.State]++
+	}
+	assert.Equal(t, map[string]int{"ACTIVE": 3, "INACTIVE": 1}, states)
+
+	_, err = newSCCExporter(viper.New())
+	assert.Error(t, err)
+}
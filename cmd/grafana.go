@@ -0,0 +1,163 @@
+package cmd
+
+import (
+	"encoding/json"
+	"net/http"
+	"time"
+
+	"github.com/aquasecurity/kube-bench/check"
+)
+
+// Targets of the Grafana JSON datasource.
+const (
+	grafanaChecks   = "checks"
+	grafanaSections = "sections"
+	grafanaStates   = "states"
+)
+
+var grafanaStateOrder = []check.State{check.PASS, check.FAIL, check.WARN, check.INFO, check.INCOMPLETE, check.ERROR}
+
+// grafanaQuery is the body of a /query request of the Grafana JSON datasource.
+type grafanaQuery struct {
+	Targets []struct {
+		Target string `json:"target"`
+		Type   string `json:"type"`
+	} `json:"targets"`
+}
+
+type grafanaColumn struct {
+	Text string `json:"text"`
+	Type string `json:"type"`
+}
+
+type grafanaTable struct {
+	Type    string          `json:"type"`
+	Columns []grafanaColumn `json:"columns"`
+	Rows    [][]interface{} `json:"rows"`
+}
+
+type grafanaSeries struct {
+	Target     string           `json:"target"`
+	Datapoints [][2]interface{} `json:"datapoints"`
+}
+
+// registerGrafanaHandlers adds the endpoints of the Grafana JSON datasource
+// plugin under prefix, so that dashboards can be built on the last results
+// without an intermediate database.
+func registerGrafanaHandlers(mux *http.ServeMux, prefix string, store *resultStore) {
+	mux.HandleFunc(prefix+"/", func(w http.ResponseWriter, r *http.Request) {
+		w.Write([]byte("ok"))
+	})
+	mux.HandleFunc(prefix+"/search", func(w http.ResponseWriter, r *http.Request) {
+		writeJSON(w, []string{grafanaChecks, grafanaSections, grafanaStates})
+	})
+	mux.HandleFunc(prefix+"/annotations", func(w http.ResponseWriter, r *http.Request) {
+		writeJSON(w, []interface{}{})
+	})
+	mux.HandleFunc(prefix+"/query", func(w http.ResponseWriter, r *http.Request) {
+		var q grafanaQuery
+		if err := json.NewDecoder(r.Body).Decode(&q); err != nil {
+			http.Error(w, err.Error(), http.StatusBadRequest)
+			return
+		}
+
+		controls, updated := store.get()
+		var resp []interface{}
+		for _, t := range q.Targets {
+			if t.Type == "timeserie" && t.Target == grafanaStates {
+				for _, s := range grafanaStateSeries(controls, updated) {
+					resp = append(resp, s)
+				}
+				continue
+			}
+
+			switch t.Target {
+			case grafanaChecks:
+				resp = append(resp, grafanaChecksTable(controls))
+			case grafanaSections:
+				resp = append(resp, grafanaSectionsTable(controls))
+			case grafanaStates:
+				resp = append(resp, grafanaStatesTable(controls))
+			}
+		}
+		writeJSON(w, resp)
+	})
+}
+
+func grafanaChecksTable(controls []*check.Controls) grafanaTable {
+	t := grafanaTable{
+		Type: "table",
+		Columns: []grafanaColumn{
+			{"Node Type", "string"}, {"Section", "string"}, {"Check", "string"},
+			{"Description", "string"}, {"State", "string"}, {"Scored", "boolean"}, {"Owner", "string"},
+		},
+		Rows: [][]interface{}{},
+	}
+	for _, c := range controls {
+		for _, g := range c.Groups {
+			for _, ch := range g.Checks {
+				t.Rows = append(t.Rows, []interface{}{c.Type, g.ID, ch.ID, ch.Text, ch.State, ch.Scored, ch.Owner})
+			}
+		}
+	}
+	return t
+}
+
+func grafanaSectionsTable(controls []*check.Controls) grafanaTable {
+	t := grafanaTable{
+		Type: "table",
+		Columns: []grafanaColumn{
+			{"Node Type", "string"}, {"Section", "string"}, {"Description", "string"},
+			{"Pass", "number"}, {"Fail", "number"}, {"Warn", "number"}, {"Info", "number"},
+			{"Incomplete", "number"}, {"Error", "number"}, {"Total", "number"},
+		},
+		Rows: [][]interface{}{},
+	}
+	for _, c := range controls {
+		for _, g := range c.Groups {
+			s := g.Summary
+			t.Rows = append(t.Rows, []interface{}{c.Type, g.ID, g.Text, s.Pass, s.Fail, s.Warn, s.Info, s.Incomplete, s.Error, s.Total})
+		}
+	}
+	return t
+}
+
+func grafanaStatesTable(controls []*check.Controls) grafanaTable {
+	t := grafanaTable{
+		Type:    "table",
+		Columns: []grafanaColumn{{"Node Type", "string"}, {"State", "string"}, {"Count", "number"}},
+		Rows:    [][]interface{}{},
+	}
+	for _, c := range controls {
+		counts := stateCounts(c.Totals)
+		for _, s := range grafanaStateOrder {
+			t.Rows = append(t.Rows, []interface{}{c.Type, s, counts[s]})
+		}
+	}
+	return t
+}
+
+// grafanaStateSeries returns one series per state, with the total of the checks
+// of all targets in that state at the time of the last run.
+func grafanaStateSeries(controls []*check.Controls, updated time.Time) []grafanaSeries {
+	totals := make(map[check.State]int)
+	for _, c := range controls {
+		for s, n := range stateCounts(c.Totals) {
+			totals[s] += n
+		}
+	}
+
+	var series []grafanaSeries
+	ts := updated.UnixNano() / int64(time.Millisecond)
+	for _, s := range grafanaStateOrder {
+		series = append(series, grafanaSeries{Target: string(s), Datapoints: [][2]interface{}{{totals[s], ts}}})
+	}
+	return series
+}
+
+func stateCounts(c check.Counts) map[check.State]int {
+	return map[check.State]int{
+		check.PASS: c.Pass, check.FAIL: c.Fail, check.WARN: c.Warn, check.INFO: c.Info,
+		check.INCOMPLETE: c.Incomplete, check.ERROR: c.Error,
+	}
+}
@@ -0,0 +1,60 @@
+package cmd
+
+import (
+	"encoding/json"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/aquasecurity/kube-bench/check"
+	"github.com/stretchr/testify/assert"
+)
+
+func TestGrafanaQuery(t *testing.T) {
+	store := &resultStore{}
+	controls := statsdControls()
+	controls.Groups[0].Checks = []*check.Check{{ID: "1.1.1", State: check.FAIL, Owner: "platform"}}
+	store.set([]*check.Controls{controls}, time.Unix(1577836800, 0))
+
+	ts := httptest.NewServer(newServeMux(store))
+	defer ts.Close()
+
+	resp, err := http.Post(ts.URL+"/grafana/search", "application/json", strings.NewReader(`{"target":""}`))
+	assert.NoError(t, err)
+	var targets []string
+	json.NewDecoder(resp.Body).Decode(&targets)
+	resp.Body.Close()
+	assert.Equal(t, []string{grafanaChecks, grafanaSections, grafanaStates}, targets)
+
+	query := `{"targets":[{"target":"checks","type":"table"},{"target":"sections","type":"table"},{"target":"states","type":"table"},{"target":"states","type":"timeserie"}]}`
+	resp, err = http.Post(ts.URL+"/grafana/query", "application/json", strings.NewReader(query))
+	assert.NoError(t, err)
+	defer resp.Body.Close()
+
+	var res []struct {
+		Type       string          `json:"type"`
+		Columns    []grafanaColumn `json:"columns"`
+		Rows       [][]interface{} `json:"rows"`
+		Target     string          `json:"target"`
+		Datapoints [][]float64     `json:"datapoints"`
+	}
+	assert.NoError(t, json.NewDecoder(resp.Body).Decode(&res))
+	assert.Len(t, res, 9)
+
+	assert.Equal(t, []interface{}{"master", "1.1", "1.1.1", "", "FAIL", false, "platform"}, res[0].Rows[0])
+	assert.Equal(t, grafanaColumn{"Scored", "boolean"}, res[0].Columns[5], "the type of a column is that of its values")
+	assert.Len(t, res[1].Rows, 2)
+	assert.Equal(t, []interface{}{"master", "1.2", "", float64(0), float64(1), float64(1), float64(0), float64(0), float64(1), float64(3)}, res[1].Rows[1])
+	assert.Equal(t, []interface{}{"master", "FAIL", float64(2)}, res[2].Rows[1])
+	assert.Equal(t, []interface{}{"master", "ERROR", float64(1)}, res[2].Rows[5])
+	assert.Equal(t, "ERROR", res[8].Target)
+	assert.Equal(t, [][]float64{{1, 1577836800000}}, res[8].Datapoints)
+	assert.Equal(t, "FAIL", res[4].Target)
+	assert.Equal(t, [][]float64{{2, 1577836800000}}, res[4].Datapoints)
+
+	resp, err = http.Post(ts.URL+"/grafana/query", "application/json", strings.NewReader("BOOM"))
+	assert.NoError(t, err)
+	assert.Equal(t, http.StatusBadRequest, resp.StatusCode)
+}
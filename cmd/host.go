@@ -0,0 +1,49 @@
+package cmd
+
+import (
+	"os"
+	"path/filepath"
+	"strings"
+)
+
+const (
+	// hostCfgDir is where the kube-bench packages install the config directory.
+	hostCfgDir = "/etc/kube-bench/cfg"
+	// containerBinDir is where the kube-bench jobs mount the binaries of the
+	// host, such as kubectl and kubelet, into the container.
+	containerBinDir = "/usr/local/mount-from-host/bin"
+)
+
+// hostMode is set when kube-bench runs directly on the node, or in its
+// namespaces through nsenter, rather than in a container.
+var hostMode bool
+
+// setupHostMode switches the defaults meant for the kube-bench container to
+// their host locations: the config directory is the installed one (or the
+// built-in one if it isn't installed), binaries are only looked up in the PATH
+// of the host, and kube-bench doesn't look for the host PID namespace and
+// filesystem it already runs in.
+func setupHostMode() {
+	if !hostMode {
+		return
+	}
+
+	if !RootCmd.PersistentFlags().Changed("config-dir") {
+		cfgDir = hostCfgDir
+	}
+	os.Setenv("PATH", withoutPathDir(os.Getenv("PATH"), containerBinDir))
+	hostCapsOnce.Do(func() {
+		hostCaps = hostCapabilities{pid: true, filesystem: true, root: os.Geteuid() == 0}
+	})
+}
+
+// withoutPathDir removes dir from the PATH list path.
+func withoutPathDir(path, dir string) string {
+	var dirs []string
+	for _, d := range filepath.SplitList(path) {
+		if filepath.Clean(d) != dir {
+			dirs = append(dirs, d)
+		}
+	}
+	return strings.Join(dirs, string(os.PathListSeparator))
+}
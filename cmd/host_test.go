@@ -0,0 +1,35 @@
+package cmd
+
+import (
+	"os"
+	"sync"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+)
+
+func TestWithoutPathDir(t *testing.T) {
+	assert.Equal(t, "/usr/sbin:/usr/bin", withoutPathDir("/usr/sbin:/usr/local/mount-from-host/bin:/usr/bin", containerBinDir))
+	assert.Equal(t, "/usr/bin", withoutPathDir("/usr/local/mount-from-host/bin/:/usr/bin", containerBinDir))
+	assert.Equal(t, "", withoutPathDir(containerBinDir, containerBinDir))
+}
+
+func TestSetupHostMode(t *testing.T) {
+	defer func(dir, path string) {
+		cfgDir, hostMode = dir, false
+		os.Setenv("PATH", path)
+		hostCaps, hostCapsOnce = hostCapabilities{}, sync.Once{}
+	}(cfgDir, os.Getenv("PATH"))
+	os.Setenv("PATH", "/usr/local/mount-from-host/bin:/usr/bin")
+
+	setupHostMode()
+	assert.Equal(t, "/usr/local/mount-from-host/bin:/usr/bin", os.Getenv("PATH"), "nothing changes without --host")
+
+	hostMode = true
+	setupHostMode()
+	assert.Equal(t, hostCfgDir, cfgDir)
+	assert.Equal(t, "/usr/bin", os.Getenv("PATH"))
+	caps := getHostCapabilities()
+	assert.True(t, caps.pid)
+	assert.True(t, caps.filesystem)
+}
@@ -0,0 +1,90 @@
+package cmd
+
+import (
+	"fmt"
+	"os"
+	"os/signal"
+	"sync"
+	"syscall"
+	"time"
+
+	"github.com/aquasecurity/kube-bench/check"
+	"github.com/golang/glog"
+)
+
+var (
+	scanTimeout time.Duration
+
+	interrupted     = make(chan struct{})
+	interruptOnce   sync.Once
+	interruptReason string
+)
+
+// interrupt stops the checks that haven't run yet. It can be called several
+// times, only the first reason is kept.
+func interrupt(reason string) {
+	interruptOnce.Do(func() {
+		interruptReason = reason
+		close(interrupted)
+	})
+}
+
+func isInterrupted() bool {
+	select {
+	case <-interrupted:
+		return true
+	default:
+		return false
+	}
+}
+
+// handleInterrupts interrupts the scan on SIGINT or SIGTERM, or once timeout
+// has elapsed if it isn't zero, so the results of the checks already run are
+// still written to the outputs. A second signal exits straight away.
+func handleInterrupts(timeout time.Duration) {
+	signals := make(chan os.Signal, 2)
+	signal.Notify(signals, syscall.SIGINT, syscall.SIGTERM)
+
+	var expired <-chan time.Time
+	if timeout > 0 {
+		expired = time.After(timeout)
+	}
+
+	go func() {
+		select {
+		case s := <-signals:
+			interrupt(fmt.Sprintf("received %v", s))
+		case <-expired:
+			interrupt(fmt.Sprintf("timed out after %v", timeout))
+		}
+		glog.Warningf("Scan interrupted: %s, writing partial results", interruptReason)
+
+		s := <-signals
+		glog.Warningf("Received %v again, exiting", s)
+		glog.Flush()
+		os.Exit(1)
+	}()
+}
+
+// exitIfInterrupted exits with an error once partial results were written.
+func exitIfInterrupted() {
+	if isInterrupted() {
+		exitWithError(partialRunError{fmt.Errorf("scan interrupted: %s", interruptReason)})
+	}
+}
+
+// interruptibleRunner runs checks with the wrapped Runner until the scan is
+// interrupted, and marks the remaining checks INCOMPLETE without running them.
+type interruptibleRunner struct {
+	check.Runner
+}
+
+func (r interruptibleRunner) Run(c *check.Check) check.State {
+	if isInterrupted() {
+		c.Reason = fmt.Sprintf("Scan interrupted: %s", interruptReason)
+		c.ReasonCode = check.ReasonInterrupted
+		c.State = check.INCOMPLETE
+		return c.State
+	}
+	return r.Runner.Run(c)
+}
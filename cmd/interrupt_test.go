@@ -0,0 +1,38 @@
+package cmd
+
+import (
+	"sync"
+	"testing"
+
+	"github.com/aquasecurity/kube-bench/check"
+	"github.com/stretchr/testify/assert"
+)
+
+type stateRunner check.State
+
+func (r stateRunner) Run(c *check.Check) check.State {
+	c.State = check.State(r)
+	return c.State
+}
+
+func TestInterruptibleRunner(t *testing.T) {
+	defer func() {
+		interrupted = make(chan struct{})
+		interruptOnce = sync.Once{}
+		interruptReason = ""
+	}()
+
+	runner := interruptibleRunner{stateRunner(check.PASS)}
+
+	c := &check.Check{ID: "1.1.1"}
+	assert.Equal(t, check.PASS, runner.Run(c))
+
+	interrupt("timed out after 1m0s")
+	interrupt("received terminated")
+	assert.True(t, isInterrupted())
+
+	c = &check.Check{ID: "1.1.2"}
+	assert.Equal(t, check.INCOMPLETE, runner.Run(c))
+	assert.Equal(t, check.INCOMPLETE, c.State)
+	assert.Equal(t, "Scan interrupted: timed out after 1m0s", c.Reason, "only the first reason is kept")
+}
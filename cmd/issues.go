@@ -0,0 +1,387 @@
+package cmd
+
+import (
+	"crypto/sha256"
+	"encoding/hex"
+	"fmt"
+	"net/http"
+	"net/url"
+	"strings"
+	"time"
+
+	"github.com/aquasecurity/kube-bench/check"
+	"github.com/golang/glog"
+	"github.com/spf13/viper"
+)
+
+func init() {
+	exporterFactories["github"] = newGitHubExporter
+	exporterFactories["jira"] = newJiraExporter
+}
+
+// issue is the ticket opened for a scored failure.
+type issue struct {
+	Key   string
+	Title string
+	Body  string
+	// Result is the digest of the outcome of the check, which tells whether
+	// the failure changed since the ticket was last updated.
+	Result string
+}
+
+// ticketUpdate is the description or a comment of a ticket.
+type ticketUpdate struct {
+	Body string
+	Time time.Time
+}
+
+// ticket is an open ticket, with its description and its comments from the
+// oldest to the newest.
+type ticket struct {
+	ID      string
+	Updates []ticketUpdate
+}
+
+// issueTracker is the API of a ticketing system used by issueExporter.
+type issueTracker interface {
+	// find returns the open ticket labelled with key, or nil if there is none.
+	find(key string) (*ticket, error)
+	create(i issue) error
+	comment(id, text string) error
+}
+
+// issueExporter opens a ticket per scored failure, or comments on the
+// ticket already open for it, so that failures become a tracked backlog.
+type issueExporter struct {
+	name    string
+	tracker issueTracker
+	cluster string
+	node    string
+	// commentInterval is how long a failure stays unchanged before the
+	// ticket gets a new comment. Zero comments only when the result changes.
+	commentInterval time.Duration
+	now             func() time.Time
+}
+
+func newIssueExporter(name string, tracker issueTracker, v *viper.Viper) *issueExporter {
+	e := &issueExporter{
+		name:            name,
+		tracker:         tracker,
+		cluster:         v.GetString("cluster"),
+		node:            v.GetString("node"),
+		commentInterval: v.GetDuration("comment_interval"),
+		now:             time.Now,
+	}
+	if e.node == "" {
+		e.node = hostName()
+	}
+	return e
+}
+
+func (e *issueExporter) Name() string { return e.name }
+
+func (e *issueExporter) Export(controls *check.Controls) error {
+	var failed []string
+	for _, g := range controls.Groups {
+		for _, c := range g.Checks {
+			if !c.Scored || c.State != check.FAIL {
+				continue
+			}
+
+			i := e.issue(controls, c)
+			t, err := e.tracker.find(i.Key)
+			if err == nil {
+				if t == nil {
+					glog.V(2).Info(fmt.Sprintf("Creating %s issue for %s", e.name, c.ID))
+					err = e.tracker.create(i)
+				} else if text := e.update(t, i); text != "" {
+					glog.V(2).Info(fmt.Sprintf("Updating %s issue %s for %s", e.name, t.ID, c.ID))
+					err = e.tracker.comment(t.ID, text)
+				} else {
+					glog.V(2).Info(fmt.Sprintf("%s issue %s for %s is up to date", e.name, t.ID, c.ID))
+				}
+			}
+			if err != nil {
+				glog.V(1).Info(fmt.Sprintf("%s issue for %s failed: %v", e.name, c.ID, err))
+				failed = append(failed, c.ID)
+			}
+		}
+	}
+
+	if len(failed) > 0 {
+		return fmt.Errorf("unable to open issues for checks %s", strings.Join(failed, ", "))
+	}
+	return nil
+}
+
+// issueKey returns the dedup key of a failure, derived from the cluster, the
+// node and the check ID. It is a single word so that it can be used as a label
+// and found by the search APIs of the trackers.
+func (e *issueExporter) issueKey(c *check.Check) string {
+	sum := sha256.Sum256([]byte(strings.Join([]string{e.cluster, e.node, c.ID}, "/")))
+	return "kube-bench-" + hex.EncodeToString(sum[:])[:16]
+}
+
+// update returns the comment to add to the open ticket t of i, or "" if the
+// last update of kube-bench on t reported the same result less than
+// commentInterval ago, so that scheduled scans don't comment on every run.
+func (e *issueExporter) update(t *ticket, i issue) string {
+	for n := len(t.Updates) - 1; n >= 0; n-- {
+		u := t.Updates[n]
+		if !hasLine(u.Body, "Dedup key: "+i.Key) {
+			continue
+		}
+		if !hasLine(u.Body, "Result: "+i.Result) {
+			return "Failing with a different result.\n\n" + i.Body
+		}
+		if e.commentInterval > 0 && e.now().Sub(u.Time) >= e.commentInterval {
+			return "Still failing.\n\n" + i.Body
+		}
+		return ""
+	}
+
+	// The ticket was not updated by kube-bench, or its updates are gone.
+	return "Still failing.\n\n" + i.Body
+}
+
+// hasLine tells whether text has the line line, whatever its line endings.
+func hasLine(text, line string) bool {
+	for _, l := range strings.Split(text, "\n") {
+		if strings.TrimSpace(l) == line {
+			return true
+		}
+	}
+	return false
+}
+
+// issueResult returns a digest of the outcome of c, leaving out what changes
+// on every run such as the scan ID and the time. The actual value of the check
+// isn't part of it, as it falls back to the whole audit output, which includes
+// the PIDs and CPU times of ps; the values of its test items are.
+func issueResult(c *check.Check) string {
+	parts := []string{string(c.State), string(c.ReasonCode)}
+	for _, r := range c.TestResults {
+		parts = append(parts, r.Flag, r.Path, r.Op, r.Value, r.ActualValue, fmt.Sprint(r.Pass))
+	}
+	sum := sha256.Sum256([]byte(strings.Join(parts, "\x00")))
+	return hex.EncodeToString(sum[:])[:16]
+}
+
+func (e *issueExporter) issue(controls *check.Controls, c *check.Check) issue {
+	key := e.issueKey(c)
+	result := issueResult(c)
+
+	var b strings.Builder
+	fmt.Fprintf(&b, "kube-bench check %s failed.\n\n", c.ID)
+	fmt.Fprintf(&b, "Check: %s %s\n", c.ID, c.Text)
+	if e.cluster != "" {
+		fmt.Fprintf(&b, "Cluster: %s\n", e.cluster)
+	}
+	fmt.Fprintf(&b, "Node: %s (%s)\n", displayNode(e.node), controls.Type)
+	if c.Owner != "" {
+		fmt.Fprintf(&b, "Owner: %s\n", c.Owner)
+	}
+	fmt.Fprintf(&b, "Scan ID: %s\n", controls.ScanID)
+	if controls.Timestamp != "" {
+		fmt.Fprintf(&b, "Checked at: %s\n", displayTime(controls.Timestamp))
+	}
+	fmt.Fprintf(&b, "\nRemediation:\n%s\n", strings.TrimSpace(c.Remediation))
+	fmt.Fprintf(&b, "\nDedup key: %s\n", key)
+	fmt.Fprintf(&b, "Result: %s\n", result)
+
+	return issue{
+		Key:    key,
+		Title:  fmt.Sprintf("[kube-bench] %s on %s: %s", c.ID, displayNode(e.node), c.Text),
+		Body:   b.String(),
+		Result: result,
+	}
+}
+
+// gitHubPageSize is the number of comments requested per page, the maximum
+// allowed by the GitHub API.
+const gitHubPageSize = 100
+
+// gitHubTracker manages issues of a GitHub repository.
+type gitHubTracker struct {
+	apiURL string
+	repo   string
+	token  string
+	labels []string
+}
+
+func newGitHubExporter(v *viper.Viper) (Exporter, error) {
+	t := &gitHubTracker{
+		apiURL: strings.TrimSuffix(v.GetString("api_url"), "/"),
+		repo:   v.GetString("repository"),
+		token:  v.GetString("token"),
+		labels: v.GetStringSlice("labels"),
+	}
+	if t.apiURL == "" {
+		t.apiURL = "https://api.github.com"
+	}
+	if t.repo == "" {
+		return nil, fmt.Errorf("repository is not set")
+	}
+	return newIssueExporter("github", t, v), nil
+}
+
+func (t *gitHubTracker) header() http.Header {
+	h := http.Header{}
+	h.Set("Accept", "application/vnd.github.v3+json")
+	if t.token != "" {
+		h.Set("Authorization", "token "+t.token)
+	}
+	return h
+}
+
+func (t *gitHubTracker) find(key string) (*ticket, error) {
+	q := fmt.Sprintf("%s repo:%s is:issue is:open in:body", key, t.repo)
+	var res struct {
+		Items []struct {
+			Number    int       `json:"number"`
+			Body      string    `json:"body"`
+			CreatedAt time.Time `json:"created_at"`
+			Comments  int       `json:"comments"`
+		} `json:"items"`
+	}
+	err := doJSON(http.MethodGet, t.apiURL+"/search/issues?q="+url.QueryEscape(q), t.header(), nil, &res)
+	if err != nil || len(res.Items) == 0 {
+		return nil, err
+	}
+
+	item := res.Items[0]
+	tk := &ticket{
+		ID:      fmt.Sprintf("%d", item.Number),
+		Updates: []ticketUpdate{{Body: item.Body, Time: item.CreatedAt}},
+	}
+	if item.Comments == 0 {
+		return tk, nil
+	}
+
+	// Comments are listed from the oldest, so the last page has the newest ones.
+	var comments []struct {
+		Body      string    `json:"body"`
+		CreatedAt time.Time `json:"created_at"`
+	}
+	page := (item.Comments-1)/gitHubPageSize + 1
+	err = doJSON(http.MethodGet, fmt.Sprintf("%s/repos/%s/issues/%s/comments?per_page=%d&page=%d", t.apiURL, t.repo, tk.ID, gitHubPageSize, page), t.header(), nil, &comments)
+	if err != nil {
+		return nil, err
+	}
+	for _, c := range comments {
+		tk.Updates = append(tk.Updates, ticketUpdate{Body: c.Body, Time: c.CreatedAt})
+	}
+	return tk, nil
+}
+
+func (t *gitHubTracker) create(i issue) error {
+	body := map[string]interface{}{
+		"title":  i.Title,
+		"body":   i.Body,
+		"labels": t.labels,
+	}
+	return doJSON(http.MethodPost, fmt.Sprintf("%s/repos/%s/issues", t.apiURL, t.repo), t.header(), body, nil)
+}
+
+func (t *gitHubTracker) comment(id, text string) error {
+	body := map[string]string{"body": text}
+	return doJSON(http.MethodPost, fmt.Sprintf("%s/repos/%s/issues/%s/comments", t.apiURL, t.repo, id), t.header(), body, nil)
+}
+
+// jiraTracker manages issues of a Jira project.
+type jiraTracker struct {
+	url       string
+	project   string
+	issueType string
+	user      string
+	token     string
+}
+
+func newJiraExporter(v *viper.Viper) (Exporter, error) {
+	t := &jiraTracker{
+		url:       strings.TrimSuffix(v.GetString("url"), "/"),
+		project:   v.GetString("project"),
+		issueType: v.GetString("issue_type"),
+		user:      v.GetString("user"),
+		token:     v.GetString("token"),
+	}
+	if t.issueType == "" {
+		t.issueType = "Bug"
+	}
+	if t.url == "" || t.project == "" {
+		return nil, fmt.Errorf("url and project must be set")
+	}
+	return newIssueExporter("jira", t, v), nil
+}
+
+func (t *jiraTracker) header() http.Header {
+	h := http.Header{}
+	if t.user != "" {
+		h.Set("Authorization", basicAuth(t.user, t.token))
+	} else if t.token != "" {
+		h.Set("Authorization", "Bearer "+t.token)
+	}
+	return h
+}
+
+func (t *jiraTracker) find(key string) (*ticket, error) {
+	jql := fmt.Sprintf(`project = "%s" AND labels = "%s" AND statusCategory != Done`, t.project, key)
+	type jiraText struct {
+		Body    string `json:"body"`
+		Created string `json:"created"`
+	}
+	var res struct {
+		Issues []struct {
+			Key    string `json:"key"`
+			Fields struct {
+				Description string `json:"description"`
+				Created     string `json:"created"`
+				Comment     struct {
+					Comments []jiraText `json:"comments"`
+				} `json:"comment"`
+			} `json:"fields"`
+		} `json:"issues"`
+	}
+	err := doJSON(http.MethodGet, t.url+"/rest/api/2/search?fields=description,created,comment&jql="+url.QueryEscape(jql), t.header(), nil, &res)
+	if err != nil || len(res.Issues) == 0 {
+		return nil, err
+	}
+
+	i := res.Issues[0]
+	tk := &ticket{
+		ID:      i.Key,
+		Updates: []ticketUpdate{{Body: i.Fields.Description, Time: parseJiraTime(i.Fields.Created)}},
+	}
+	for _, c := range i.Fields.Comment.Comments {
+		tk.Updates = append(tk.Updates, ticketUpdate{Body: c.Body, Time: parseJiraTime(c.Created)})
+	}
+	return tk, nil
+}
+
+// parseJiraTime parses the times of the Jira API, such as
+// 2021-01-17T12:34:56.000+0000. Invalid times are the zero time.
+func parseJiraTime(s string) time.Time {
+	t, err := time.Parse("2006-01-02T15:04:05.000-0700", s)
+	if err != nil {
+		glog.V(2).Info(fmt.Sprintf("invalid Jira time %q: %v", s, err))
+	}
+	return t
+}
+
+func (t *jiraTracker) create(i issue) error {
+	body := map[string]interface{}{
+		"fields": map[string]interface{}{
+			"project":     map[string]string{"key": t.project},
+			"issuetype":   map[string]string{"name": t.issueType},
+			"summary":     i.Title,
+			"description": i.Body,
+			"labels":      []string{"kube-bench", i.Key},
+		},
+	}
+	return doJSON(http.MethodPost, t.url+"/rest/api/2/issue", t.header(), body, nil)
+}
+
+func (t *jiraTracker) comment(id, text string) error {
+	body := map[string]string{"body": text}
+	return doJSON(http.MethodPost, fmt.Sprintf("%s/rest/api/2/issue/%s/comment", t.url, id), t.header(), body, nil)
+}
@@ -0,0 +1,261 @@
+package cmd
+
+import (
+	"encoding/json"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/aquasecurity/kube-bench/check"
+	"github.com/spf13/viper"
+	"github.com/stretchr/testify/assert"
+)
+
+func issueControls() *check.Controls {
+	return &check.Controls{
+		ID:     "4",
+		Type:   check.NODE,
+		ScanID: "scan",
+		Groups: []*check.Group{{
+			ID: "4.2",
+			Checks: []*check.Check{
+				{ID: "4.2.1", Text: "anonymous-auth", State: check.FAIL, Scored: true},
+				{ID: "4.2.2", Text: "authorization-mode", State: check.FAIL, Scored: true},
+				{ID: "4.2.3", Text: "client-ca-file", State: check.FAIL, Scored: false},
+				{ID: "4.2.4", Text: "read-only-port", State: check.PASS, Scored: true},
+			},
+		}},
+	}
+}
+
+func TestIssueKey(t *testing.T) {
+	a := &issueExporter{cluster: "prod", node: "node-1"}
+	b := &issueExporter{cluster: "prod", node: "node-2"}
+	c := &check.Check{ID: "4.2.1"}
+
+	assert.Equal(t, a.issueKey(c), a.issueKey(c))
+	assert.NotEqual(t, a.issueKey(c), b.issueKey(c))
+	assert.NotEqual(t, a.issueKey(c), a.issueKey(&check.Check{ID: "4.2.2"}))
+	assert.False(t, strings.ContainsAny(a.issueKey(c), " /:"))
+}
+
+func TestGitHubExporter(t *testing.T) {
+	e := &issueExporter{name: "github", cluster: "prod", node: "node-1"}
+	existing := e.issueKey(&check.Check{ID: "4.2.1"})
+
+	var created, commented []string
+	ts := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		assert.Equal(t, "token secret", r.Header.Get("Authorization"))
+		switch {
+		case r.URL.Path == "/search/issues":
+			if strings.Contains(r.URL.Query().Get("q"), existing) {
+				w.Write([]byte(`{"items":[{"number":7}]}`))
+				return
+			}
+			w.Write([]byte(`{"items":[]}`))
+		case r.URL.Path == "/repos/acme/cluster/issues":
+			var body map[string]interface{}
+			json.NewDecoder(r.Body).Decode(&body)
+			created = append(created, body["title"].(string))
+		case r.URL.Path == "/repos/acme/cluster/issues/7/comments":
+			commented = append(commented, r.URL.Path)
+		default:
+			http.NotFound(w, r)
+		}
+	}))
+	defer ts.Close()
+
+	v := viper.New()
+	v.Set("api_url", ts.URL)
+	v.Set("repository", "acme/cluster")
+	v.Set("token", "secret")
+	v.Set("cluster", "prod")
+	v.Set("node", "node-1")
+	exp, err := newGitHubExporter(v)
+	assert.NoError(t, err)
+
+	assert.NoError(t, exp.Export(issueControls()))
+	assert.Len(t, commented, 1, "the open issue of 4.2.1 is updated")
+	assert.Len(t, created, 1, "only new scored failures are opened")
+	assert.Contains(t, created[0], "4.2.2")
+
+	_, err = newGitHubExporter(viper.New())
+	assert.Error(t, err)
+}
+
+func TestJiraExporter(t *testing.T) {
+	var created int
+	ts := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		user, pass, ok := r.BasicAuth()
+		assert.True(t, ok)
+		assert.Equal(t, "bot", user)
+		assert.Equal(t, "secret", pass)
+		switch r.URL.Path {
+		case "/rest/api/2/search":
+			assert.Contains(t, r.URL.Query().Get("jql"), `project = "SEC"`)
+			w.Write([]byte(`{"issues":[]}`))
+		case "/rest/api/2/issue":
+			created++
+		default:
+			http.Error(w, "unexpected", http.StatusBadRequest)
+		}
+	}))
+	defer ts.Close()
+
+	v := viper.New()
+	v.Set("url", ts.URL)
+	v.Set("project", "SEC")
+	v.Set("user", "bot")
+	v.Set("token", "secret")
+	exp, err := newJiraExporter(v)
+	assert.NoError(t, err)
+
+	assert.NoError(t, exp.Export(issueControls()))
+	assert.Equal(t, 2, created)
+}
+
+func TestIssueExporterUpdate(t *testing.T) {
+	now := time.Date(2021, 1, 17, 12, 0, 0, 0, time.UTC)
+	e := &issueExporter{cluster: "prod", node: "node-1", now: func() time.Time { return now }}
+	controls := issueControls()
+	c := controls.Groups[0].Checks[0]
+
+	c.ActualValue = "root 1234 1 0 10:30 ? 00:00:12 /usr/bin/kubelet --anonymous-auth=true"
+	c.TestResults = []*check.TestResult{{Flag: "--anonymous-auth", Op: "eq", Value: "false", ActualValue: "true"}}
+	i := e.issue(controls, c)
+
+	c.ActualValue = "root 1234 1 0 10:31 ? 00:00:13 /usr/bin/kubelet --anonymous-auth=true"
+	assert.Equal(t, i.Result, e.issue(controls, c).Result, "the audit output isn't part of the result")
+
+	c.TestResults[0].ActualValue = "maybe"
+	changed := e.issue(controls, c)
+	assert.Equal(t, i.Key, changed.Key)
+	assert.NotEqual(t, i.Result, changed.Result)
+
+	controls.ScanID = "next-scan"
+	rerun := e.issue(controls, c)
+	assert.Equal(t, changed.Result, rerun.Result, "the scan ID is not part of the result")
+
+	human := ticketUpdate{Body: "Looking into it.", Time: now}
+	yesterday := now.Add(-24 * time.Hour)
+	for name, tc := range map[string]struct {
+		updates  []ticketUpdate
+		interval time.Duration
+		expected string
+	}{
+		"unchanged": {
+			updates:  []ticketUpdate{{Body: i.Body, Time: yesterday}, human},
+			expected: "",
+		},
+		"unchanged within the interval": {
+			updates:  []ticketUpdate{{Body: i.Body, Time: yesterday}},
+			interval: 48 * time.Hour,
+			expected: "",
+		},
+		"unchanged for longer than the interval": {
+			updates:  []ticketUpdate{{Body: i.Body, Time: yesterday}, human},
+			interval: 24 * time.Hour,
+			expected: "Still failing.",
+		},
+		"changed": {
+			updates:  []ticketUpdate{{Body: changed.Body, Time: yesterday}},
+			expected: "Failing with a different result.",
+		},
+		"changed back": {
+			updates:  []ticketUpdate{{Body: i.Body, Time: yesterday}, {Body: changed.Body, Time: now}},
+			expected: "Failing with a different result.",
+		},
+		"line endings of the tracker": {
+			updates:  []ticketUpdate{{Body: strings.Replace(i.Body, "\n", "\r\n", -1), Time: yesterday}},
+			expected: "",
+		},
+		"no update of kube-bench": {
+			updates:  []ticketUpdate{human},
+			expected: "Still failing.",
+		},
+	} {
+		t.Run(name, func(t *testing.T) {
+			e.commentInterval = tc.interval
+			text := e.update(&ticket{ID: "7", Updates: tc.updates}, i)
+			if tc.expected == "" {
+				assert.Empty(t, text)
+				return
+			}
+			assert.Equal(t, tc.expected+"\n\n"+i.Body, text)
+		})
+	}
+}
+
+func TestGitHubTrackerFind(t *testing.T) {
+	ts := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		switch r.URL.Path {
+		case "/search/issues":
+			w.Write([]byte(`{"items":[{"number":7,"body":"opened","created_at":"2021-01-01T00:00:00Z","comments":150}]}`))
+		case "/repos/acme/cluster/issues/7/comments":
+			assert.Equal(t, "100", r.URL.Query().Get("per_page"))
+			assert.Equal(t, "2", r.URL.Query().Get("page"), "the newest comments are on the last page")
+			w.Write([]byte(`[{"body":"latest","created_at":"2021-01-17T12:00:00Z"}]`))
+		default:
+			http.NotFound(w, r)
+		}
+	}))
+	defer ts.Close()
+
+	tracker := &gitHubTracker{apiURL: ts.URL, repo: "acme/cluster"}
+	tk, err := tracker.find("kube-bench-key")
+	assert.NoError(t, err)
+	assert.Equal(t, &ticket{ID: "7", Updates: []ticketUpdate{
+		{Body: "opened", Time: time.Date(2021, 1, 1, 0, 0, 0, 0, time.UTC)},
+		{Body: "latest", Time: time.Date(2021, 1, 17, 12, 0, 0, 0, time.UTC)},
+	}}, tk)
+}
+
+func TestJiraExporterComments(t *testing.T) {
+	e := &issueExporter{cluster: "prod", node: "node-1"}
+	controls := issueControls()
+	unchanged := e.issue(controls, controls.Groups[0].Checks[0])
+	body, err := json.Marshal(unchanged.Body)
+	assert.NoError(t, err)
+
+	var commented []string
+	ts := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		switch r.URL.Path {
+		case "/rest/api/2/search":
+			jql := r.URL.Query().Get("jql")
+			switch {
+			case strings.Contains(jql, unchanged.Key):
+				// The ticket of 4.2.1 was last updated by a person, after the
+				// comment of kube-bench with the same result.
+				w.Write([]byte(`{"issues":[{"key":"SEC-1","fields":{"description":"opened","created":"2021-01-01T00:00:00.000+0000",` +
+					`"comment":{"comments":[{"body":` + string(body) + `,"created":"2021-01-16T12:00:00.000+0000"},{"body":"on it","created":"2021-01-17T00:00:00.000+0000"}]}}}]}`))
+			default:
+				w.Write([]byte(`{"issues":[{"key":"SEC-2","fields":{"description":"opened by hand","created":"2021-01-01T00:00:00.000+0000"}}]}`))
+			}
+		case "/rest/api/2/issue/SEC-1/comment", "/rest/api/2/issue/SEC-2/comment":
+			commented = append(commented, r.URL.Path)
+		default:
+			http.Error(w, "unexpected", http.StatusBadRequest)
+		}
+	}))
+	defer ts.Close()
+
+	v := viper.New()
+	v.Set("url", ts.URL)
+	v.Set("project", "SEC")
+	v.Set("cluster", "prod")
+	v.Set("node", "node-1")
+	v.Set("comment_interval", "72h")
+	exp, err := newJiraExporter(v)
+	assert.NoError(t, err)
+	ie := exp.(*issueExporter)
+	assert.Equal(t, 72*time.Hour, ie.commentInterval)
+	ie.now = func() time.Time { return time.Date(2021, 1, 17, 12, 0, 0, 0, time.UTC) }
+
+	assert.NoError(t, exp.Export(controls))
+	assert.Equal(t, []string{"/rest/api/2/issue/SEC-2/comment"}, commented)
+
+	assert.Equal(t, time.Date(2021, 1, 16, 12, 0, 0, 0, time.UTC), parseJiraTime("2021-01-16T12:00:00.000+0000").UTC())
+	assert.True(t, parseJiraTime("yesterday").IsZero())
+}
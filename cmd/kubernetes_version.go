@@ -6,6 +6,7 @@ import (
 	"encoding/pem"
 	"fmt"
 	"io/ioutil"
+	"net"
 	"net/http"
 	"os"
 	"strings"
@@ -16,20 +17,10 @@ import (
 
 func getKubeVersionFromRESTAPI() (string, error) {
 	k8sVersionURL := getKubernetesURL()
-	serviceaccount := "/var/run/secrets/kubernetes.io/serviceaccount"
-	cacertfile := fmt.Sprintf("%s/ca.crt", serviceaccount)
-	tokenfile := fmt.Sprintf("%s/token", serviceaccount)
-
-	tlsCert, err := loadCertficate(cacertfile)
-	if err != nil {
-		return "", err
-	}
-
-	tb, err := ioutil.ReadFile(tokenfile)
+	token, tlsCert, err := loadServiceAccount(serviceAccountDir)
 	if err != nil {
 		return "", err
 	}
-	token := strings.TrimSpace(string(tb))
 
 	data, err := getWebDataWithRetry(k8sVersionURL, token, tlsCert)
 	if err != nil {
@@ -144,16 +135,19 @@ func loadCertficate(certFile string) (*tls.Certificate, error) {
 func getKubernetesURL() string {
 	k8sVersionURL := "https://kubernetes.default.svc/version"
 
-	// The following provides flexibility to use
-	// K8S provided variables is situations where
-	// hostNetwork: true
-	if !isEmpty(os.Getenv("KUBE_BENCH_K8S_ENV")) {
-		k8sHost := os.Getenv("KUBERNETES_SERVICE_HOST")
-		k8sPort := os.Getenv("KUBERNETES_SERVICE_PORT_HTTPS")
-		if !isEmpty(k8sHost) && !isEmpty(k8sPort) {
-			return fmt.Sprintf("https://%s:%s/version", k8sHost, k8sPort)
-		}
+	// Pods are given the address of the kubernetes service in their
+	// environment. It works whatever the cluster domain, with hostNetwork: true
+	// where the service name doesn't resolve, and on IPv6 clusters.
+	k8sHost := strings.Trim(os.Getenv("KUBERNETES_SERVICE_HOST"), "[]")
+	k8sPort := os.Getenv("KUBERNETES_SERVICE_PORT_HTTPS")
+	if isEmpty(k8sPort) {
+		k8sPort = os.Getenv("KUBERNETES_SERVICE_PORT")
+	}
+	if !isEmpty(k8sHost) && !isEmpty(k8sPort) {
+		return fmt.Sprintf("https://%s/version", net.JoinHostPort(k8sHost, k8sPort))
+	}
 
+	if !isEmpty(os.Getenv("KUBE_BENCH_K8S_ENV")) {
 		glog.V(2).Info(fmt.Sprintf("KUBE_BENCH_K8S_ENV is set, but environment variables KUBERNETES_SERVICE_HOST or KUBERNETES_SERVICE_PORT_HTTPS are not set"))
 	}
 
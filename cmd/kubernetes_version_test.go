@@ -279,3 +279,46 @@ func TestGetKubernetesURL(t *testing.T) {
 	}
 
 }
+
+func TestGetKubernetesURLFromServiceEnv(t *testing.T) {
+	envs := []string{"KUBE_BENCH_K8S_ENV", "KUBERNETES_SERVICE_HOST", "KUBERNETES_SERVICE_PORT_HTTPS", "KUBERNETES_SERVICE_PORT"}
+	resetEnvs := func() {
+		for _, e := range envs {
+			os.Unsetenv(e)
+		}
+	}
+	defer resetEnvs()
+
+	cases := []struct {
+		env      map[string]string
+		expected string
+	}{
+		{
+			env:      map[string]string{"KUBERNETES_SERVICE_HOST": "10.96.0.1", "KUBERNETES_SERVICE_PORT_HTTPS": "443"},
+			expected: "https://10.96.0.1:443/version",
+		},
+		{
+			env:      map[string]string{"KUBERNETES_SERVICE_HOST": "fd00:10:96::1", "KUBERNETES_SERVICE_PORT_HTTPS": "443"},
+			expected: "https://[fd00:10:96::1]:443/version",
+		},
+		{
+			env:      map[string]string{"KUBERNETES_SERVICE_HOST": "[fd00:10:96::1]", "KUBERNETES_SERVICE_PORT": "6443"},
+			expected: "https://[fd00:10:96::1]:6443/version",
+		},
+		{
+			env:      map[string]string{"KUBERNETES_SERVICE_HOST": "10.96.0.1"},
+			expected: "https://kubernetes.default.svc/version",
+		},
+	}
+	for id, c := range cases {
+		t.Run(strconv.Itoa(id), func(t *testing.T) {
+			resetEnvs()
+			for k, v := range c.env {
+				os.Setenv(k, v)
+			}
+			if k8sURL := getKubernetesURL(); k8sURL != c.expected {
+				t.Errorf("Expected %q but Got %q", c.expected, k8sURL)
+			}
+		})
+	}
+}
@@ -0,0 +1,108 @@
+package cmd
+
+import (
+	"fmt"
+	"os"
+	"path/filepath"
+	"time"
+
+	"github.com/golang/glog"
+)
+
+// lockedExitCode is the exit code of a run that found another one holding the
+// lock with --lock-mode exit, or still holding it after --lock-timeout.
+const lockedExitCode = 4
+
+const (
+	lockModeWait = "wait"
+	lockModeExit = "exit"
+	lockModeOff  = "off"
+)
+
+var (
+	lockMode    string
+	lockTimeout time.Duration
+	// lockPath is in a directory only root can write to, so that other users
+	// can neither hold the lock nor replace the file with a symlink.
+	lockPath = filepath.Join("/var/run", "kube-bench.lock")
+	// lockPollInterval is how often a waiting run tries to take the lock.
+	lockPollInterval = time.Second
+)
+
+// acquireRunLock keeps two runs of kube-bench on the same node, such as
+// overlapping CronJobs sharing the lock file through a hostPath volume, from
+// interleaving. Depending on --lock-mode, a run waits for the other to finish,
+// for up to --lock-timeout, or exits with lockedExitCode. It returns the
+// function releasing the lock, which is also released when kube-bench exits.
+// If the lock file can't be used, the run goes on without the lock.
+func acquireRunLock() func() {
+	if lockMode == lockModeOff {
+		return func() {}
+	}
+	if lockMode != lockModeWait && lockMode != lockModeExit {
+		exitWithError(configError{fmt.Errorf("unknown --lock-mode %q, valid modes are exit, off and wait", lockMode)})
+	}
+
+	release, ok, err := lockRun(lockPath, 0)
+	if err != nil {
+		glog.Warningf("Unable to lock %s, running without the lock: %v", lockPath, err)
+		return func() {}
+	}
+	if ok {
+		return release
+	}
+
+	if lockMode == lockModeExit {
+		glog.Warningf("Another kube-bench run holds the lock %s, exiting", lockPath)
+		glog.Flush()
+		os.Exit(lockedExitCode)
+	}
+	glog.Warningf("Another kube-bench run holds the lock %s, waiting up to %v for it to finish", lockPath, lockTimeout)
+	release, ok, err = lockRun(lockPath, lockTimeout)
+	if err != nil {
+		glog.Warningf("Unable to lock %s, running without the lock: %v", lockPath, err)
+		return func() {}
+	}
+	if !ok {
+		glog.Warningf("Another kube-bench run still holds the lock %s after %v, exiting", lockPath, lockTimeout)
+		glog.Flush()
+		os.Exit(lockedExitCode)
+	}
+	return release
+}
+
+// lockRun takes an exclusive lock on the file at path, trying again for up to
+// timeout, and reports whether it got it. The file must be a regular file
+// owned by the user, which other users can't write to. The PID of the run is
+// written to the file, for whoever wonders which run holds it.
+func lockRun(path string, timeout time.Duration) (func(), bool, error) {
+	f, err := openLockFile(path)
+	if err != nil {
+		return nil, false, err
+	}
+
+	deadline := time.Now().Add(timeout)
+	for {
+		ok, err := lockFile(f)
+		if err != nil {
+			f.Close()
+			return nil, false, err
+		}
+		if ok {
+			break
+		}
+		if !time.Now().Before(deadline) {
+			f.Close()
+			return nil, false, nil
+		}
+		time.Sleep(lockPollInterval)
+	}
+
+	if err := f.Truncate(0); err == nil {
+		fmt.Fprintf(f, "%d\n", os.Getpid())
+	}
+	return func() {
+		unlockFile(f)
+		f.Close()
+	}, true, nil
+}
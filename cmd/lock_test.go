@@ -0,0 +1,96 @@
+package cmd
+
+import (
+	"io/ioutil"
+	"os"
+	"path/filepath"
+	"strconv"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+)
+
+func TestLockRun(t *testing.T) {
+	dir, err := ioutil.TempDir("", "kube-bench-lock-")
+	assert.NoError(t, err)
+	defer os.RemoveAll(dir)
+	path := filepath.Join(dir, "kube-bench.lock")
+
+	defer func(interval time.Duration) { lockPollInterval = interval }(lockPollInterval)
+	lockPollInterval = 10 * time.Millisecond
+
+	release, ok, err := lockRun(path, 0)
+	assert.NoError(t, err)
+	assert.True(t, ok)
+	pid, _ := ioutil.ReadFile(path)
+	assert.Equal(t, strconv.Itoa(os.Getpid()), strings.TrimSpace(string(pid)))
+
+	// Another run doesn't get the lock, or waits for it up to a timeout.
+	_, ok, err = lockRun(path, 0)
+	assert.NoError(t, err)
+	assert.False(t, ok)
+	_, ok, err = lockRun(path, 50*time.Millisecond)
+	assert.NoError(t, err)
+	assert.False(t, ok)
+
+	locked := make(chan func())
+	go func() {
+		release, _, _ := lockRun(path, time.Minute)
+		locked <- release
+	}()
+	select {
+	case <-locked:
+		t.Fatal("the lock was taken while held by another run")
+	case <-time.After(100 * time.Millisecond):
+	}
+
+	release()
+	select {
+	case release := <-locked:
+		release()
+	case <-time.After(5 * time.Second):
+		t.Fatal("the waiting run didn't get the released lock")
+	}
+}
+
+func TestAcquireRunLockOff(t *testing.T) {
+	defer func(mode, path string) { lockMode, lockPath = mode, path }(lockMode, lockPath)
+	lockMode = lockModeOff
+	lockPath = filepath.Join("/nonexistent", "kube-bench.lock")
+
+	// No lock file is needed when the lock is off.
+	acquireRunLock()()
+}
+
+func TestLockRunUnsafeFile(t *testing.T) {
+	dir, err := ioutil.TempDir("", "kube-bench-lock-")
+	assert.NoError(t, err)
+	defer os.RemoveAll(dir)
+
+	// A symlink planted in place of the lock file isn't followed.
+	target := filepath.Join(dir, "target")
+	assert.NoError(t, ioutil.WriteFile(target, []byte("precious"), 0644))
+	link := filepath.Join(dir, "link.lock")
+	assert.NoError(t, os.Symlink(target, link))
+	_, _, err = lockRun(link, 0)
+	assert.Error(t, err)
+	content, _ := ioutil.ReadFile(target)
+	assert.Equal(t, "precious", string(content))
+
+	shared := filepath.Join(dir, "shared.lock")
+	assert.NoError(t, ioutil.WriteFile(shared, nil, 0644))
+	assert.NoError(t, os.Chmod(shared, 0666))
+	_, _, err = lockRun(shared, 0)
+	assert.Error(t, err, "a lock file other users can write to is refused")
+}
+
+func TestAcquireRunLockUnavailable(t *testing.T) {
+	defer func(mode, path string) { lockMode, lockPath = mode, path }(lockMode, lockPath)
+	lockMode = lockModeWait
+	lockPath = filepath.Join("/nonexistent", "kube-bench.lock")
+
+	// The run goes on without the lock when the lock file can't be opened.
+	acquireRunLock()()
+}
@@ -0,0 +1,201 @@
+package cmd
+
+import (
+	"fmt"
+	"io/ioutil"
+	"os"
+	"path"
+	"path/filepath"
+	"regexp"
+	"sort"
+	"strconv"
+	"strings"
+	"time"
+
+	"github.com/aquasecurity/kube-bench/check"
+	"github.com/golang/glog"
+	"github.com/spf13/viper"
+	"gopkg.in/yaml.v2"
+)
+
+var (
+	mockMode bool
+	mock     *mockHost
+
+	mockPsAudit   = regexp.MustCompile(`ps -ef \| (?:/bin/)?grep (\S+)|ps -fC (\S+)`)
+	mockStatAudit = regexp.MustCompile(`stat -c ((?:\\ |\S)+) (/[^\s;']+)`)
+	mockCatAudit  = regexp.MustCompile(`cat (/[^\s;']+)`)
+
+	// mockPipelines are the commands that may follow ps in an audit. The
+	// lines of the process matching the grep are the output of the audit.
+	mockPipelines = regexp.MustCompile(`^(?:\s*\|\s*(?:/bin/)?grep -v grep)*\s*$`)
+)
+
+// mockHost is a recorded host, which the checks are evaluated against
+// with --mock instead of the host kube-bench runs on.
+type mockHost struct {
+	Version   string               `yaml:"version"`
+	Processes []string             `yaml:"processes"`
+	Files     map[string]*mockFile `yaml:"files"`
+}
+
+type mockFile struct {
+	Mode    string `yaml:"mode"`
+	Owner   string `yaml:"owner"`
+	Content string `yaml:"content"`
+}
+
+// loadMockHost reads the recorded host bundled in the config directory.
+func loadMockHost(file string) (*mockHost, error) {
+	in, err := ioutil.ReadFile(file)
+	if err != nil {
+		return nil, fmt.Errorf("failed to read mock host %s: %v", file, err)
+	}
+
+	m := new(mockHost)
+	if err := yaml.Unmarshal(in, m); err != nil {
+		return nil, fmt.Errorf("failed to load mock host %s: %v", file, err)
+	}
+	return m, nil
+}
+
+// setupMock replaces the host with the recorded one bundled in the config
+// directory: running processes, files and Kubernetes version.
+func setupMock() {
+	m, err := loadMockHost(filepath.Join(cfgDir, "mock", "host.yaml"))
+	if err != nil {
+		exitWithError(err)
+	}
+	glog.V(1).Info("Running checks against the recorded mock host")
+
+	mock = m
+	psFunc = m.ps
+	statFunc = m.stat
+	if kubeVersion == "" && benchmarkVersion == "" {
+		kubeVersion = m.Version
+	}
+	hostCapsOnce.Do(func() {
+		hostCaps = hostCapabilities{pid: true, filesystem: true, root: true}
+	})
+}
+
+// ps returns the command line of the processes named proc, as ps -C does.
+func (m *mockHost) ps(proc string) string {
+	var out []string
+	for _, p := range m.Processes {
+		if fields := strings.Fields(p); len(fields) > 0 && path.Base(fields[0]) == proc {
+			out = append(out, p)
+		}
+	}
+	return strings.Join(out, "\n")
+}
+
+func (m *mockHost) stat(name string) (os.FileInfo, error) {
+	f, ok := m.Files[name]
+	if !ok {
+		return nil, &os.PathError{Op: "stat", Path: name, Err: os.ErrNotExist}
+	}
+	return mockFileInfo{name: name, file: f}, nil
+}
+
+// audit returns the output of an audit command on the recorded host. It
+// supports the audits used by the bundled benchmarks: listing the processes
+// with ps, the permissions and owner of files with stat, and their content
+// with cat.
+func (m *mockHost) audit(audit string) (string, error) {
+	if loc := mockPsAudit.FindStringSubmatchIndex(audit); loc != nil {
+		if !mockPipelines.MatchString(audit[loc[1]:]) {
+			return "", fmt.Errorf("audit %q is not supported in mock mode", audit)
+		}
+		grep := mockPsAudit.FindStringSubmatch(audit)
+		proc := strings.Trim(grep[1]+grep[2], `'"`)
+
+		var out []string
+		for i, p := range m.Processes {
+			// ps -C selects the processes by name, while grep matches the whole line.
+			match := strings.Contains(p, proc)
+			if grep[2] != "" {
+				fields := strings.Fields(p)
+				match = len(fields) > 0 && path.Base(fields[0]) == proc
+			}
+			if match {
+				out = append(out, fmt.Sprintf("root %6d 1 0 Jan01 ? 00:10:00 %s", 1000+i, p))
+			}
+		}
+		return strings.Join(out, "\n"), nil
+	}
+
+	if matches := mockStatAudit.FindStringSubmatch(audit); matches != nil {
+		format := strings.Replace(strings.Trim(matches[1], `'"`), `\ `, " ", -1)
+		var out []string
+		for _, name := range m.glob(matches[2]) {
+			f := m.Files[name]
+			out = append(out, strings.NewReplacer("%a", f.Mode, "%U:%G", f.Owner, "%n", name).Replace(format))
+		}
+		return strings.Join(out, "\n"), nil
+	}
+
+	if matches := mockCatAudit.FindStringSubmatch(audit); matches != nil {
+		in, err := m.readFile(matches[1])
+		if err != nil {
+			return "", fmt.Errorf("cat: %s: No such file or directory", matches[1])
+		}
+		return string(in), nil
+	}
+
+	return "", fmt.Errorf("audit %q is not supported in mock mode", audit)
+}
+
+// readFile returns the content of a recorded file.
+func (m *mockHost) readFile(name string) ([]byte, error) {
+	f, ok := m.Files[name]
+	if !ok {
+		return nil, &os.PathError{Op: "open", Path: name, Err: os.ErrNotExist}
+	}
+	return []byte(f.Content), nil
+}
+
+// glob returns the recorded files matching pattern, in order.
+func (m *mockHost) glob(pattern string) []string {
+	var names []string
+	for name := range m.Files {
+		if ok, _ := path.Match(pattern, name); ok {
+			names = append(names, name)
+		}
+	}
+	sort.Strings(names)
+	return names
+}
+
+type mockFileInfo struct {
+	name string
+	file *mockFile
+}
+
+func (fi mockFileInfo) Name() string       { return path.Base(fi.name) }
+func (fi mockFileInfo) Size() int64        { return int64(len(fi.file.Content)) }
+func (fi mockFileInfo) ModTime() time.Time { return time.Time{} }
+func (fi mockFileInfo) IsDir() bool        { return false }
+func (fi mockFileInfo) Sys() interface{}   { return nil }
+
+func (fi mockFileInfo) Mode() os.FileMode {
+	mode, _ := strconv.ParseUint(fi.file.Mode, 8, 32)
+	return os.FileMode(mode)
+}
+
+// newRunner returns the Runner of the checks: against the recorded host with
+// --mock, against the flags of the components with --flagz, evaluated by
+// kube-bench itself with --native, or running the audit commands within the
+// configured limits.
+func newRunner() check.Runner {
+	if mock != nil {
+		return check.NewAuditRunner(mock.audit)
+	}
+	if flagz != nil {
+		return check.NewAuditRunner(flagzAudit)
+	}
+	if nativeMode {
+		return check.NewAuditRunner(nativeAudit)
+	}
+	return check.NewLimitedRunner(getAuditLimits(viper.GetViper()))
+}
@@ -0,0 +1,52 @@
+package cmd
+
+import (
+	"os"
+	"strings"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+)
+
+func TestMockHost(t *testing.T) {
+	m, err := loadMockHost("../cfg/mock/host.yaml")
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	assert.Equal(t, "/usr/bin/kubelet --bootstrap-kubeconfig=/etc/kubernetes/bootstrap-kubelet.conf --kubeconfig=/etc/kubernetes/kubelet.conf --config=/var/lib/kubelet/config.yaml --network-plugin=cni --pod-infra-container-image=k8s.gcr.io/pause:3.2", m.ps("kubelet"))
+	assert.Equal(t, "", m.ps("kube"))
+
+	fi, err := m.stat("/etc/kubernetes/admin.conf")
+	assert.NoError(t, err)
+	assert.Equal(t, os.FileMode(0600), fi.Mode())
+	_, err = m.stat("/etc/kubernetes/missing.conf")
+	assert.True(t, os.IsNotExist(err))
+
+	out, err := m.audit("/bin/ps -ef | grep kube-scheduler | grep -v grep")
+	assert.NoError(t, err)
+	assert.Contains(t, out, "--leader-elect=true")
+	assert.Equal(t, 1, len(strings.Split(out, "\n")))
+
+	out, err = m.audit("/bin/ps -fC kubelet")
+	assert.NoError(t, err)
+	assert.Contains(t, out, "--config=/var/lib/kubelet/config.yaml")
+	assert.NotContains(t, out, "kube-apiserver", "ps -C selects processes by name")
+
+	out, err = m.audit("/bin/sh -c 'if test -e /etc/kubernetes/admin.conf; then stat -c %U:%G /etc/kubernetes/admin.conf; fi'")
+	assert.NoError(t, err)
+	assert.Equal(t, "root:root", out)
+
+	out, err = m.audit(`stat -c %n\ %a /etc/kubernetes/pki/*.key`)
+	assert.NoError(t, err)
+	assert.Equal(t, "/etc/kubernetes/pki/apiserver.key 640\n/etc/kubernetes/pki/ca.key 600\n/etc/kubernetes/pki/sa.key 600", out)
+
+	out, err = m.audit("/bin/cat /var/lib/kubelet/config.yaml")
+	assert.NoError(t, err)
+	assert.Contains(t, out, "readOnlyPort: 0")
+
+	_, err = m.audit("ps -ef | grep etcd | grep -- --data-dir | sed 's%.*data-dir[= ]\\([^ ]*\\).*%\\1%' | xargs stat -c %U:%G")
+	assert.Error(t, err)
+	_, err = m.audit("ls -laR /etc/kubernetes/pki/")
+	assert.Error(t, err)
+}
@@ -0,0 +1,61 @@
+package cmd
+
+import (
+	"io/ioutil"
+	"os"
+	"os/user"
+	"path/filepath"
+	"strconv"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+)
+
+func TestNativeAudit(t *testing.T) {
+	dir, err := ioutil.TempDir("", "kube-bench-native-")
+	assert.NoError(t, err)
+	defer os.RemoveAll(dir)
+
+	proc := filepath.Join(dir, "proc")
+	for pid, cmdline := range map[string]string{
+		"1":    "/sbin/init\x00",
+		"2":    "",
+		"1001": "/usr/bin/kubelet\x00--anonymous-auth=false\x00--config=/var/lib/kubelet/config.yaml\x00",
+		"self": "",
+	} {
+		assert.NoError(t, os.MkdirAll(filepath.Join(proc, pid), 0755))
+		assert.NoError(t, ioutil.WriteFile(filepath.Join(proc, pid, "cmdline"), []byte(cmdline), 0644))
+	}
+	defer func(dir string) { procDir = dir }(procDir)
+	procDir = proc
+
+	assert.Equal(t, []string{"/sbin/init", "/usr/bin/kubelet --anonymous-auth=false --config=/var/lib/kubelet/config.yaml"}, readProcesses(proc))
+	assert.Equal(t, "/usr/bin/kubelet --anonymous-auth=false --config=/var/lib/kubelet/config.yaml", nativePs("kubelet"))
+
+	out, err := nativeAudit("/bin/ps -fC kubelet")
+	assert.NoError(t, err)
+	assert.Contains(t, out, "/usr/bin/kubelet --anonymous-auth=false")
+
+	conf := filepath.Join(dir, "kubelet.conf")
+	assert.NoError(t, ioutil.WriteFile(conf, []byte("readOnlyPort: 0\n"), 0600))
+	assert.NoError(t, os.Chmod(conf, 0600))
+
+	out, err = nativeAudit("/bin/sh -c 'if test -e " + conf + "; then stat -c %a " + conf + "; fi'")
+	assert.NoError(t, err)
+	assert.Equal(t, "600", out)
+
+	u, err := user.Current()
+	assert.NoError(t, err)
+	g, err := user.LookupGroupId(strconv.Itoa(os.Getgid()))
+	assert.NoError(t, err)
+	out, err = nativeAudit("stat -c %U:%G " + conf)
+	assert.NoError(t, err)
+	assert.Equal(t, u.Username+":"+g.Name, out)
+
+	out, err = nativeAudit("cat " + conf)
+	assert.NoError(t, err)
+	assert.Equal(t, "readOnlyPort: 0\n", out)
+
+	_, err = nativeAudit("journalctl -u kubelet")
+	assert.EqualError(t, err, `audit "journalctl -u kubelet" has no built-in implementation, run it without --native`)
+}
@@ -0,0 +1,386 @@
+package cmd
+
+import (
+	"bytes"
+	"encoding/json"
+	"fmt"
+	"io/ioutil"
+	"net/http"
+	"os"
+	"path/filepath"
+	"sort"
+	"strings"
+	"time"
+
+	"github.com/aquasecurity/kube-bench/check"
+	"github.com/golang/glog"
+	"github.com/spf13/viper"
+)
+
+const (
+	batchBySection = "section"
+	batchByGroup   = "group"
+	batchByCheck   = "check"
+	batchByOwner   = "owner"
+
+	// Events of notification batches.
+	eventFailures = "failures"
+	eventDrift    = "drift"
+
+	// defaultNotifyStateFile is where the failures seen in previous runs are
+	// recorded, in a directory only root can write to, unlike the temporary
+	// directory.
+	defaultNotifyStateFile = "/var/lib/kube-bench/notifications.json"
+)
+
+// notifyFinding is a single failed check, or a check whose state changed,
+// reported to a notifier sink.
+type notifyFinding struct {
+	NodeType    check.NodeType `json:"node_type"`
+	Section     string         `json:"section"`
+	Group       string         `json:"group"`
+	ID          string         `json:"test_number"`
+	Text        string         `json:"test_desc"`
+	Remediation string         `json:"remediation"`
+	State       check.State    `json:"status"`
+	Owner       string         `json:"owner,omitempty"`
+	// Annotations are the pairs of --annotate.
+	Annotations map[string]string `json:"annotations,omitempty"`
+	// Previous is the state of the check before a drift event.
+	Previous check.State `json:"previous_status,omitempty"`
+}
+
+// notifyBatch is a set of findings delivered to a sink in a single message.
+type notifyBatch struct {
+	ScanID   string          `json:"scan_id"`
+	Event    string          `json:"event"`
+	Key      string          `json:"key"`
+	Findings []notifyFinding `json:"findings"`
+}
+
+// Notifier delivers batches of findings to an external sink.
+type Notifier interface {
+	Name() string
+	Notify(batch notifyBatch) error
+}
+
+// notifyPolicy decides which findings are worth sending and how they are batched,
+// so that scheduled scans don't repeat the same failures on every run.
+type notifyPolicy struct {
+	OnlyNew        bool
+	RepeatInterval time.Duration
+	BatchBy        string
+	now            func() time.Time
+}
+
+// notifyState records the failing checks seen in previous runs,
+// together with the time they were last notified.
+type notifyState struct {
+	Failing map[string]time.Time `json:"failing"`
+}
+
+func newNotifyPolicy(v *viper.Viper) *notifyPolicy {
+	p := &notifyPolicy{
+		OnlyNew: true,
+		BatchBy: batchBySection,
+		now:     time.Now,
+	}
+	if v == nil {
+		return p
+	}
+
+	if v.IsSet("only_new") {
+		p.OnlyNew = v.GetBool("only_new")
+	}
+	p.RepeatInterval = v.GetDuration("repeat_interval")
+	if by := v.GetString("batch_by"); by != "" {
+		p.BatchBy = by
+	}
+
+	return p
+}
+
+// recovered tells whether a check which isn't failing ran and passed. Checks
+// which didn't run, because they weren't selected or the scan was interrupted,
+// or couldn't be evaluated, may still be failing.
+func recovered(c *check.Check) bool {
+	if c.ReasonCode == check.ReasonFiltered || c.ReasonCode == check.ReasonInterrupted {
+		return false
+	}
+	return c.State == check.PASS || c.State == check.INFO
+}
+
+func findingKey(nodetype check.NodeType, id string) string {
+	return fmt.Sprintf("%s/%s", nodetype, id)
+}
+
+// apply returns the failed checks of controls that should be notified, and
+// updates state to reflect the outcome of this run.
+func (p *notifyPolicy) apply(controls *check.Controls, state *notifyState) []notifyFinding {
+	if state.Failing == nil {
+		state.Failing = make(map[string]time.Time)
+	}
+
+	now := p.now().UTC()
+	var findings []notifyFinding
+	for _, g := range controls.Groups {
+		for _, c := range g.Checks {
+			key := findingKey(controls.Type, c.ID)
+			if c.State != check.FAIL {
+				// The check recovered; a later failure counts as new again.
+				if recovered(c) {
+					delete(state.Failing, key)
+				}
+				continue
+			}
+
+			last, seen := state.Failing[key]
+			switch {
+			case !seen:
+			case p.RepeatInterval > 0 && now.Sub(last) >= p.RepeatInterval:
+			case !p.OnlyNew && p.RepeatInterval == 0:
+			default:
+				glog.V(2).Info(fmt.Sprintf("Suppressing notification for %s, last notified at %s", key, formatTime(last)))
+				continue
+			}
+
+			state.Failing[key] = now
+			findings = append(findings, notifyFinding{
+				NodeType:    controls.Type,
+				Section:     fmt.Sprintf("%s %s", controls.ID, controls.Text),
+				Group:       fmt.Sprintf("%s %s", g.ID, g.Text),
+				ID:          c.ID,
+				Text:        c.Text,
+				Remediation: c.Remediation,
+				State:       c.State,
+				Owner:       c.Owner,
+				Annotations: c.Annotations,
+			})
+		}
+	}
+
+	return findings
+}
+
+// batch groups findings according to the policy's batch_by setting.
+func (p *notifyPolicy) batch(findings []notifyFinding) []notifyBatch {
+	var keys []string
+	m := make(map[string]*notifyBatch)
+
+	for _, f := range findings {
+		var key string
+		switch p.BatchBy {
+		case batchByGroup:
+			key = f.Group
+		case batchByCheck:
+			key = f.ID
+		case batchByOwner:
+			key = f.Owner
+		default:
+			key = f.Section
+		}
+
+		b, ok := m[key]
+		if !ok {
+			b = &notifyBatch{ScanID: scanID, Event: eventFailures, Key: key}
+			m[key] = b
+			keys = append(keys, key)
+		}
+		b.Findings = append(b.Findings, f)
+	}
+
+	batches := make([]notifyBatch, 0, len(keys))
+	for _, k := range keys {
+		batches = append(batches, *m[k])
+	}
+	return batches
+}
+
+func loadNotifyState(path string) (*notifyState, error) {
+	state := &notifyState{Failing: make(map[string]time.Time)}
+
+	data, err := ioutil.ReadFile(path)
+	if err != nil {
+		if os.IsNotExist(err) {
+			return state, nil
+		}
+		return nil, err
+	}
+
+	if err := json.Unmarshal(data, state); err != nil {
+		return nil, fmt.Errorf("failed to parse notification state %s: %v", path, err)
+	}
+	if state.Failing == nil {
+		state.Failing = make(map[string]time.Time)
+	}
+
+	return state, nil
+}
+
+func saveNotifyState(path string, state *notifyState) error {
+	data, err := json.Marshal(state)
+	if err != nil {
+		return err
+	}
+
+	if err := os.MkdirAll(filepath.Dir(path), 0700); err != nil {
+		return err
+	}
+	return ioutil.WriteFile(path, data, 0600)
+}
+
+// getNotifiers builds the configured notifier sinks.
+func getNotifiers(v *viper.Viper) []Notifier {
+	var notifiers []Notifier
+	if v == nil {
+		return notifiers
+	}
+
+	if url := v.GetString("sinks.webhook.url"); url != "" {
+		notifiers = append(notifiers, &webhookNotifier{
+			url:            url,
+			maxPayloadSize: v.GetInt("sinks.webhook.max_payload_size"),
+			compress:       v.GetBool("sinks.webhook.compress"),
+		})
+	}
+	if url := v.GetString("sinks.slack.url"); url != "" {
+		notifiers = append(notifiers, &slackNotifier{url: url})
+	}
+
+	return notifiers
+}
+
+// notifyResults sends the failures found in controls to every configured sink,
+// subject to the notification policy.
+func notifyResults(controls *check.Controls) {
+	conf := viper.Sub("notifications")
+	notifiers := getNotifiers(conf)
+	if len(notifiers) == 0 {
+		glog.Warning("--notify was specified but no notification sinks are configured")
+		return
+	}
+
+	stateFile := defaultNotifyStateFile
+	if conf.GetString("state_file") != "" {
+		stateFile = conf.GetString("state_file")
+	}
+
+	state, err := loadNotifyState(stateFile)
+	if err != nil {
+		glog.Warningf("Unable to load notification state, all failures will be notified: %v", err)
+		state = &notifyState{}
+	}
+
+	policy := newNotifyPolicy(conf)
+	previous := make(map[string]time.Time, len(state.Failing))
+	for k, v := range state.Failing {
+		previous[k] = v
+	}
+	deliverBatches(notifiers, policy.batch(policy.apply(controls, state)), state, previous)
+
+	if err := saveNotifyState(stateFile, state); err != nil {
+		glog.Warningf("Unable to save notification state %s: %v", stateFile, err)
+	}
+}
+
+// deliverBatches sends every batch to the notifiers. The findings of a batch
+// which no notifier accepted get back their entry of previous in state, so
+// that they are sent again on the next run rather than taken as notified.
+func deliverBatches(notifiers []Notifier, batches []notifyBatch, state *notifyState, previous map[string]time.Time) {
+	for _, b := range batches {
+		delivered := false
+		for _, n := range notifiers {
+			if err := n.Notify(b); err != nil {
+				glog.Warningf("%s notification for %q failed: %v", n.Name(), b.Key, err)
+				continue
+			}
+			delivered = true
+		}
+		if delivered {
+			continue
+		}
+
+		for _, f := range b.Findings {
+			key := findingKey(f.NodeType, f.ID)
+			if last, ok := previous[key]; ok {
+				state.Failing[key] = last
+			} else {
+				delete(state.Failing, key)
+			}
+		}
+	}
+}
+
+var notifyClient = &http.Client{Timeout: 10 * time.Second}
+
+func postJSON(url string, payload interface{}) error {
+	body, err := json.Marshal(payload)
+	if err != nil {
+		return err
+	}
+
+	resp, err := notifyClient.Post(url, "application/json", bytes.NewReader(body))
+	if err != nil {
+		return err
+	}
+	defer resp.Body.Close()
+
+	if resp.StatusCode < 200 || resp.StatusCode > 299 {
+		return fmt.Errorf("URL:[%s], StatusCode:[%d]", url, resp.StatusCode)
+	}
+	return nil
+}
+
+// webhookNotifier posts each batch as a JSON document, compressed or split
+// into chunks like the results of the webhook exporter.
+type webhookNotifier struct {
+	url            string
+	maxPayloadSize int
+	compress       bool
+}
+
+func (w *webhookNotifier) Name() string { return "webhook" }
+
+func (w *webhookNotifier) Notify(batch notifyBatch) error {
+	body, err := json.Marshal(batch)
+	if err != nil {
+		return err
+	}
+
+	bodies, err := chunkPayload(fmt.Sprintf("%s/%s/%s", batch.ScanID, batch.Event, batch.Key), body, w.maxPayloadSize, w.compress)
+	if err != nil {
+		return err
+	}
+	for _, b := range bodies {
+		if err := postJSON(w.url, json.RawMessage(b)); err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+// slackNotifier posts each batch as a message to a Slack incoming webhook.
+type slackNotifier struct {
+	url string
+}
+
+func (s *slackNotifier) Name() string { return "slack" }
+
+func (s *slackNotifier) Notify(batch notifyBatch) error {
+	ids := make([]string, 0, len(batch.Findings))
+	for _, f := range batch.Findings {
+		if batch.Event == eventDrift {
+			ids = append(ids, fmt.Sprintf("%s %s: %s -> %s", f.ID, f.Text, f.Previous, f.State))
+			continue
+		}
+		ids = append(ids, fmt.Sprintf("%s %s", f.ID, f.Text))
+	}
+	sort.Strings(ids)
+
+	summary := "new failure(s)"
+	if batch.Event == eventDrift {
+		summary = "state change(s)"
+	}
+	text := fmt.Sprintf("kube-bench: %d %s in %s (scan %s)\n%s", len(ids), summary, batch.Key, batch.ScanID, strings.Join(ids, "\n"))
+	return postJSON(s.url, map[string]string{"text": text})
+}
@@ -0,0 +1,216 @@
+package cmd
+
+import (
+	"encoding/json"
+	"fmt"
+	"io/ioutil"
+	"net/http"
+	"net/http/httptest"
+	"os"
+	"path/filepath"
+	"testing"
+	"time"
+
+	"github.com/aquasecurity/kube-bench/check"
+	"github.com/stretchr/testify/assert"
+)
+
+func notifyControls(states ...check.State) *check.Controls {
+	g := &check.Group{ID: "1.1", Text: "API Server"}
+	for i, s := range states {
+		g.Checks = append(g.Checks, &check.Check{ID: fmt.Sprintf("1.1.%d", i+1), State: s})
+	}
+	return &check.Controls{ID: "1", Text: "Master", Type: check.MASTER, Groups: []*check.Group{g}}
+}
+
+func TestNotifyPolicyApply(t *testing.T) {
+	start := time.Date(2020, 1, 1, 0, 0, 0, 0, time.UTC)
+	now := start
+
+	cases := []struct {
+		name    string
+		policy  notifyPolicy
+		elapsed time.Duration
+		second  []check.State
+		exp     []string
+	}{
+		{
+			name:   "only new failures are notified on the second run",
+			policy: notifyPolicy{OnlyNew: true},
+			second: []check.State{check.FAIL, check.FAIL},
+			exp:    []string{"1.1.2"},
+		},
+		{
+			name:    "repeats are notified once the interval has elapsed",
+			policy:  notifyPolicy{OnlyNew: true, RepeatInterval: time.Hour},
+			elapsed: 2 * time.Hour,
+			second:  []check.State{check.FAIL, check.PASS},
+			exp:     []string{"1.1.1"},
+		},
+		{
+			name:    "repeats are suppressed within the interval",
+			policy:  notifyPolicy{OnlyNew: false, RepeatInterval: time.Hour},
+			elapsed: time.Minute,
+			second:  []check.State{check.FAIL, check.PASS},
+			exp:     nil,
+		},
+		{
+			name:   "without dedup every failure is notified",
+			policy: notifyPolicy{OnlyNew: false},
+			second: []check.State{check.FAIL, check.FAIL},
+			exp:    []string{"1.1.1", "1.1.2"},
+		},
+	}
+
+	for _, c := range cases {
+		t.Run(c.name, func(t *testing.T) {
+			now = start
+			c.policy.now = func() time.Time { return now }
+			state := &notifyState{}
+
+			first := c.policy.apply(notifyControls(check.FAIL, check.PASS), state)
+			assert.Len(t, first, 1)
+
+			now = start.Add(c.elapsed)
+			var ids []string
+			for _, f := range c.policy.apply(notifyControls(c.second...), state) {
+				ids = append(ids, f.ID)
+			}
+			assert.Equal(t, c.exp, ids)
+		})
+	}
+}
+
+func TestNotifyPolicyRecovery(t *testing.T) {
+	p := &notifyPolicy{OnlyNew: true, now: time.Now}
+	state := &notifyState{}
+
+	p.apply(notifyControls(check.FAIL), state)
+	p.apply(notifyControls(check.PASS), state)
+	assert.Len(t, p.apply(notifyControls(check.FAIL), state), 1, "a failure after recovery is new")
+}
+
+func TestNotifyPolicyNotRun(t *testing.T) {
+	p := &notifyPolicy{OnlyNew: true, now: time.Now}
+	state := &notifyState{}
+	p.apply(notifyControls(check.FAIL, check.FAIL, check.FAIL, check.FAIL), state)
+
+	// Checks which didn't run or couldn't be evaluated haven't recovered.
+	notRun := notifyControls("", check.INCOMPLETE, check.WARN, check.INFO)
+	notRun.Groups[0].Checks[1].ReasonCode = check.ReasonInterrupted
+	notRun.Groups[0].Checks[3].ReasonCode = check.ReasonFiltered
+	p.apply(notRun, state)
+	assert.Empty(t, p.apply(notifyControls(check.FAIL, check.FAIL, check.FAIL, check.FAIL), state))
+
+	p.apply(notifyControls(check.PASS, check.INFO, check.FAIL, check.FAIL), state)
+	assert.Len(t, p.apply(notifyControls(check.FAIL, check.FAIL, check.FAIL, check.FAIL), state), 2)
+}
+
+// failingNotifier is a sink which fails when down is set.
+type failingNotifier struct{ down bool }
+
+func (n *failingNotifier) Name() string { return "test" }
+
+func (n *failingNotifier) Notify(batch notifyBatch) error {
+	if n.down {
+		return fmt.Errorf("sink is down")
+	}
+	return nil
+}
+
+func TestDeliverBatches(t *testing.T) {
+	start := time.Date(2020, 1, 1, 0, 0, 0, 0, time.UTC)
+	now := start
+	p := &notifyPolicy{OnlyNew: true, RepeatInterval: time.Hour, BatchBy: batchByCheck, now: func() time.Time { return now }}
+	state := &notifyState{}
+	run := func(notifiers ...Notifier) []string {
+		previous := make(map[string]time.Time)
+		for k, v := range state.Failing {
+			previous[k] = v
+		}
+		findings := p.apply(notifyControls(check.FAIL, check.FAIL), state)
+		deliverBatches(notifiers, p.batch(findings), state, previous)
+		var ids []string
+		for _, f := range findings {
+			ids = append(ids, f.ID)
+		}
+		return ids
+	}
+
+	down := &failingNotifier{down: true}
+	assert.Equal(t, []string{"1.1.1", "1.1.2"}, run(down))
+	assert.Empty(t, state.Failing, "undelivered failures aren't notified")
+
+	assert.Equal(t, []string{"1.1.1", "1.1.2"}, run(down, &failingNotifier{}), "one sink accepting a batch is enough")
+	assert.Empty(t, run(down))
+
+	// A repeat which isn't delivered keeps the time of the last notification.
+	now = start.Add(2 * time.Hour)
+	assert.Equal(t, []string{"1.1.1", "1.1.2"}, run(down))
+	assert.Equal(t, start, state.Failing["master/1.1.1"])
+	assert.Equal(t, []string{"1.1.1", "1.1.2"}, run(&failingNotifier{}))
+	assert.Equal(t, now, state.Failing["master/1.1.1"])
+}
+
+func TestNotifyPolicyBatch(t *testing.T) {
+	findings := []notifyFinding{
+		{Section: "1 Master", Group: "1.1 API Server", ID: "1.1.1", Owner: "platform"},
+		{Section: "1 Master", Group: "1.2 Scheduler", ID: "1.2.1", Owner: "platform"},
+		{Section: "1 Master", Group: "1.1 API Server", ID: "1.1.2"},
+	}
+
+	cases := []struct {
+		by  string
+		exp int
+	}{
+		{by: "", exp: 1},
+		{by: batchBySection, exp: 1},
+		{by: batchByGroup, exp: 2},
+		{by: batchByCheck, exp: 3},
+		{by: batchByOwner, exp: 2},
+	}
+
+	for _, c := range cases {
+		p := &notifyPolicy{BatchBy: c.by}
+		assert.Len(t, p.batch(findings), c.exp, c.by)
+	}
+}
+
+func TestNotifyStatePersistence(t *testing.T) {
+	dir, err := ioutil.TempDir("", "kube-bench-notify")
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer os.RemoveAll(dir)
+
+	path := filepath.Join(dir, "state", "notify.json")
+	state, err := loadNotifyState(path)
+	assert.NoError(t, err)
+	assert.Empty(t, state.Failing)
+
+	state.Failing["master/1.1.1"] = time.Now()
+	assert.NoError(t, saveNotifyState(path, state))
+
+	loaded, err := loadNotifyState(path)
+	assert.NoError(t, err)
+	assert.Contains(t, loaded.Failing, "master/1.1.1")
+}
+
+func TestWebhookNotifier(t *testing.T) {
+	var got notifyBatch
+	ts := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		json.NewDecoder(r.Body).Decode(&got)
+	}))
+	defer ts.Close()
+
+	batch := notifyBatch{Key: "1 Master", Findings: []notifyFinding{{ID: "1.1.1"}}}
+	n := &webhookNotifier{url: ts.URL}
+	assert.NoError(t, n.Notify(batch))
+	assert.Equal(t, batch, got)
+
+	errTs := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		http.Error(w, "nope", http.StatusInternalServerError)
+	}))
+	defer errTs.Close()
+	assert.Error(t, (&slackNotifier{url: errTs.URL}).Notify(batch))
+}
@@ -0,0 +1,75 @@
+package cmd
+
+import (
+	"fmt"
+	"os"
+	"path/filepath"
+
+	"github.com/aquasecurity/kube-bench/check"
+	"github.com/golang/glog"
+)
+
+// configOverlays are directories laid out like the config directory, typically
+// mounted ConfigMaps, whose settings and checks take precedence over those of
+// the config directory, in order.
+var configOverlays []string
+
+// setupOverlays merges the config.yaml of the overlays over the main one.
+func setupOverlays() {
+	for _, dir := range configOverlays {
+		file := filepath.Join(dir, "config.yaml")
+		if _, err := os.Stat(file); err != nil {
+			continue
+		}
+		if err := mergeConfig(dir); err != nil {
+			exitWithError(err)
+		}
+		// The overlay can provide the whole config when the config directory lacks it.
+		configFileError = nil
+	}
+}
+
+// mergeOverlayConfig merges the version-specific config.yaml of the overlays
+// over the one of the config directory.
+func mergeOverlayConfig(benchmarkVersion string) {
+	for _, dir := range configOverlays {
+		if err := mergeConfig(filepath.Join(dir, benchmarkVersion)); err != nil {
+			exitWithError(err)
+		}
+	}
+}
+
+// overlayFiles returns the controls files of the overlays for testYamlFile,
+// found at the same path relative to each overlay as testYamlFile is to the
+// config directory.
+func overlayFiles(testYamlFile string) []string {
+	var files []string
+	benchmark := filepath.Base(filepath.Dir(testYamlFile))
+	for _, dir := range configOverlays {
+		files = append(files, filepath.Join(dir, benchmark, filepath.Base(testYamlFile)))
+	}
+	return files
+}
+
+// applyOverlays overlays the checks of the overlays onto controls. Their
+// controls files don't need to be complete, only to hold the groups and
+// checks to replace or add.
+func applyOverlays(controls *check.Controls, nodetype check.NodeType, testYamlFile string, read func(string) ([]byte, error), substitute func(string) string) error {
+	for _, file := range overlayFiles(testYamlFile) {
+		in, err := read(file)
+		if os.IsNotExist(err) {
+			continue
+		}
+		if err != nil {
+			return fmt.Errorf("error opening %s overlay file: %v", file, err)
+		}
+
+		glog.V(1).Info(fmt.Sprintf("Using overlay file: %s\n", file))
+		overlay, err := check.LoadControls(nodetype, []byte(substitute(string(in))), check.LoadOptions{File: file, AllowUnknownFields: allowUnknownFields, Strict: strictControls})
+		if err != nil {
+			return err
+		}
+		controls.Overlay(overlay)
+	}
+	return nil
+}
@@ -0,0 +1,70 @@
+package cmd
+
+import (
+	"io/ioutil"
+	"os"
+	"path/filepath"
+	"testing"
+
+	"github.com/aquasecurity/kube-bench/check"
+	"github.com/spf13/viper"
+	"github.com/stretchr/testify/assert"
+)
+
+func TestOverlays(t *testing.T) {
+	dir, err := ioutil.TempDir("", "kube-bench-overlay")
+	if err != nil {
+		t.Fatalf("unable to create temp directory: %v", err)
+	}
+	defer os.RemoveAll(dir)
+
+	write := func(file, data string) string {
+		file = filepath.Join(dir, file)
+		if err := os.MkdirAll(filepath.Dir(file), 0755); err != nil {
+			t.Fatalf("error creating %s: %v", filepath.Dir(file), err)
+		}
+		if err := ioutil.WriteFile(file, []byte(data), 0644); err != nil {
+			t.Fatalf("error writing %s: %v", file, err)
+		}
+		return file
+	}
+
+	testYamlFile := write("cfg/cis-1.5/policies.yaml", `---
+controls:
+type: "policies"
+groups:
+- id: 5.1
+  checks:
+  - id: 5.1.1
+    type: "manual"
+  - id: 5.1.2
+    type: "manual"
+`)
+	write("overlay/config.yaml", "policies:\n  components: []\nowners:\n  \"5.1\": rbac-team\n")
+	write("overlay/cis-1.5/policies.yaml", `---
+type: "policies"
+groups:
+- id: 5.1
+  checks:
+  - id: 5.1.2
+    type: "skip"
+`)
+
+	defer func(overlays []string) { configOverlays = overlays }(configOverlays)
+	defer viper.Reset()
+	defer func(err error) { configFileError = err }(configFileError)
+	configOverlays = []string{filepath.Join(dir, "overlay")}
+	configFileError = viper.ConfigFileNotFoundError{}
+
+	setupOverlays()
+	assert.NoError(t, configFileError, "the overlay provides the config")
+	assert.Equal(t, "rbac-team", viper.GetStringMapString("owners")["5.1"])
+	assert.Equal(t, []string{filepath.Join(dir, "overlay", "cis-1.5", "policies.yaml")}, overlayFiles(testYamlFile))
+
+	filterOpts = FilterOpts{Scored: true, Unscored: true}
+	controls, summary, err := runTarget(check.POLICIES, testYamlFile, ioutil.ReadFile)
+	assert.NoError(t, err)
+	assert.Equal(t, check.Summary{Warn: 1, Info: 1}, summary)
+	assert.Equal(t, check.INFO, controls.Groups[0].Checks[1].State, "the overlay skips 5.1.2")
+	assert.Equal(t, "rbac-team", controls.Groups[0].Checks[1].Owner)
+}
@@ -0,0 +1,247 @@
+package cmd
+
+import (
+	"bytes"
+	"context"
+	"fmt"
+	"os"
+	"os/exec"
+	"sort"
+	"strings"
+	"time"
+
+	"github.com/aquasecurity/kube-bench/check"
+	"github.com/golang/glog"
+	"github.com/spf13/cobra"
+	"github.com/spf13/pflag"
+	"github.com/spf13/viper"
+)
+
+const postHookTimeout = 5 * time.Minute
+
+var (
+	pipelineName string
+	pipeline     *scanPipeline
+
+	// pipelineFilters are the settings of the filters section of a pipeline,
+	// which select the checks to run.
+	pipelineFilters = map[string]bool{"check": true, "group": true, "scored": true, "unscored": true}
+)
+
+// scanPipeline is a scan declared in the pipelines section of config.yaml:
+// the settings of the flags it runs with, such as its targets, notifiers and
+// exporters, the files its results are written to and the commands run once
+// it is done.
+type scanPipeline struct {
+	name      string
+	settings  map[string]interface{}
+	outputs   []pipelineOutput
+	postHooks []string
+
+	// written are the output files written so far.
+	written map[string]bool
+}
+
+// pipelineOutput is a file the results of a pipeline are written to, in one
+// of the formats of --format. As the results of each target are a document of
+// their own, the name of the file must hold {target}, which is replaced by the
+// target of the results.
+type pipelineOutput struct {
+	Format string `mapstructure:"format"`
+	File   string `mapstructure:"file"`
+}
+
+// setupPipeline sets the flags missing from the command line and the
+// environment from the settings of the pipeline selected with --pipeline.
+// They take precedence over the keys of config.yaml.
+func setupPipeline(cmd *cobra.Command) error {
+	p, err := loadPipeline(viper.GetViper(), pipelineName)
+	if err != nil {
+		return err
+	}
+	if err := p.applySettings(cmd); err != nil {
+		return err
+	}
+	glog.V(1).Info(fmt.Sprintf("Running pipeline %s", p.name))
+	pipeline = p
+	return nil
+}
+
+// loadPipeline reads the pipeline called name from the pipelines section of
+// the config.
+func loadPipeline(v *viper.Viper, name string) (*scanPipeline, error) {
+	key := "pipelines." + name
+	if !v.IsSet(key) {
+		names := sortedKeys(v.GetStringMapString("pipelines"))
+		return nil, fmt.Errorf("pipeline %q not found in the pipelines section of the config, valid pipelines are %v", name, names)
+	}
+
+	p := &scanPipeline{name: name, settings: make(map[string]interface{}), written: make(map[string]bool)}
+	for k, value := range v.GetStringMap(key) {
+		switch k {
+		case "filters":
+			filters, ok := value.(map[string]interface{})
+			if !ok {
+				return nil, fmt.Errorf("filters of pipeline %s must be a map of check, group, scored or unscored settings", name)
+			}
+			for f, fv := range filters {
+				if !pipelineFilters[f] {
+					return nil, fmt.Errorf("unknown filter %q in pipeline %s, valid filters are check, group, scored and unscored", f, name)
+				}
+				p.settings[f] = fv
+			}
+		case "outputs":
+			if err := v.UnmarshalKey(key+".outputs", &p.outputs); err != nil {
+				return nil, fmt.Errorf("invalid outputs of pipeline %s: %v", name, err)
+			}
+		case "post_hooks":
+			p.postHooks = v.GetStringSlice(key + ".post_hooks")
+		default:
+			if pipelineFilters[k] {
+				return nil, fmt.Errorf("%s of pipeline %s must be set in its filters", k, name)
+			}
+			p.settings[k] = value
+		}
+	}
+
+	files := make(map[string]bool)
+	for i, o := range p.outputs {
+		if o.File == "" {
+			return nil, fmt.Errorf("output %d of pipeline %s has no file", i+1, name)
+		}
+		if !strings.Contains(o.File, "{target}") {
+			return nil, fmt.Errorf("output %s of pipeline %s must have {target} in its name, the results of each target are written to a file of their own", o.File, name)
+		}
+		if files[o.File] {
+			return nil, fmt.Errorf("output %s of pipeline %s is written more than once", o.File, name)
+		}
+		files[o.File] = true
+		if _, err := check.GetRenderer(o.Format); err != nil {
+			return nil, fmt.Errorf("output %s of pipeline %s: %v", o.File, name, err)
+		}
+	}
+	return p, nil
+}
+
+// applySettings sets the flags of cmd and its subcommands missing from the
+// command line and the environment from the settings of the pipeline, failing
+// on the settings which aren't flags.
+func (p *scanPipeline) applySettings(cmd *cobra.Command) error {
+	flags := make(map[string]bool)
+	var walk func(c *cobra.Command)
+	walk = func(c *cobra.Command) {
+		for _, fs := range []*pflag.FlagSet{c.PersistentFlags(), c.Flags()} {
+			fs.VisitAll(func(f *pflag.Flag) {
+				if !bootstrapFlags[f.Name] {
+					flags[settingKey(f.Name)] = true
+				}
+			})
+		}
+		for _, sub := range c.Commands() {
+			walk(sub)
+		}
+	}
+	walk(cmd)
+
+	var unknown []string
+	for k := range p.settings {
+		if !flags[k] {
+			unknown = append(unknown, k)
+		}
+	}
+	if len(unknown) > 0 {
+		sort.Strings(unknown)
+		return fmt.Errorf("unknown settings %v in pipeline %s", unknown, p.name)
+	}
+
+	return applySettings(cmd, func(name string) (string, string, bool) {
+		value, ok := p.settings[settingKey(name)]
+		if !ok {
+			return "", "", false
+		}
+		return settingValue(value), fmt.Sprintf("%s in pipeline %s", settingKey(name), p.name), true
+	})
+}
+
+// writeOutputs writes the results of a target to the output files of the
+// pipeline, if any.
+func (p *scanPipeline) writeOutputs(controls *check.Controls) {
+	if p == nil {
+		return
+	}
+	for _, o := range p.outputs {
+		renderer, err := check.GetRenderer(o.Format)
+		if err != nil {
+			exitWithError(configError{err})
+		}
+		out, err := renderer.Render(controls)
+		if err != nil {
+			exitWithError(fmt.Errorf("failed to output in %s format: %v", o.Format, err))
+		}
+
+		file := strings.Replace(o.File, "{target}", string(controls.Type), -1)
+		if err := writeOutputToFile(string(out), file); err != nil {
+			exitWithError(fmt.Errorf("Failed to write to output file %s: %v", file, err))
+		}
+		p.written[file] = true
+	}
+}
+
+// runPostHooks runs the post-hooks of the pipeline in turn with the shell,
+// once its results are output. Failing hooks are reported but don't change
+// the outcome of the run.
+func (p *scanPipeline) runPostHooks() {
+	if p == nil {
+		return
+	}
+	env := append(os.Environ(), p.hookEnv()...)
+	for _, hook := range p.postHooks {
+		glog.V(1).Info(fmt.Sprintf("Running post-hook of pipeline %s: %s", p.name, hook))
+		if err := runPostHook(hook, env); err != nil {
+			glog.Warningf("Post-hook %q of pipeline %s failed: %v", hook, p.name, err)
+		}
+	}
+}
+
+func runPostHook(hook string, env []string) error {
+	ctx, cancel := context.WithTimeout(context.Background(), postHookTimeout)
+	defer cancel()
+
+	cmd := exec.CommandContext(ctx, "/bin/sh", "-c", hook)
+	cmd.Env = env
+	cmd.Stdout = os.Stdout
+
+	var stderr bytes.Buffer
+	cmd.Stderr = &stderr
+	if err := cmd.Run(); err != nil {
+		return fmt.Errorf("%v: %s", err, strings.TrimSpace(stderr.String()))
+	}
+	return nil
+}
+
+// hookEnv returns the environment variables describing the run to the
+// post-hooks: the pipeline, the scan ID, the output files written and the
+// number of checks in each state.
+func (p *scanPipeline) hookEnv() []string {
+	files := make([]string, 0, len(p.written))
+	for f := range p.written {
+		files = append(files, f)
+	}
+	sort.Strings(files)
+
+	runSummaryMu.Lock()
+	summary := runSummary
+	runSummaryMu.Unlock()
+
+	return []string{
+		fmt.Sprintf("%s_PIPELINE_NAME=%s", envVarsPrefix, p.name),
+		fmt.Sprintf("%s_SCAN_ID=%s", envVarsPrefix, scanID),
+		fmt.Sprintf("%s_OUTPUT_FILES=%s", envVarsPrefix, strings.Join(files, " ")),
+		fmt.Sprintf("%s_TOTAL_PASS=%d", envVarsPrefix, summary.Pass),
+		fmt.Sprintf("%s_TOTAL_FAIL=%d", envVarsPrefix, summary.Fail),
+		fmt.Sprintf("%s_TOTAL_WARN=%d", envVarsPrefix, summary.Warn),
+		fmt.Sprintf("%s_TOTAL_INFO=%d", envVarsPrefix, summary.Info),
+		fmt.Sprintf("%s_TOTAL_INCOMPLETE=%d", envVarsPrefix, summary.Incomplete),
+		fmt.Sprintf("%s_TOTAL_ERROR=%d", envVarsPrefix, summary.Error),
+	}
+}
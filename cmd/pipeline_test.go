@@ -0,0 +1,198 @@
+package cmd
+
+import (
+	"io/ioutil"
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+
+	"github.com/aquasecurity/kube-bench/check"
+	"github.com/spf13/cobra"
+	"github.com/spf13/viper"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+const pipelinesConfig = `
+pipelines:
+  nightly:
+    targets:
+    - master
+    - node
+    benchmark: cis-1.5
+    notify: true
+    scan_id: from-pipeline
+    filters:
+      scored: false
+      group: "1.1,4.2"
+    outputs:
+    - format: json
+      file: /tmp/kube-bench-{target}.json
+    - format: junit
+      file: /tmp/kube-bench-{target}.xml
+    post_hooks:
+    - echo done
+  unknown:
+    targets: [node]
+    not_a_flag: true
+`
+
+func readPipelinesConfig(t *testing.T, config string) *viper.Viper {
+	v := viper.New()
+	v.SetConfigType("yaml")
+	require.NoError(t, v.ReadConfig(strings.NewReader(config)))
+	return v
+}
+
+func TestLoadPipeline(t *testing.T) {
+	v := readPipelinesConfig(t, pipelinesConfig)
+
+	p, err := loadPipeline(v, "nightly")
+	require.NoError(t, err)
+	assert.Equal(t, "nightly", p.name)
+	assert.Equal(t, []interface{}{"master", "node"}, p.settings["targets"])
+	assert.Equal(t, false, p.settings["scored"])
+	assert.Equal(t, "1.1,4.2", p.settings["group"])
+	assert.Equal(t, []pipelineOutput{
+		{Format: "json", File: "/tmp/kube-bench-{target}.json"},
+		{Format: "junit", File: "/tmp/kube-bench-{target}.xml"},
+	}, p.outputs)
+	assert.Equal(t, []string{"echo done"}, p.postHooks)
+
+	_, err = loadPipeline(v, "weekly")
+	assert.EqualError(t, err, `pipeline "weekly" not found in the pipelines section of the config, valid pipelines are [nightly unknown]`)
+
+	for name, config := range map[string]string{
+		"unknown filter":   "pipelines:\n  p:\n    filters:\n      owner: me\n",
+		"filter as a flag": "pipelines:\n  p:\n    check: 1.1.1\n",
+		"unknown format":   "pipelines:\n  p:\n    outputs:\n    - format: pdf\n      file: out.pdf\n",
+		"no file":          "pipelines:\n  p:\n    outputs:\n    - format: json\n",
+		"shared file":      "pipelines:\n  p:\n    outputs:\n    - format: junit\n      file: results.xml\n",
+		"same file twice":  "pipelines:\n  p:\n    outputs:\n    - format: json\n      file: '{target}'\n    - format: junit\n      file: '{target}'\n",
+	} {
+		t.Run(name, func(t *testing.T) {
+			_, err := loadPipeline(readPipelinesConfig(t, config), "p")
+			assert.Error(t, err)
+		})
+	}
+}
+
+func TestPipelineApplySettings(t *testing.T) {
+	var (
+		notify, scored  bool
+		scanID, group   string
+		config, targets []string
+	)
+	root := &cobra.Command{Use: "root"}
+	root.PersistentFlags().BoolVar(&notify, "notify", false, "")
+	root.PersistentFlags().BoolVar(&scored, "scored", true, "")
+	root.PersistentFlags().StringVar(&scanID, "scan-id", "", "")
+	root.PersistentFlags().StringVar(&group, "group", "", "")
+	root.PersistentFlags().StringSliceVar(&config, "config", nil, "")
+	sub := &cobra.Command{Use: "run"}
+	sub.Flags().StringSliceVarP(&targets, "targets", "s", []string{}, "")
+	sub.Flags().String("benchmark", "", "")
+	root.AddCommand(sub)
+
+	v := readPipelinesConfig(t, pipelinesConfig)
+	p, err := loadPipeline(v, "nightly")
+	require.NoError(t, err)
+
+	// The command line takes precedence over the pipeline.
+	assert.NoError(t, root.PersistentFlags().Set("scan-id", "from-command-line"))
+	assert.NoError(t, p.applySettings(root))
+	assert.Equal(t, "from-command-line", scanID)
+	assert.Equal(t, []string{"master", "node"}, targets)
+	assert.Equal(t, "1.1,4.2", group)
+	assert.True(t, notify)
+	assert.False(t, scored)
+
+	p, err = loadPipeline(v, "unknown")
+	require.NoError(t, err)
+	assert.EqualError(t, p.applySettings(root), "unknown settings [not_a_flag] in pipeline unknown")
+
+	// Bootstrap flags can't be set by a pipeline.
+	p, err = loadPipeline(readPipelinesConfig(t, "pipelines:\n  p:\n    config: other.yaml\n"), "p")
+	require.NoError(t, err)
+	assert.Error(t, p.applySettings(root))
+}
+
+func TestPipelineWriteOutputs(t *testing.T) {
+	dir, err := ioutil.TempDir("", "kube-bench-pipeline")
+	require.NoError(t, err)
+	defer os.RemoveAll(dir)
+
+	p := &scanPipeline{
+		name: "nightly",
+		outputs: []pipelineOutput{
+			{Format: "json", File: filepath.Join(dir, "{target}.json")},
+			{Format: "junit", File: filepath.Join(dir, "results-{target}.xml")},
+		},
+		written: make(map[string]bool),
+	}
+	master := &check.Controls{ID: "1", Type: check.MASTER}
+	node := &check.Controls{ID: "4", Type: check.NODE}
+	p.writeOutputs(master)
+	p.writeOutputs(node)
+
+	for _, target := range []string{"master", "node"} {
+		out, err := ioutil.ReadFile(filepath.Join(dir, target+".json"))
+		require.NoError(t, err)
+		assert.Contains(t, string(out), `"node_type":"`+target+`"`)
+	}
+
+	// The results of each target are a document of their own.
+	renderer, err := check.GetRenderer("junit")
+	require.NoError(t, err)
+	nodeOut, err := renderer.Render(node)
+	require.NoError(t, err)
+	xml := filepath.Join(dir, "results-node.xml")
+	out, err := ioutil.ReadFile(xml)
+	require.NoError(t, err)
+	assert.Equal(t, string(nodeOut)+"\n", string(out))
+	assert.Equal(t, map[string]bool{
+		filepath.Join(dir, "master.json"):        true,
+		filepath.Join(dir, "node.json"):          true,
+		filepath.Join(dir, "results-master.xml"): true,
+		xml:                                      true,
+	}, p.written)
+
+	// Files are truncated by the next run.
+	assert.NoError(t, ioutil.WriteFile(xml, []byte("earlier results, longer than the new ones\n"+string(nodeOut)), 0644))
+	rerun := &scanPipeline{outputs: p.outputs[1:], written: make(map[string]bool)}
+	rerun.writeOutputs(node)
+	out, err = ioutil.ReadFile(xml)
+	require.NoError(t, err)
+	assert.Equal(t, string(nodeOut)+"\n", string(out))
+
+	// writeOutputs does nothing without a pipeline.
+	var none *scanPipeline
+	none.writeOutputs(master)
+}
+
+func TestPipelineRunPostHooks(t *testing.T) {
+	dir, err := ioutil.TempDir("", "kube-bench-pipeline")
+	require.NoError(t, err)
+	defer os.RemoveAll(dir)
+
+	defer func(id string, summary check.Summary) { scanID, runSummary = id, summary }(scanID, runSummary)
+	scanID = "abc"
+	runSummary = check.Summary{Pass: 3, Fail: 2, Warn: 1, Incomplete: 4, Error: 5}
+
+	env := filepath.Join(dir, "env")
+	p := &scanPipeline{
+		name: "nightly",
+		postHooks: []string{
+			"exit 1",
+			`echo "$KUBE_BENCH_PIPELINE_NAME $KUBE_BENCH_SCAN_ID $KUBE_BENCH_OUTPUT_FILES $KUBE_BENCH_TOTAL_PASS $KUBE_BENCH_TOTAL_FAIL $KUBE_BENCH_TOTAL_WARN $KUBE_BENCH_TOTAL_INCOMPLETE $KUBE_BENCH_TOTAL_ERROR" > ` + env,
+		},
+		written: map[string]bool{"b.json": true, "a.json": true},
+	}
+
+	// A failing hook doesn't stop the next ones.
+	p.runPostHooks()
+	out, err := ioutil.ReadFile(env)
+	require.NoError(t, err)
+	assert.Equal(t, "nightly abc a.json b.json 3 2 1 4 5\n", string(out))
+}
@@ -0,0 +1,103 @@
+package cmd
+
+import (
+	"fmt"
+	"io/ioutil"
+	"os"
+	"path/filepath"
+	"regexp"
+	"strconv"
+	"strings"
+
+	"github.com/golang/glog"
+	"gopkg.in/yaml.v2"
+)
+
+var (
+	proxyModeFlagRe = regexp.MustCompile(`--proxy-mode[= ]([a-z]+)`)
+	configFlagRe    = regexp.MustCompile(`--config[= ](\S+)`)
+)
+
+// detectProxyMode returns the mode kube-proxy runs in, such as iptables or
+// ipvs, from its --proxy-mode flag or the mode of its config file. Without
+// either, kube-proxy runs in iptables mode. It returns "" when kube-proxy
+// isn't running.
+func detectProxyMode(bin string) string {
+	cmdline := strings.TrimSpace(psFunc(bin))
+	if cmdline == "" {
+		return ""
+	}
+
+	if m := proxyModeFlagRe.FindStringSubmatch(cmdline); m != nil {
+		return m[1]
+	}
+	for _, line := range strings.Split(cmdline, "\n") {
+		m := configFlagRe.FindStringSubmatch(line)
+		if m == nil {
+			continue
+		}
+		mode, err := proxyConfigMode(m[1], strings.TrimSpace(line))
+		if err != nil {
+			glog.V(1).Info(fmt.Sprintf("Failed to read the mode of kube-proxy from its config file: %v", err))
+		} else if mode != "" {
+			return mode
+		}
+		break
+	}
+	return "iptables"
+}
+
+// proxyConfigMode reads the mode of the KubeProxyConfiguration file of the
+// kube-proxy running with cmdline.
+func proxyConfigMode(file, cmdline string) (string, error) {
+	in, err := readComponentConfig(file, cmdline)
+	if err != nil {
+		return "", err
+	}
+
+	var config struct {
+		Mode string `yaml:"mode"`
+	}
+	if err := yaml.Unmarshal(in, &config); err != nil {
+		return "", fmt.Errorf("failed to parse %s: %v", file, err)
+	}
+	return config.Mode, nil
+}
+
+// readComponentConfig reads the config file of the component running with
+// cmdline. Components such as kube-proxy usually run in a pod, so the file is
+// looked up in the root filesystem of the process of the component when it
+// isn't on the host. Only that process is trusted with the file: any pod of
+// the node could hold a file with the same path.
+func readComponentConfig(file, cmdline string) ([]byte, error) {
+	if mock != nil {
+		return mock.readFile(file)
+	}
+	in, err := ioutil.ReadFile(file)
+	if !os.IsNotExist(err) {
+		return in, err
+	}
+	pid, err := componentPid(cmdline)
+	if err != nil {
+		return nil, fmt.Errorf("failed to find the root filesystem of %s: %v", file, err)
+	}
+	return ioutil.ReadFile(filepath.Join(procDir, strconv.Itoa(pid), "root", file))
+}
+
+// componentPid returns the PID of the process running with cmdline, failing
+// when there are several of them.
+func componentPid(cmdline string) (int, error) {
+	var pids []int
+	for _, p := range readProcessTable(procDir) {
+		if p.cmdline == cmdline {
+			pids = append(pids, p.pid)
+		}
+	}
+	switch len(pids) {
+	case 0:
+		return 0, fmt.Errorf("no process is running %q", cmdline)
+	case 1:
+		return pids[0], nil
+	}
+	return 0, fmt.Errorf("processes %v are all running %q", pids, cmdline)
+}
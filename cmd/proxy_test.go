@@ -0,0 +1,101 @@
+package cmd
+
+import (
+	"io/ioutil"
+	"os"
+	"path/filepath"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+)
+
+func TestDetectProxyMode(t *testing.T) {
+	dir, err := ioutil.TempDir("", "kube-bench-proxy-")
+	assert.NoError(t, err)
+	defer os.RemoveAll(dir)
+
+	conf := filepath.Join(dir, "config.conf")
+	assert.NoError(t, ioutil.WriteFile(conf, []byte("apiVersion: kubeproxy.config.k8s.io/v1alpha1\nkind: KubeProxyConfiguration\nmode: ipvs\n"), 0644))
+	// In a pod, the config file is in the root filesystem of the process.
+	// Files of other processes are never read, they could be from any pod.
+	proc := filepath.Join(dir, "proc")
+	for pid, p := range map[string]struct{ cmdline, file, config string }{
+		"1234": {"/usr/local/bin/kube-proxy\x00--config=/var/lib/kube-proxy/config.conf", "config.conf", "mode: \"\"\n"},
+		"1235": {"/usr/local/bin/kube-proxy\x00--config=/var/lib/kube-proxy/ipvs.conf", "ipvs.conf", "mode: ipvs\n"},
+		"999":  {"sleep\x003600", "planted.conf", "mode: ipvs\n"},
+	} {
+		assert.NoError(t, os.MkdirAll(filepath.Join(proc, pid, "root", "var", "lib", "kube-proxy"), 0755))
+		assert.NoError(t, ioutil.WriteFile(filepath.Join(proc, pid, "cmdline"), []byte(p.cmdline), 0644))
+		assert.NoError(t, ioutil.WriteFile(filepath.Join(proc, pid, "root", "var", "lib", "kube-proxy", p.file), []byte(p.config), 0644))
+	}
+
+	defer func(ps func(string) string, dir string) { psFunc, procDir = ps, dir }(psFunc, procDir)
+	procDir = proc
+
+	cases := []struct {
+		cmdline  string
+		expected string
+	}{
+		{cmdline: "", expected: ""},
+		{cmdline: "/usr/local/bin/kube-proxy --proxy-mode=ipvs", expected: "ipvs"},
+		{cmdline: "/usr/local/bin/kube-proxy --proxy-mode iptables --config=" + conf, expected: "iptables"},
+		{cmdline: "/usr/local/bin/kube-proxy --config=" + conf, expected: "ipvs"},
+		{cmdline: "/usr/local/bin/kube-proxy --config=/var/lib/kube-proxy/config.conf", expected: "iptables"},
+		{cmdline: "/usr/local/bin/kube-proxy --config=/var/lib/kube-proxy/ipvs.conf", expected: "ipvs"},
+		{cmdline: "/usr/local/bin/kube-proxy --config=/var/lib/kube-proxy/planted.conf", expected: "iptables"},
+		{cmdline: "/usr/local/bin/kube-proxy --config=/nonexistent/config.conf", expected: "iptables"},
+		{cmdline: "/usr/local/bin/kube-proxy --kubeconfig=/var/lib/kube-proxy/kubeconfig", expected: "iptables"},
+	}
+	for _, c := range cases {
+		psFunc = func(string) string { return c.cmdline }
+		assert.Equal(t, c.expected, detectProxyMode("kube-proxy"), c.cmdline)
+	}
+}
+
+func TestScanFacts(t *testing.T) {
+	defer func(ps func(string) string) { psFunc = ps }(psFunc)
+	psFunc = func(string) string { return "kube-proxy --proxy-mode=ipvs" }
+	defer func() { facts = nil }()
+
+	assert.Equal(t, map[string]string{}, scanFacts(map[string]string{"kubelet": "kubelet"}))
+	assert.Equal(t, map[string]string{"proxymode": "ipvs"}, scanFacts(map[string]string{"proxy": "kube-proxy"}))
+
+	assert.Equal(t, map[string]string{"cloudcontrollermanager": "not running"}, scanFacts(map[string]string{"cloudcontrollermanager": "cloudcontrollermanager"}))
+	psFunc = func(string) string { return "/usr/local/bin/aws-cloud-controller-manager --cloud-provider=aws" }
+	assert.Equal(t, map[string]string{"cloudcontrollermanager": "running"}, scanFacts(map[string]string{"cloudcontrollermanager": "aws-cloud-controller-manager"}))
+
+	psFunc = func(string) string { return "kubelet --feature-gates=PodSecurity=true" }
+	assert.Equal(t, map[string]string{"featuregatePodSecurity": "true"}, scanFacts(map[string]string{"kubelet": "kubelet"}))
+	psFunc = func(string) string { return "kube-proxy --proxy-mode=ipvs" }
+
+	// The facts of --facts take precedence.
+	facts = map[string]string{"proxymode": "iptables", "registry": "registry.example.com"}
+	assert.Equal(t, map[string]string{"proxymode": "iptables", "registry": "registry.example.com"}, scanFacts(map[string]string{"proxy": "kube-proxy"}))
+}
+
+func TestReadComponentConfig(t *testing.T) {
+	proc, err := ioutil.TempDir("", "kube-bench-proc-")
+	assert.NoError(t, err)
+	defer os.RemoveAll(proc)
+	defer func(dir string) { procDir = dir }(procDir)
+	procDir = proc
+
+	for _, pid := range []string{"10", "20"} {
+		assert.NoError(t, os.MkdirAll(filepath.Join(proc, pid, "root", "etc"), 0755))
+		assert.NoError(t, ioutil.WriteFile(filepath.Join(proc, pid, "cmdline"), []byte("kubelet\x00--config=/etc/kubelet.yaml"), 0644))
+		assert.NoError(t, ioutil.WriteFile(filepath.Join(proc, pid, "root", "etc", "kubelet.yaml"), []byte(pid), 0644))
+	}
+
+	// With several processes running the same command line, the process of the
+	// component can't be told apart.
+	_, err = readComponentConfig("/etc/kubelet.yaml", "kubelet --config=/etc/kubelet.yaml")
+	assert.EqualError(t, err, `failed to find the root filesystem of /etc/kubelet.yaml: processes [10 20] are all running "kubelet --config=/etc/kubelet.yaml"`)
+
+	assert.NoError(t, os.RemoveAll(filepath.Join(proc, "20")))
+	in, err := readComponentConfig("/etc/kubelet.yaml", "kubelet --config=/etc/kubelet.yaml")
+	assert.NoError(t, err)
+	assert.Equal(t, "10", string(in))
+
+	_, err = readComponentConfig("/etc/kubelet.yaml", "kubelet")
+	assert.Error(t, err)
+}
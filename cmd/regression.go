@@ -0,0 +1,60 @@
+package cmd
+
+import (
+	"fmt"
+	"os"
+
+	"github.com/aquasecurity/kube-bench/check"
+)
+
+// regressionExitCode is the exit code of a run with regressions.
+const regressionExitCode = 3
+
+// baselinePath is a JSON results file, or a directory of earlier results, that
+// the run is compared to.
+var baselinePath string
+
+// markRegressions flags the scored checks which fail in the results although
+// they passed in every run of the baseline, and returns them. Checks which
+// already failed in the baseline are long-standing failures rather than
+// regressions, and checks missing from it are new ones.
+func markRegressions(results []targetResult, baseline map[string]*aggregateCheck) []*check.Check {
+	var regressions []*check.Check
+	for _, r := range results {
+		for _, g := range r.controls.Groups {
+			for _, c := range g.Checks {
+				b, ok := baseline[fmt.Sprintf("%s/%s", r.controls.Type, c.ID)]
+				if !ok || !c.Scored || c.State != check.FAIL || b.State != check.PASS {
+					continue
+				}
+				c.Regression = true
+				regressions = append(regressions, c)
+			}
+		}
+	}
+	return regressions
+}
+
+// loadBaseline reads and aggregates the results of baselinePath.
+func loadBaseline() map[string]*aggregateCheck {
+	reports, err := loadReports(baselinePath)
+	if err != nil {
+		exitWithError(fmt.Errorf("failed to load the baseline: %v", err))
+	}
+	return aggregateReports(reports)
+}
+
+// printRegressions lists the regressions after the results.
+func printRegressions(regressions []*check.Check) {
+	colorPrint(check.FAIL, "== Regressions since the baseline ==\n")
+	for _, c := range regressions {
+		fmt.Printf("%s %s\n", c.ID, c.Text)
+	}
+}
+
+// exitIfRegressed exits with regressionExitCode when the run has regressions.
+func exitIfRegressed(regressions []*check.Check) {
+	if len(regressions) > 0 {
+		os.Exit(regressionExitCode)
+	}
+}
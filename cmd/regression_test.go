@@ -0,0 +1,60 @@
+package cmd
+
+import (
+	"testing"
+
+	"github.com/aquasecurity/kube-bench/check"
+	"github.com/stretchr/testify/assert"
+)
+
+func TestMarkRegressions(t *testing.T) {
+	baseline := aggregateReports([]*check.Controls{
+		{Type: check.NODE, Groups: []*check.Group{{Checks: []*check.Check{
+			{ID: "4.1.1", State: check.PASS, Scored: true},
+			{ID: "4.1.2", State: check.FAIL, Scored: true},
+			{ID: "4.1.3", State: check.PASS, Scored: false},
+			{ID: "4.1.4", State: check.PASS, Scored: true},
+		}}}},
+		// A later run of the baseline, in which 4.1.4 failed already.
+		{Type: check.NODE, Groups: []*check.Group{{Checks: []*check.Check{
+			{ID: "4.1.4", State: check.FAIL, Scored: true},
+		}}}},
+	})
+
+	current := &check.Controls{Type: check.NODE, Groups: []*check.Group{{Checks: []*check.Check{
+		{ID: "4.1.1", State: check.FAIL, Scored: true},
+		{ID: "4.1.2", State: check.FAIL, Scored: true},
+		{ID: "4.1.3", State: check.FAIL, Scored: false},
+		{ID: "4.1.4", State: check.FAIL, Scored: true},
+		{ID: "4.1.5", State: check.FAIL, Scored: true},
+	}}}}
+	master := &check.Controls{Type: check.MASTER, Groups: []*check.Group{{Checks: []*check.Check{
+		{ID: "4.1.1", State: check.FAIL, Scored: true},
+	}}}}
+
+	regressions := markRegressions([]targetResult{{controls: current}, {controls: master}}, baseline)
+	if assert.Len(t, regressions, 1) {
+		assert.Equal(t, "4.1.1", regressions[0].ID)
+	}
+	assert.True(t, current.Groups[0].Checks[0].Regression)
+	assert.False(t, current.Groups[0].Checks[1].Regression, "long-standing failure")
+
+	// A check which couldn't be evaluated on a node of the baseline didn't pass
+	// in it.
+	baseline = aggregateReports([]*check.Controls{
+		{Type: check.NODE, Groups: []*check.Group{{Checks: []*check.Check{
+			{ID: "4.1.1", State: check.PASS, Scored: true},
+			{ID: "4.1.2", State: check.PASS, Scored: true},
+		}}}},
+		{Type: check.NODE, Groups: []*check.Group{{Checks: []*check.Check{
+			{ID: "4.1.1", State: check.INCOMPLETE, Scored: true},
+			{ID: "4.1.2", State: check.ERROR, Scored: true},
+		}}}},
+	})
+	current = &check.Controls{Type: check.NODE, Groups: []*check.Group{{Checks: []*check.Check{
+		{ID: "4.1.1", State: check.FAIL, Scored: true},
+		{ID: "4.1.2", State: check.FAIL, Scored: true},
+	}}}}
+	assert.Empty(t, markRegressions([]targetResult{{controls: current}}, baseline))
+	assert.False(t, master.Groups[0].Checks[0].Regression, "checks are matched by node type")
+}
@@ -0,0 +1,202 @@
+package cmd
+
+import (
+	"crypto/sha256"
+	"encoding/hex"
+	"fmt"
+	"io"
+	"io/ioutil"
+	"os"
+	"strings"
+	"sync"
+	"time"
+
+	"github.com/aquasecurity/kube-bench/check"
+	"github.com/golang/glog"
+)
+
+var reloadInterval time.Duration
+
+// definitionSet is a snapshot of the controls files of a benchmark and of
+// their overlays. It's loaded and validated as a whole, so that a run never
+// mixes old and new check definitions, nor stops on a broken update.
+type definitionSet struct {
+	// files are the controls files to run, without their overlays.
+	files    []string
+	contents map[string][]byte
+	digest   string
+}
+
+// loadDefinitions reads and validates the controls files listed by list.
+// Mounted ConfigMaps are updated by swapping a symlink to a new directory,
+// so the files are read twice, and the snapshot is only taken when both
+// readings agree, rather than while the update is in progress.
+func loadDefinitions(list func() ([]string, error)) (*definitionSet, error) {
+	read := func() (*definitionSet, error) {
+		files, err := list()
+		if err != nil {
+			return nil, err
+		}
+
+		d := &definitionSet{files: files, contents: make(map[string][]byte)}
+		h := sha256.New()
+		for _, file := range files {
+			in, err := ioutil.ReadFile(file)
+			if err != nil {
+				return nil, fmt.Errorf("error opening %s test file: %v", file, err)
+			}
+			d.add(h, file, in)
+
+			for _, overlay := range overlayFiles(file) {
+				in, err := ioutil.ReadFile(overlay)
+				if os.IsNotExist(err) {
+					continue
+				}
+				if err != nil {
+					return nil, fmt.Errorf("error opening %s overlay file: %v", overlay, err)
+				}
+				d.add(h, overlay, in)
+			}
+		}
+		d.digest = hex.EncodeToString(h.Sum(nil))
+		return d, nil
+	}
+
+	d, err := read()
+	if err != nil {
+		return nil, err
+	}
+	if again, err := read(); err != nil || again.digest != d.digest {
+		return nil, fmt.Errorf("controls files changed while loading them")
+	}
+
+	var errs []string
+	for _, file := range d.files {
+		for _, f := range append([]string{file}, overlayFiles(file)...) {
+			in, ok := d.contents[f]
+			if !ok {
+				continue
+			}
+			// An update with invalid checks is rejected rather than run with
+			// them in ERROR, as the previous definitions keep running.
+			opts := check.LoadOptions{File: f, AllowUnknownFields: allowUnknownFields, Strict: true}
+			if _, err := check.LoadControls(targetType(file), in, opts); err != nil {
+				errs = append(errs, err.Error())
+			}
+		}
+	}
+	if len(errs) > 0 {
+		return nil, fmt.Errorf("invalid controls files:\n%s", strings.Join(errs, "\n"))
+	}
+	return d, nil
+}
+
+func (d *definitionSet) add(h io.Writer, file string, in []byte) {
+	d.contents[file] = in
+	fmt.Fprintf(h, "%s\x00%d\x00", file, len(in))
+	h.Write(in)
+}
+
+func (d *definitionSet) read(file string) ([]byte, error) {
+	in, ok := d.contents[file]
+	if !ok {
+		return nil, &os.PathError{Op: "open", Path: file, Err: os.ErrNotExist}
+	}
+	return in, nil
+}
+
+// runTargets runs the checks of the snapshot.
+func (d *definitionSet) runTargets(parallel bool) []targetResult {
+	return runTargetsFrom(d.files, parallel, d.read)
+}
+
+// definitionWatcher holds the check definitions of the long-running modes,
+// and reloads them when the controls files change, so that benchmark updates
+// roll out without restarting kube-bench.
+type definitionWatcher struct {
+	list func() ([]string, error)
+
+	mu      sync.RWMutex
+	current *definitionSet
+
+	// changed receives a value when new definitions are swapped in.
+	changed chan struct{}
+}
+
+// newDefinitionWatcher loads the controls files listed by list, which is
+// called again on every reload, so that files added to or removed from the
+// benchmark directory are picked up as well.
+func newDefinitionWatcher(list func() ([]string, error)) (*definitionWatcher, error) {
+	d, err := loadDefinitions(list)
+	if err != nil {
+		return nil, err
+	}
+	return &definitionWatcher{list: list, current: d, changed: make(chan struct{}, 1)}, nil
+}
+
+// watchDefinitions loads the controls files of the targets, and reloads them
+// every reloadInterval in the background.
+func watchDefinitions(targets []string) *definitionWatcher {
+	benchmarkVersion := resolveBenchmark(targets)
+	w, err := newDefinitionWatcher(func() ([]string, error) {
+		return getTestYamlFiles(targets, benchmarkVersion)
+	})
+	if err != nil {
+		exitWithError(err)
+	}
+	if reloadInterval > 0 {
+		go w.watch(reloadInterval)
+	}
+	return w
+}
+
+func (w *definitionWatcher) get() *definitionSet {
+	w.mu.RLock()
+	defer w.mu.RUnlock()
+	return w.current
+}
+
+// reload loads the controls files again and swaps them in if they changed.
+// Invalid files are reported, and the current definitions kept.
+func (w *definitionWatcher) reload() (bool, error) {
+	d, err := loadDefinitions(w.list)
+	if err != nil {
+		return false, err
+	}
+
+	w.mu.Lock()
+	defer w.mu.Unlock()
+	if d.digest == w.current.digest {
+		return false, nil
+	}
+	w.current = d
+	select {
+	case w.changed <- struct{}{}:
+	default:
+	}
+	return true, nil
+}
+
+// watch reloads the controls files every interval. It never returns.
+func (w *definitionWatcher) watch(interval time.Duration) {
+	for {
+		time.Sleep(interval)
+		swapped, err := w.reload()
+		if err != nil {
+			glog.Warningf("keeping the current check definitions: %v", err)
+			continue
+		}
+		if swapped {
+			glog.V(1).Info(fmt.Sprintf("Reloaded check definitions from %v", w.get().files))
+		}
+	}
+}
+
+// wait sleeps for interval, or until new definitions are swapped in.
+func (w *definitionWatcher) wait(interval time.Duration) {
+	select {
+	case <-time.After(interval):
+	case <-w.changed:
+		glog.V(1).Info("Check definitions changed, running the checks again")
+	}
+}
@@ -0,0 +1,73 @@
+package cmd
+
+import (
+	"io/ioutil"
+	"os"
+	"path/filepath"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+)
+
+func TestDefinitionWatcher(t *testing.T) {
+	dir, err := ioutil.TempDir("", "kube-bench-reload")
+	if err != nil {
+		t.Fatalf("unable to create temp directory: %v", err)
+	}
+	defer os.RemoveAll(dir)
+
+	file := filepath.Join(dir, "master.yaml")
+	write := func(data string) {
+		if err := ioutil.WriteFile(file, []byte(data), 0644); err != nil {
+			t.Fatalf("error writing %s: %v", file, err)
+		}
+	}
+	list := func() ([]string, error) { return getYamlFilesFromDir(dir) }
+
+	v1 := "---\ntype: \"master\"\ngroups:\n- id: 1.1\n  checks:\n  - id: 1.1.1\n    type: \"manual\"\n"
+	write(v1)
+	w, err := newDefinitionWatcher(list)
+	assert.NoError(t, err)
+	d := w.get()
+	in, err := d.read(file)
+	assert.NoError(t, err)
+	assert.Equal(t, v1, string(in))
+
+	t.Run("unchanged", func(t *testing.T) {
+		swapped, err := w.reload()
+		assert.NoError(t, err)
+		assert.False(t, swapped)
+		assert.Equal(t, d, w.get())
+	})
+
+	t.Run("invalid update", func(t *testing.T) {
+		write(v1 + "    remedation: \"typo\"\n")
+		swapped, err := w.reload()
+		assert.Error(t, err)
+		assert.Contains(t, err.Error(), file+`:8: check 1.1.1: unknown field "remedation"`)
+		assert.False(t, swapped)
+		assert.Equal(t, d, w.get(), "the current definitions are kept")
+	})
+
+	t.Run("valid update", func(t *testing.T) {
+		v2 := v1 + "  - id: 1.1.2\n    type: \"skip\"\n"
+		write(v2)
+		swapped, err := w.reload()
+		assert.NoError(t, err)
+		assert.True(t, swapped)
+		in, err := w.get().read(file)
+		assert.NoError(t, err)
+		assert.Equal(t, v2, string(in))
+		assert.Len(t, w.changed, 1, "the next run is triggered")
+		_, err = d.read(file)
+		assert.NoError(t, err, "runs in progress keep their snapshot")
+	})
+
+	t.Run("removed file", func(t *testing.T) {
+		os.Remove(file)
+		_, err := w.reload()
+		assert.NoError(t, err)
+		_, err = w.get().read(file)
+		assert.True(t, os.IsNotExist(err))
+	})
+}
@@ -18,6 +18,7 @@ import (
 	goflag "flag"
 	"fmt"
 	"os"
+	"time"
 
 	"github.com/aquasecurity/kube-bench/check"
 	"github.com/golang/glog"
@@ -41,7 +42,11 @@ var (
 	cfgDir              = "./cfg/"
 	jsonFmt             bool
 	junitFmt            bool
+	outputFormat        string
 	pgSQL               bool
+	notify              bool
+	exportNames         []string
+	exporterDir         string
 	masterFile          = "master.yaml"
 	nodeFile            = "node.yaml"
 	etcdFile            = "etcd.yaml"
@@ -54,7 +59,16 @@ var (
 	filterOpts          FilterOpts
 	includeTestOutput   bool
 	outputFile          string
+	scanID              string
+	anonymize           bool
+	allowUnknownFields  bool
+	strictControls      bool
 	configFileError     error
+	traceCheck          string
+	usageEndpoint       string
+	timezone            string
+	// displayTimezone is the time zone of the times in the text output.
+	displayTimezone = time.UTC
 )
 
 // RootCmd represents the base command when called without any subcommands
@@ -65,8 +79,12 @@ var RootCmd = &cobra.Command{
 	Run: func(cmd *cobra.Command, args []string) {
 		benchmarkVersion, err := getBenchmarkVersion(kubeVersion, benchmarkVersion, viper.GetViper())
 		if err != nil {
-			exitWithError(fmt.Errorf("unable to determine benchmark version: %v", err))
+			exitWithError(fmt.Errorf("unable to determine benchmark version: %w", err))
 		}
+		defer acquireRunLock()()
+		handleInterrupts(scanTimeout)
+		startCheckpoint(benchmarkVersion)
+		start := time.Now()
 
 		if isMaster() {
 			glog.V(1).Info("== Running master checks ==\n")
@@ -103,6 +121,10 @@ var RootCmd = &cobra.Command{
 			glog.V(1).Info("== Running managed services checks ==\n")
 			runChecks(check.MANAGEDSERVICES, loadConfig(check.MANAGEDSERVICES))
 		}
+		finishCheckpoint()
+		sendUsageMetrics(benchmarkVersion, time.Since(start))
+		exitIfInterrupted()
+		exitWithResults()
 
 	},
 }
@@ -131,11 +153,29 @@ func init() {
 	RootCmd.PersistentFlags().BoolVar(&noRemediations, "noremediations", false, "Disable printing of remediations section")
 	RootCmd.PersistentFlags().BoolVar(&jsonFmt, "json", false, "Prints the results as JSON")
 	RootCmd.PersistentFlags().BoolVar(&junitFmt, "junit", false, "Prints the results as JUnit")
+	RootCmd.PersistentFlags().StringVar(&outputFormat, "format", "", fmt.Sprintf("Prints the results in the given format, one of %v", check.RendererNames()))
 	RootCmd.PersistentFlags().BoolVar(&pgSQL, "pgsql", false, "Save the results to PostgreSQL")
+	RootCmd.PersistentFlags().StringSliceVar(&exportNames, "export", []string{}, "A comma-delimited list of exporters to send the results to, configured in the exporters section of config.yaml")
+	RootCmd.PersistentFlags().StringVar(&exporterDir, "exporter-dir", "", "Directory of kube-bench-exporter-<name> plugin binaries")
+	RootCmd.PersistentFlags().BoolVar(&notify, "notify", false, "Send new failures to the notification sinks configured in config.yaml")
 	RootCmd.PersistentFlags().BoolVar(&filterOpts.Scored, "scored", true, "Run the scored CIS checks")
 	RootCmd.PersistentFlags().BoolVar(&filterOpts.Unscored, "unscored", true, "Run the unscored CIS checks")
-	RootCmd.PersistentFlags().BoolVar(&includeTestOutput, "include-test-output", false, "Prints the actual result when test fails")
+	RootCmd.PersistentFlags().BoolVar(&includeTestOutput, "include-test-output", false, "Prints the expected and actual result when test fails")
 	RootCmd.PersistentFlags().StringVar(&outputFile, "outputfile", "", "Writes the JSON results to output file")
+	RootCmd.PersistentFlags().StringVar(&scanID, "scan-id", "", "Identifier of the scan included in results and exported payloads, generated if unset")
+	RootCmd.PersistentFlags().DurationVar(&scanTimeout, "timeout", 0, "Stop running checks after this duration and output partial results, e.g. 10m. No timeout if unset")
+	RootCmd.PersistentFlags().StringVar(&checkpointFile, "checkpoint", "", "Record completed checks in this file, and resume an interrupted scan from it")
+	RootCmd.PersistentFlags().BoolVar(&allowUnknownFields, "allow-unknown-fields", false, "Warn about unknown fields in the controls files instead of treating their checks as invalid")
+	RootCmd.PersistentFlags().BoolVar(&strictControls, "strict-controls", false, "Fail on the invalid groups and checks of the controls files instead of reporting them in ERROR and running the others")
+	RootCmd.PersistentFlags().BoolVar(&flagzMode, "flagz", false, "Evaluate the checks of the control plane and kubelet against the flags from their /flagz endpoints instead of their processes")
+	RootCmd.PersistentFlags().StringVar(&usageEndpoint, "usage-metrics-endpoint", "", "Opt in to sending anonymous usage metrics (kube-bench version, benchmark, run duration and platform) to this URL after each run")
+	RootCmd.PersistentFlags().BoolVar(&nativeMode, "native", false, "Evaluate the audits with the built-in implementations of ps, stat and cat, for hosts without these tools")
+	RootCmd.PersistentFlags().BoolVar(&sidecarMode, "sidecar", false, "Scan the component running in the pod of kube-bench, which must share the process namespace of the pod, with the checks of that component only")
+	RootCmd.PersistentFlags().StringVar(&sidecarComponent, "sidecar-component", "", "Component scanned with --sidecar, such as apiserver, when several are running in the pod")
+	RootCmd.PersistentFlags().BoolVar(&mockMode, "mock", false, "Evaluate the checks against a recorded host bundled in the config directory instead of this host")
+	RootCmd.PersistentFlags().BoolVar(&hostMode, "host", false, fmt.Sprintf("Run directly on the node or through nsenter rather than in a container, with the config directory in %s", hostCfgDir))
+	RootCmd.PersistentFlags().StringVar(&traceCheck, "trace-check", "", "Print every step of the evaluation of the check with this ID to the standard error, running only this check unless --check or --group is set")
+	RootCmd.PersistentFlags().BoolVar(&anonymize, "anonymize", false, "Replace hostnames, node names, IP addresses and user names in the results with hashes")
 
 	RootCmd.PersistentFlags().StringVarP(
 		&filterOpts.CheckList,
@@ -153,6 +193,14 @@ func init() {
 	)
 	RootCmd.PersistentFlags().StringVar(&cfgFile, "config", "", "config file (default is ./cfg/config.yaml)")
 	RootCmd.PersistentFlags().StringVarP(&cfgDir, "config-dir", "D", cfgDir, "config directory")
+	RootCmd.PersistentFlags().StringVar(&lockMode, "lock-mode", lockModeWait, fmt.Sprintf("What a run does when another kube-bench run holds the lock file: wait for it, exit with code %d, or off to run regardless", lockedExitCode))
+	RootCmd.PersistentFlags().StringVar(&lockPath, "lock-file", lockPath, "Lock file guarding against concurrent runs, to be shared by the runs of a node")
+	RootCmd.PersistentFlags().DurationVar(&lockTimeout, "lock-timeout", time.Hour, fmt.Sprintf("How long a run waits for the lock file with --lock-mode wait, before exiting with code %d", lockedExitCode))
+	RootCmd.PersistentFlags().StringVar(&exitCodePolicy, "exit-code-policy", exitCodePolicyLegacy, "How the exit code reports the outcome of the run: legacy exits with 0 whatever the results, detailed with a code for scored failures, warnings, config and environment errors and partial runs, see the README")
+	RootCmd.PersistentFlags().StringVar(&timezone, "timezone", "UTC", "Time zone of the times in the text output and issues, such as Europe/Paris or Local. Results and payloads are always in UTC")
+	RootCmd.PersistentFlags().StringArrayVar(&annotateFlags, "annotate", nil, "Attach a key=value annotation, such as a change ticket or build ID, to the result of every check. May be repeated")
+	RootCmd.PersistentFlags().StringVar(&factsFile, "facts", "", "YAML file of site-specific variables, such as registry hostnames or log paths, used as $<name>fact in the controls files")
+	RootCmd.PersistentFlags().StringSliceVar(&configOverlays, "config-overlay", []string{}, "Directories laid out like the config directory, such as mounted ConfigMaps, whose settings and checks override those of the config directory")
 	RootCmd.PersistentFlags().StringVar(&kubeVersion, "version", "", "Manually specify Kubernetes version, automatically detected if unset")
 	RootCmd.PersistentFlags().StringVar(&benchmarkVersion, "benchmark", "", "Manually specify CIS benchmark version. It would be an error to specify both --version and --benchmark flags")
 
@@ -164,24 +212,25 @@ func init() {
 
 // initConfig reads in config file and ENV variables if set.
 func initConfig() {
+	// Read flag values from environment variables, then from the pipeline and
+	// the config file. Precedence: Command line flags take precedence over
+	// environment variables, which take precedence over the settings of the
+	// pipeline, then over the config file.
+	if err := applyEnvSettings(RootCmd); err != nil {
+		exitWithError(configError{err})
+	}
+	setupHostMode()
+	useEmbeddedCfg()
+
 	if cfgFile != "" { // enable ability to specify config file via flag
 		viper.SetConfigFile(cfgFile)
 	} else {
 		viper.SetConfigName("config") // name of config file (without extension)
 		viper.AddConfigPath(cfgDir)   // adding ./cfg as first search path
 	}
-
-	// Read flag values from environment variables.
-	// Precedence: Command line flags take precedence over environment variables.
 	viper.SetEnvPrefix(envVarsPrefix)
 	viper.AutomaticEnv()
 
-	if kubeVersion == "" {
-		if env := viper.Get("version"); env != nil {
-			kubeVersion = env.(string)
-		}
-	}
-
 	// If a config file is found, read it in.
 	if err := viper.ReadInConfig(); err != nil {
 		if _, ok := err.(viper.ConfigFileNotFoundError); ok {
@@ -191,7 +240,59 @@ func initConfig() {
 		} else {
 			// Config file was found but another error was produced
 			colorPrint(check.FAIL, fmt.Sprintf("Failed to read config file: %v\n", err))
-			os.Exit(1)
+			os.Exit(errorExitCode(configError{err}))
+		}
+	}
+
+	setupOverlays()
+	if pipelineName != "" {
+		if err := setupPipeline(RootCmd); err != nil {
+			exitWithError(configError{err})
+		}
+	}
+	if err := applyConfigSettings(RootCmd); err != nil {
+		exitWithError(configError{err})
+	}
+	if err := validateExitCodePolicy(); err != nil {
+		exitWithError(err)
+	}
+
+	if factsFile != "" {
+		var err error
+		if facts, err = loadFacts(factsFile); err != nil {
+			exitWithError(configError{err})
 		}
 	}
+
+	var err error
+	if annotations, err = getAnnotations(viper.GetViper(), annotateFlags); err != nil {
+		exitWithError(configError{err})
+	}
+	if displayTimezone, err = time.LoadLocation(timezone); err != nil {
+		exitWithError(configError{fmt.Errorf("invalid --timezone %q: %v", timezone, err)})
+	}
+
+	if scanID == "" {
+		scanID = newScanID()
+	}
+	glog.V(1).Info(fmt.Sprintf("Scan ID: %s\n", scanID))
+
+	if outputFormat != "" {
+		if _, err := check.GetRenderer(outputFormat); err != nil {
+			exitWithError(configError{err})
+		}
+	}
+
+	if mockMode {
+		setupMock()
+	}
+	if flagzMode {
+		setupFlagz()
+	}
+	if nativeMode {
+		setupNative()
+	}
+	if sidecarMode || sidecarComponent != "" {
+		setupSidecar()
+	}
 }
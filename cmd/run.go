@@ -2,9 +2,12 @@ package cmd
 
 import (
 	"fmt"
+	"io/ioutil"
 	"os"
 	"path/filepath"
 	"strings"
+	"sync"
+	"time"
 
 	"github.com/aquasecurity/kube-bench/check"
 	"github.com/golang/glog"
@@ -19,6 +22,19 @@ func init() {
 	For example, to run the tests specified in master.yaml and etcd.yaml, specify --targets=master,etcd 
 	If no targets are specified, run tests from all files in the cfg/<version> directory.
 	`)
+	runCmd.Flags().BoolVar(&parallelTargets, "parallel-targets", false, "Run the checks of the different targets concurrently")
+	runCmd.Flags().StringVar(&pipelineName, "pipeline", "", "Run the scan declared by this pipeline of the pipelines section of config.yaml, with its targets, filters, outputs, notifiers and post-hooks")
+	runCmd.Flags().StringVar(&baselinePath, "baseline", "", fmt.Sprintf("JSON results file, or directory of earlier results, to report the scored checks that passed in all of them and now fail as regressions, exiting with code %d", regressionExitCode))
+}
+
+var parallelTargets bool
+
+// targetResult holds the outcome of running the checks of a single target.
+type targetResult struct {
+	controls *check.Controls
+	summary  check.Summary
+	// err is why the checks of the target couldn't be run.
+	err error
 }
 
 // runCmd represents the run command
@@ -31,45 +47,147 @@ var runCmd = &cobra.Command{
 		if err != nil {
 			exitWithError(fmt.Errorf("unable to get `targets` from command line :%v", err))
 		}
-
-		benchmarkVersion, err := getBenchmarkVersion(kubeVersion, benchmarkVersion, viper.GetViper())
-		if err != nil {
-			exitWithError(fmt.Errorf("unable to get benchmark version. error: %v", err))
-		}
-
-		glog.V(2).Infof("Checking targets %v for %v", targets, benchmarkVersion)
-		if len(targets) > 0 && !validTargets(benchmarkVersion, targets) {
-			exitWithError(fmt.Errorf(fmt.Sprintf(`The specified --targets "%s" does not apply to the CIS Benchmark %s \n Valid targets %v`, strings.Join(targets, ","), benchmarkVersion, benchmarkVersionToTargetsMap[benchmarkVersion])))
+		if sidecar != nil && len(targets) == 0 {
+			targets = []string{string(sidecar.nodetype)}
 		}
 
-		// Merge version-specific config if any.
-		path := filepath.Join(cfgDir, benchmarkVersion)
-		mergeConfig(path)
-
-		err = run(targets, benchmarkVersion)
+		benchmarkVersion := resolveBenchmark(targets)
+		defer acquireRunLock()()
+		handleInterrupts(scanTimeout)
+		startCheckpoint(benchmarkVersion)
+		start := time.Now()
+		regressions, err := run(targets, benchmarkVersion)
 		if err != nil {
 			fmt.Printf("Error in run: %v\n", err)
 		}
+		finishCheckpoint()
+		pipeline.runPostHooks()
+		sendUsageMetrics(benchmarkVersion, time.Since(start))
+		exitIfRunFailed(err)
+		exitIfInterrupted()
+		exitIfRegressed(regressions)
+		exitWithResults()
 	},
 }
 
-func run(targets []string, benchmarkVersion string) (err error) {
+// resolveBenchmark returns the benchmark version to run, after checking that
+// the targets apply to it and merging its version-specific config.
+func resolveBenchmark(targets []string) string {
+	benchmarkVersion, err := getBenchmarkVersion(kubeVersion, benchmarkVersion, viper.GetViper())
+	if err != nil {
+		exitWithError(fmt.Errorf("unable to get benchmark version. error: %w", err))
+	}
+
+	glog.V(2).Infof("Checking targets %v for %v", targets, benchmarkVersion)
+	if len(targets) > 0 && !validTargets(benchmarkVersion, targets) {
+		exitWithError(configError{fmt.Errorf(fmt.Sprintf(`The specified --targets "%s" does not apply to the CIS Benchmark %s \n Valid targets %v`, strings.Join(targets, ","), benchmarkVersion, benchmarkVersionToTargetsMap[benchmarkVersion]))})
+	}
+
+	// Merge version-specific config if any.
+	path := filepath.Join(cfgDir, benchmarkVersion)
+	mergeConfig(path)
+	mergeOverlayConfig(benchmarkVersion)
+
+	return benchmarkVersion
+}
+
+// run runs the checks of the targets and outputs their results. It returns the
+// checks which regressed since the baseline, if any.
+func run(targets []string, benchmarkVersion string) (regressions []*check.Check, err error) {
 	yamlFiles, err := getTestYamlFiles(targets, benchmarkVersion)
 	if err != nil {
-		return err
+		return nil, err
 	}
 
 	glog.V(3).Infof("Running tests from files %v\n", yamlFiles)
 
-	for _, yamlFile := range yamlFiles {
-		_, name := filepath.Split(yamlFile)
-		testType := check.NodeType(strings.Split(name, ".")[0])
-		runChecks(testType, yamlFile)
+	results := runTargets(yamlFiles, parallelTargets)
+	if baselinePath != "" {
+		regressions = markRegressions(results, loadBaseline())
+	}
+	for _, r := range results {
+		outputResults(r.controls, r.summary)
+	}
+
+	textOutput := getOutputFormat() == "" && !pgSQL
+	if parallelTargets && len(results) > 1 && textOutput && !noSummary {
+		printSummary("== Summary total ==", mergeSummaries(results))
+	}
+	if len(regressions) > 0 && textOutput {
+		printRegressions(regressions)
+	}
+
+	return regressions, nil
+}
+
+// runTargets runs the checks from each of the yamlFiles, concurrently if parallel is set.
+// Each target gets its own controls so no state is shared between them, and results are
+// returned in the same order as yamlFiles. It exits if the checks of a target can't be run.
+func runTargets(yamlFiles []string, parallel bool) []targetResult {
+	results := runTargetsFrom(yamlFiles, parallel, ioutil.ReadFile)
+	if err := targetsError(results); err != nil {
+		exitWithError(err)
 	}
+	return results
+}
 
+// targetsError returns the error of the first target whose checks couldn't be run.
+func targetsError(results []targetResult) error {
+	for _, r := range results {
+		if r.err != nil {
+			return r.err
+		}
+	}
 	return nil
 }
 
+// runTargetsFrom runs the targets like runTargets, with the contents of their
+// controls files returned by read. The errors of the targets are returned in
+// their results.
+func runTargetsFrom(yamlFiles []string, parallel bool, read func(string) ([]byte, error)) []targetResult {
+	results := make([]targetResult, len(yamlFiles))
+
+	var wg sync.WaitGroup
+	for i, yamlFile := range yamlFiles {
+		testType := targetType(yamlFile)
+
+		if !parallel {
+			results[i].controls, results[i].summary, results[i].err = runTarget(testType, yamlFile, read)
+			continue
+		}
+
+		wg.Add(1)
+		go func(i int, testType check.NodeType, yamlFile string) {
+			defer wg.Done()
+			glog.V(2).Infof("Running %s checks concurrently", testType)
+			results[i].controls, results[i].summary, results[i].err = runTarget(testType, yamlFile, read)
+		}(i, testType, yamlFile)
+	}
+	wg.Wait()
+
+	return results
+}
+
+// targetType returns the node type of the checks of a controls file, from its name.
+func targetType(yamlFile string) check.NodeType {
+	_, name := filepath.Split(yamlFile)
+	return check.NodeType(strings.Split(name, ".")[0])
+}
+
+// mergeSummaries adds up the summaries of all the targets.
+func mergeSummaries(results []targetResult) check.Summary {
+	var total check.Summary
+	for _, r := range results {
+		total.Pass += r.summary.Pass
+		total.Fail += r.summary.Fail
+		total.Warn += r.summary.Warn
+		total.Info += r.summary.Info
+		total.Incomplete += r.summary.Incomplete
+		total.Error += r.summary.Error
+	}
+	return total
+}
+
 func getTestYamlFiles(targets []string, benchmarkVersion string) (yamlFiles []string, err error) {
 	// Check that the specified targets have corresponding YAML files in the config directory
 	configFileDirectory := filepath.Join(cfgDir, benchmarkVersion)
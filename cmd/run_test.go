@@ -1,10 +1,15 @@
 package cmd
 
 import (
+	"fmt"
 	"io/ioutil"
 	"os"
 	"path/filepath"
 	"testing"
+
+	"github.com/aquasecurity/kube-bench/check"
+	"github.com/spf13/viper"
+	"github.com/stretchr/testify/assert"
 )
 
 func TestGetTestYamlFiles(t *testing.T) {
@@ -120,3 +125,50 @@ func TestTranslate(t *testing.T) {
 		})
 	}
 }
+
+func TestRunTargets(t *testing.T) {
+	dir, err := ioutil.TempDir("", "kube-bench-test")
+	if err != nil {
+		t.Fatalf("Failed to create temp directory")
+	}
+	defer os.RemoveAll(dir)
+
+	controlsTemplate := `---
+controls:
+id: %d
+text: "%s"
+type: "%s"
+groups:
+- id: %d.1
+  text: "group"
+  checks:
+  - id: %d.1.1
+    text: "manual check"
+    type: "manual"
+  - id: %d.1.2
+    text: "skipped check"
+    type: "skip"
+`
+	var yamlFiles []string
+	for i, nodetype := range []check.NodeType{check.CONTROLPLANE, check.POLICIES} {
+		viper.Set(string(nodetype), map[string]interface{}{"components": []string{}})
+		file := filepath.Join(dir, string(nodetype)+".yaml")
+		data := fmt.Sprintf(controlsTemplate, i, nodetype, nodetype, i, i, i)
+		if err := ioutil.WriteFile(file, []byte(data), 0666); err != nil {
+			t.Fatalf("error writing temp file %s: %v", file, err)
+		}
+		yamlFiles = append(yamlFiles, file)
+	}
+	defer viper.Reset()
+
+	filterOpts = FilterOpts{Scored: true, Unscored: true}
+	for _, parallel := range []bool{false, true} {
+		t.Run(fmt.Sprintf("parallel=%t", parallel), func(t *testing.T) {
+			results := runTargets(yamlFiles, parallel)
+			assert.Len(t, results, 2)
+			assert.Equal(t, check.NodeType(check.CONTROLPLANE), results[0].controls.Type)
+			assert.Equal(t, check.NodeType(check.POLICIES), results[1].controls.Type)
+			assert.Equal(t, check.Summary{Warn: 2, Info: 2}, mergeSummaries(results))
+		})
+	}
+}
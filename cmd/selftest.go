@@ -0,0 +1,217 @@
+package cmd
+
+import (
+	"fmt"
+	"io/ioutil"
+	"os"
+	"path/filepath"
+	"sort"
+	"strings"
+
+	"github.com/aquasecurity/kube-bench/check"
+	"github.com/spf13/cobra"
+	"github.com/spf13/viper"
+	"gopkg.in/yaml.v2"
+)
+
+var (
+	selftestUpdate     bool
+	selftestBenchmarks []string
+)
+
+func init() {
+	RootCmd.AddCommand(selftestCmd)
+	selftestCmd.Flags().BoolVar(&selftestUpdate, "update", false, "Write the results of the benchmarks as their new golden results")
+	selftestCmd.Flags().StringSliceVar(&selftestBenchmarks, "benchmarks", []string{}, "Benchmarks to test, every benchmark with golden results if unset")
+}
+
+// selftestCmd represents the selftest command
+var selftestCmd = &cobra.Command{
+	Use:   "selftest",
+	Short: "Check that the benchmarks give their golden results on the recorded host",
+	Long: `Run the benchmarks of the config directory against the recorded host used by
+--mock, and compare the state of every check to the golden results recorded in
+mock/golden/<benchmark>.yaml, so that changes to the controls files can't silently
+flip verdicts. Custom benchmark repositories can use the same harness with
+--config-dir, providing their own recorded host and golden results.`,
+	Run: func(cmd *cobra.Command, args []string) {
+		if mock == nil {
+			setupMock()
+		}
+
+		benchmarks := selftestBenchmarks
+		if len(benchmarks) == 0 {
+			var err error
+			if benchmarks, err = goldenBenchmarks(); err != nil {
+				exitWithError(err)
+			}
+			if len(benchmarks) == 0 {
+				exitWithError(fmt.Errorf("no golden results found in %s", goldenDir()))
+			}
+		}
+
+		failed := false
+		for _, benchmark := range benchmarks {
+			got, err := selftestBenchmark(benchmark)
+			if err != nil {
+				exitWithError(err)
+			}
+
+			if selftestUpdate {
+				if err := writeGolden(benchmark, got); err != nil {
+					exitWithError(err)
+				}
+				colorPrint(check.INFO, fmt.Sprintf("%s: golden results updated\n", benchmark))
+				continue
+			}
+
+			want, err := readGolden(benchmark)
+			if err != nil {
+				exitWithError(err)
+			}
+			diffs := compareGolden(want, got)
+			if len(diffs) == 0 {
+				colorPrint(check.PASS, fmt.Sprintf("%s: all checks give their golden results\n", benchmark))
+				continue
+			}
+
+			failed = true
+			colorPrint(check.FAIL, fmt.Sprintf("%s: %d checks differ from their golden results\n", benchmark, len(diffs)))
+			for _, d := range diffs {
+				fmt.Printf("%s %s: expected %s, got %s\n", d.NodeType, d.ID, goldenState(d.Want), goldenState(d.Got))
+			}
+		}
+
+		if failed {
+			os.Exit(1)
+		}
+	},
+}
+
+// goldenResults are the states of the checks of a benchmark, by target and check ID.
+type goldenResults map[check.NodeType]map[string]check.State
+
+// goldenDiff is a check whose state differs from its golden state.
+type goldenDiff struct {
+	NodeType check.NodeType
+	ID       string
+	Want     check.State
+	Got      check.State
+}
+
+func goldenDir() string {
+	return filepath.Join(cfgDir, "mock", "golden")
+}
+
+func goldenFile(benchmark string) string {
+	return filepath.Join(goldenDir(), benchmark+".yaml")
+}
+
+// goldenBenchmarks returns the benchmarks that have golden results.
+func goldenBenchmarks() ([]string, error) {
+	files, err := filepath.Glob(filepath.Join(goldenDir(), "*.yaml"))
+	if err != nil {
+		return nil, err
+	}
+
+	var benchmarks []string
+	for _, f := range files {
+		benchmarks = append(benchmarks, strings.TrimSuffix(filepath.Base(f), ".yaml"))
+	}
+	return benchmarks, nil
+}
+
+// selftestBenchmark runs all the targets of a benchmark. The config is loaded
+// again for each benchmark, so that the version-specific config of a benchmark
+// doesn't leak into the next one.
+func selftestBenchmark(benchmark string) (goldenResults, error) {
+	viper.Reset()
+	viper.SetConfigFile(filepath.Join(cfgDir, "config.yaml"))
+	if err := viper.ReadInConfig(); err != nil {
+		return nil, fmt.Errorf("failed to read config file: %v", err)
+	}
+	mergeConfig(filepath.Join(cfgDir, benchmark))
+
+	yamlFiles, err := getTestYamlFiles(benchmarkVersionToTargetsMap[benchmark], benchmark)
+	if err != nil {
+		return nil, err
+	}
+
+	results := make(goldenResults)
+	for _, r := range runTargets(yamlFiles, false) {
+		states := make(map[string]check.State)
+		for _, g := range r.controls.Groups {
+			for _, c := range g.Checks {
+				states[c.ID] = c.State
+			}
+		}
+		results[r.controls.Type] = states
+	}
+	return results, nil
+}
+
+func readGolden(benchmark string) (goldenResults, error) {
+	in, err := ioutil.ReadFile(goldenFile(benchmark))
+	if err != nil {
+		return nil, fmt.Errorf("failed to read golden results of %s: %v", benchmark, err)
+	}
+
+	results := make(goldenResults)
+	if err := yaml.Unmarshal(in, &results); err != nil {
+		return nil, fmt.Errorf("failed to load golden results of %s: %v", benchmark, err)
+	}
+	return results, nil
+}
+
+func writeGolden(benchmark string, results goldenResults) error {
+	out, err := yaml.Marshal(results)
+	if err != nil {
+		return err
+	}
+
+	if err := os.MkdirAll(goldenDir(), 0755); err != nil {
+		return err
+	}
+	header := fmt.Sprintf("## Golden results of %s on the recorded host, written by kube-bench selftest --update.\n", benchmark)
+	return ioutil.WriteFile(goldenFile(benchmark), append([]byte(header), out...), 0644)
+}
+
+// compareGolden returns the checks whose state differs from their golden
+// state, sorted by target and check ID. A check missing on one side has an
+// empty state there.
+func compareGolden(want, got goldenResults) []goldenDiff {
+	var diffs []goldenDiff
+	add := func(nodetype check.NodeType, id string) {
+		if w, g := want[nodetype][id], got[nodetype][id]; w != g {
+			diffs = append(diffs, goldenDiff{NodeType: nodetype, ID: id, Want: w, Got: g})
+		}
+	}
+
+	for nodetype, states := range want {
+		for id := range states {
+			add(nodetype, id)
+		}
+	}
+	for nodetype, states := range got {
+		for id := range states {
+			if _, ok := want[nodetype][id]; !ok {
+				add(nodetype, id)
+			}
+		}
+	}
+
+	sort.Slice(diffs, func(i, j int) bool {
+		if diffs[i].NodeType != diffs[j].NodeType {
+			return diffs[i].NodeType < diffs[j].NodeType
+		}
+		return diffs[i].ID < diffs[j].ID
+	})
+	return diffs
+}
+
+func goldenState(s check.State) string {
+	if s == "" {
+		return "no result"
+	}
+	return string(s)
+}
@@ -0,0 +1,46 @@
+package cmd
+
+import (
+	"testing"
+
+	"github.com/aquasecurity/kube-bench/check"
+	"github.com/spf13/viper"
+	"github.com/stretchr/testify/assert"
+)
+
+// TestGoldenResults runs the bundled benchmarks against the recorded host, so
+// that changes to the controls files can't silently flip verdicts. After an
+// intended change, update the golden results with kube-bench selftest --update.
+func TestGoldenResults(t *testing.T) {
+	savedCfgDir, savedPs, savedStat, savedKubeVersion := cfgDir, psFunc, statFunc, kubeVersion
+	defer func() {
+		cfgDir, psFunc, statFunc, kubeVersion, mock = savedCfgDir, savedPs, savedStat, savedKubeVersion, nil
+		viper.Reset()
+	}()
+	cfgDir = "../cfg/"
+	setupMock()
+
+	benchmarks, err := goldenBenchmarks()
+	assert.NoError(t, err)
+	assert.NotEmpty(t, benchmarks)
+	for _, benchmark := range benchmarks {
+		t.Run(benchmark, func(t *testing.T) {
+			want, err := readGolden(benchmark)
+			assert.NoError(t, err)
+			got, err := selftestBenchmark(benchmark)
+			assert.NoError(t, err)
+			assert.Empty(t, compareGolden(want, got))
+		})
+	}
+}
+
+func TestCompareGolden(t *testing.T) {
+	want := goldenResults{check.NODE: {"4.2.1": check.PASS, "4.2.2": check.FAIL, "4.2.3": check.WARN}}
+	got := goldenResults{check.NODE: {"4.2.1": check.PASS, "4.2.2": check.PASS, "4.2.4": check.FAIL}}
+
+	assert.Equal(t, []goldenDiff{
+		{NodeType: check.NODE, ID: "4.2.2", Want: check.FAIL, Got: check.PASS},
+		{NodeType: check.NODE, ID: "4.2.3", Want: check.WARN},
+		{NodeType: check.NODE, ID: "4.2.4", Got: check.FAIL},
+	}, compareGolden(want, got))
+}
@@ -0,0 +1,130 @@
+package cmd
+
+import (
+	"encoding/json"
+	"fmt"
+	"net/http"
+	"sync"
+	"time"
+
+	"github.com/aquasecurity/kube-bench/check"
+	"github.com/golang/glog"
+	"github.com/spf13/cobra"
+)
+
+var (
+	serveAddress  string
+	serveInterval time.Duration
+)
+
+func init() {
+	RootCmd.AddCommand(serveCmd)
+	serveCmd.Flags().StringSliceP("targets", "s", []string{}, "Specify targets of the benchmark to run, as with the run command")
+	serveCmd.Flags().StringVar(&serveAddress, "address", ":8080", "Address the results are served on")
+	serveCmd.Flags().DurationVar(&serveInterval, "interval", time.Hour, "Interval between runs of the checks")
+	serveCmd.Flags().BoolVar(&parallelTargets, "parallel-targets", false, "Run the checks of the different targets concurrently")
+	serveCmd.Flags().DurationVar(&reloadInterval, "reload-interval", time.Minute, "Interval between checks for changes to the controls files, which are reloaded without restarting. Never reloaded if 0")
+}
+
+// serveCmd represents the serve command
+var serveCmd = &cobra.Command{
+	Use:   "serve",
+	Short: "Run tests periodically and serve the results over HTTP",
+	Long: `Run tests periodically and serve the results of the last run over HTTP.
+The results are available as JSON on /results, and through the endpoints of
+the Grafana JSON datasource plugin under /grafana. Changes to the controls files
+are picked up without restarting, once they're all valid.`,
+	Run: func(cmd *cobra.Command, args []string) {
+		targets, err := cmd.Flags().GetStringSlice("targets")
+		if err != nil {
+			exitWithError(fmt.Errorf("unable to get `targets` from command line :%v", err))
+		}
+
+		w := watchDefinitions(targets)
+		store := &resultStore{}
+		go runPeriodically(store, w, serveInterval)
+
+		glog.V(1).Info(fmt.Sprintf("Serving results on %s", serveAddress))
+		if err := http.ListenAndServe(serveAddress, newServeMux(store)); err != nil {
+			exitWithError(fmt.Errorf("failed to serve results: %v", err))
+		}
+	},
+}
+
+// resultStore holds the results of the last run of the checks.
+type resultStore struct {
+	mu       sync.RWMutex
+	controls []*check.Controls
+	updated  time.Time
+}
+
+func (s *resultStore) set(controls []*check.Controls, updated time.Time) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	s.controls = controls
+	s.updated = updated
+}
+
+func (s *resultStore) get() ([]*check.Controls, time.Time) {
+	s.mu.RLock()
+	defer s.mu.RUnlock()
+	return s.controls, s.updated
+}
+
+// runPeriodically runs the checks of the current definitions every interval,
+// and as soon as new definitions are loaded, storing the results of each run
+// and sending them to the selected notifiers and exporters.
+func runPeriodically(store *resultStore, w *definitionWatcher, interval time.Duration) {
+	for {
+		if err := runAndStore(store, w.get()); err != nil {
+			glog.Warningf("Failed to run the checks, serving the results of the last run: %v", err)
+		}
+
+		w.wait(interval)
+	}
+}
+
+// runAndStore runs the checks of defs and stores their results. If the checks
+// of a target can't be run, for instance because a component isn't running,
+// the error is returned and the results of the last run are kept.
+func runAndStore(store *resultStore, defs *definitionSet) error {
+	glog.V(1).Info(fmt.Sprintf("Running checks from %v", defs.files))
+	results := defs.runTargets(parallelTargets)
+	if err := targetsError(results); err != nil {
+		return err
+	}
+
+	var controls []*check.Controls
+	for _, r := range results {
+		if err := sendResults(r.controls); err != nil {
+			glog.Warningf("Unable to send the %s results: %v", r.controls.Type, err)
+		}
+		controls = append(controls, r.controls)
+	}
+	store.set(controls, time.Now())
+	return nil
+}
+
+func newServeMux(store *resultStore) *http.ServeMux {
+	mux := http.NewServeMux()
+	mux.HandleFunc("/healthz", func(w http.ResponseWriter, r *http.Request) {
+		w.Write([]byte("ok"))
+	})
+	mux.HandleFunc("/results", func(w http.ResponseWriter, r *http.Request) {
+		controls, updated := store.get()
+		if updated.IsZero() {
+			http.Error(w, "no results yet", http.StatusServiceUnavailable)
+			return
+		}
+		writeJSON(w, controls)
+	})
+	registerGrafanaHandlers(mux, "/grafana", store)
+	return mux
+}
+
+func writeJSON(w http.ResponseWriter, v interface{}) {
+	w.Header().Set("Content-Type", "application/json")
+	if err := json.NewEncoder(w).Encode(v); err != nil {
+		glog.Warningf("failed to write response: %v", err)
+	}
+}
@@ -0,0 +1,76 @@
+package cmd
+
+import (
+	"encoding/json"
+	"io/ioutil"
+	"net/http"
+	"net/http/httptest"
+	"os"
+	"path/filepath"
+	"testing"
+	"time"
+
+	"github.com/aquasecurity/kube-bench/check"
+	"github.com/spf13/viper"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestServeResults(t *testing.T) {
+	store := &resultStore{}
+	ts := httptest.NewServer(newServeMux(store))
+	defer ts.Close()
+
+	resp, err := http.Get(ts.URL + "/results")
+	assert.NoError(t, err)
+	assert.Equal(t, http.StatusServiceUnavailable, resp.StatusCode, "no results before the first run")
+
+	store.set([]*check.Controls{issueControls()}, time.Now())
+	resp, err = http.Get(ts.URL + "/results")
+	assert.NoError(t, err)
+	defer resp.Body.Close()
+
+	var controls []*check.Controls
+	assert.NoError(t, json.NewDecoder(resp.Body).Decode(&controls))
+	assert.Len(t, controls, 1)
+	assert.Equal(t, "scan", controls[0].ScanID)
+}
+
+func TestRunAndStore(t *testing.T) {
+	dir, err := ioutil.TempDir("", "kube-bench-serve")
+	require.NoError(t, err)
+	defer os.RemoveAll(dir)
+
+	file := filepath.Join(dir, "master.yaml")
+	require.NoError(t, ioutil.WriteFile(file, []byte("---\ntype: \"master\"\ngroups:\n- id: 1.1\n  checks:\n  - id: 1.1.1\n    type: \"manual\"\n"), 0644))
+	w, err := newDefinitionWatcher(func() ([]string, error) { return []string{file}, nil })
+	require.NoError(t, err)
+
+	defer viper.Reset()
+	store := &resultStore{}
+
+	// Without the settings of the target, the checks can't be run.
+	assert.Error(t, runAndStore(store, w.get()))
+	controls, updated := store.get()
+	assert.Nil(t, controls)
+	assert.True(t, updated.IsZero())
+
+	viper.Set("master", map[string]interface{}{"components": []string{}})
+	assert.NoError(t, runAndStore(store, w.get()))
+	controls, updated = store.get()
+	assert.Len(t, controls, 1)
+	assert.False(t, updated.IsZero())
+
+	// A component which isn't running doesn't stop the server, which keeps
+	// serving the last results.
+	viper.Set("master", map[string]interface{}{
+		"components": []string{"apiserver"},
+		"apiserver":  map[string]interface{}{"bins": []string{"kube-bench-not-running"}},
+	})
+	err = runAndStore(store, w.get())
+	assert.Error(t, err)
+	assert.Contains(t, err.Error(), "failed to get a set of executables")
+	last, lastUpdated := store.get()
+	assert.Equal(t, controls, last)
+	assert.Equal(t, updated, lastUpdated)
+}
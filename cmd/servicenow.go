@@ -0,0 +1,176 @@
+package cmd
+
+import (
+	"crypto/sha256"
+	"encoding/hex"
+	"fmt"
+	"net/http"
+	"net/url"
+	"strings"
+
+	"github.com/aquasecurity/kube-bench/check"
+	"github.com/golang/glog"
+	"github.com/spf13/viper"
+)
+
+func init() {
+	exporterFactories["servicenow"] = newServiceNowExporter
+}
+
+// serviceNowExporter creates a record in a ServiceNow table for each finding,
+// through the Table API. The open record of a check, found by its
+// correlation_id, is updated by the next runs, and resolved once the check
+// passes.
+type serviceNowExporter struct {
+	url        string
+	table      string
+	user       string
+	password   string
+	states     map[check.State]bool
+	fields     map[string]string
+	ownerField string
+	node       string
+	// openQuery selects the records which are still open, such as active=true.
+	openQuery string
+	// resolveFields are set on the open record of a check which passes.
+	resolveFields map[string]string
+}
+
+func newServiceNowExporter(v *viper.Viper) (Exporter, error) {
+	e := &serviceNowExporter{
+		url:        strings.TrimSuffix(v.GetString("url"), "/"),
+		table:      v.GetString("table"),
+		user:       v.GetString("user"),
+		password:   v.GetString("password"),
+		states:     make(map[check.State]bool),
+		fields:     v.GetStringMapString("fields"),
+		ownerField: v.GetString("owner_field"),
+		node:       v.GetString("node"),
+		openQuery:  "active=true",
+		resolveFields: map[string]string{
+			"state":       "6",
+			"close_code":  "Solved (Permanently)",
+			"close_notes": "The kube-bench check passes.",
+		},
+	}
+	if v.IsSet("open_query") {
+		e.openQuery = v.GetString("open_query")
+	}
+	if v.IsSet("resolve_fields") {
+		e.resolveFields = v.GetStringMapString("resolve_fields")
+	}
+	if e.url == "" {
+		return nil, fmt.Errorf("url is not set")
+	}
+	if e.table == "" {
+		e.table = "incident"
+	}
+	if e.node == "" {
+		e.node = hostName()
+	}
+
+	states := v.GetStringSlice("states")
+	if len(states) == 0 {
+		states = []string{string(check.FAIL)}
+	}
+	for _, s := range states {
+		e.states[check.State(strings.ToUpper(s))] = true
+	}
+
+	return e, nil
+}
+
+func (e *serviceNowExporter) Name() string { return "servicenow" }
+
+func (e *serviceNowExporter) Export(controls *check.Controls) error {
+	endpoint := fmt.Sprintf("%s/api/now/table/%s", e.url, e.table)
+	header := http.Header{}
+	header.Set("Authorization", basicAuth(e.user, e.password))
+
+	var failed []string
+	for _, g := range controls.Groups {
+		for _, c := range g.Checks {
+			report := e.states[c.State]
+			if !report && c.State != check.PASS {
+				continue
+			}
+
+			sysID, err := e.find(endpoint, header, e.correlationID(c))
+			switch {
+			case err != nil:
+			case report && sysID == "":
+				glog.V(2).Info(fmt.Sprintf("Creating ServiceNow record for %s in %s", c.ID, e.table))
+				err = doJSON(http.MethodPost, endpoint, header, e.record(controls, c), nil)
+			case report:
+				glog.V(2).Info(fmt.Sprintf("Updating ServiceNow record %s for %s in %s", sysID, c.ID, e.table))
+				err = doJSON(http.MethodPatch, endpoint+"/"+sysID, header, e.record(controls, c), nil)
+			case sysID != "":
+				glog.V(2).Info(fmt.Sprintf("Resolving ServiceNow record %s for %s in %s", sysID, c.ID, e.table))
+				err = doJSON(http.MethodPatch, endpoint+"/"+sysID, header, e.resolveFields, nil)
+			}
+			if err != nil {
+				glog.V(1).Info(fmt.Sprintf("ServiceNow record for %s failed: %v", c.ID, err))
+				failed = append(failed, c.ID)
+			}
+		}
+	}
+
+	if len(failed) > 0 {
+		return fmt.Errorf("unable to update records for checks %s", strings.Join(failed, ", "))
+	}
+	return nil
+}
+
+// find returns the sys_id of the open record with the correlation ID id, or
+// "" if there is none.
+func (e *serviceNowExporter) find(endpoint string, header http.Header, id string) (string, error) {
+	query := "correlation_id=" + id
+	if e.openQuery != "" {
+		query += "^" + e.openQuery
+	}
+	var res struct {
+		Result []struct {
+			SysID string `json:"sys_id"`
+		} `json:"result"`
+	}
+	q := url.Values{"sysparm_query": {query}, "sysparm_fields": {"sys_id"}, "sysparm_limit": {"1"}}
+	if err := doJSON(http.MethodGet, endpoint+"?"+q.Encode(), header, nil, &res); err != nil {
+		return "", err
+	}
+	if len(res.Result) == 0 {
+		return "", nil
+	}
+	return res.Result[0].SysID, nil
+}
+
+// correlationID returns the key of the record of a check on this node, the
+// same on every run. With --anonymize, the name of the node is replaced by an
+// unsalted hash, as the hashes of the results change on every run.
+func (e *serviceNowExporter) correlationID(c *check.Check) string {
+	node := e.node
+	if anonymize {
+		sum := sha256.Sum256([]byte(node))
+		node = "host-" + hex.EncodeToString(sum[:])[:16]
+	}
+	return fmt.Sprintf("kube-bench/%s/%s", node, c.ID)
+}
+
+// record returns the fields of the record of a finding. The configured fields
+// are added to every record, and the owner of the check is set in owner_field.
+func (e *serviceNowExporter) record(controls *check.Controls, c *check.Check) map[string]string {
+	r := make(map[string]string, len(e.fields)+3)
+	for k, v := range e.fields {
+		r[k] = v
+	}
+
+	node := displayNode(e.node)
+	r["short_description"] = fmt.Sprintf("[kube-bench] %s %s on %s: %s", c.State, c.ID, node, c.Text)
+	r["description"] = fmt.Sprintf("Check: %s %s\nNode: %s (%s)\nScored: %t\nScan ID: %s\n\nRemediation:\n%s\n",
+		c.ID, c.Text, node, controls.Type, c.Scored, controls.ScanID, strings.TrimSpace(c.Remediation))
+	r["correlation_id"] = e.correlationID(c)
+	if e.ownerField != "" && c.Owner != "" {
+		r[e.ownerField] = c.Owner
+	}
+
+	return r
+}
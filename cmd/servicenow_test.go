@@ -0,0 +1,78 @@
+package cmd
+
+import (
+	"encoding/json"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+
+	"github.com/aquasecurity/kube-bench/check"
+	"github.com/spf13/viper"
+	"github.com/stretchr/testify/assert"
+)
+
+func TestServiceNowExporter(t *testing.T) {
+	var records []map[string]string
+	updated := make(map[string]map[string]string)
+	ts := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		user, pass, _ := r.BasicAuth()
+		assert.Equal(t, "admin", user)
+		assert.Equal(t, "secret", pass)
+
+		var rec map[string]string
+		switch {
+		case r.Method == http.MethodGet && r.URL.Path == "/api/now/table/u_compliance_finding":
+			// 4.2.2 and 4.2.3 have open records.
+			switch r.URL.Query().Get("sysparm_query") {
+			case "correlation_id=kube-bench/node-1/4.2.2^active=true":
+				w.Write([]byte(`{"result":[{"sys_id":"abc"}]}`))
+			case "correlation_id=kube-bench/node-1/4.2.3^active=true":
+				w.Write([]byte(`{"result":[{"sys_id":"def"}]}`))
+			default:
+				w.Write([]byte(`{"result":[]}`))
+			}
+		case r.Method == http.MethodPost && r.URL.Path == "/api/now/table/u_compliance_finding":
+			json.NewDecoder(r.Body).Decode(&rec)
+			records = append(records, rec)
+			w.WriteHeader(http.StatusCreated)
+		case r.Method == http.MethodPatch:
+			json.NewDecoder(r.Body).Decode(&rec)
+			updated[r.URL.Path] = rec
+		default:
+			http.Error(w, "unexpected", http.StatusBadRequest)
+		}
+	}))
+	defer ts.Close()
+
+	v := viper.New()
+	v.Set("url", ts.URL)
+	v.Set("table", "u_compliance_finding")
+	v.Set("user", "admin")
+	v.Set("password", "secret")
+	v.Set("states", []string{"fail", "warn"})
+	v.Set("fields", map[string]string{"category": "security"})
+	v.Set("owner_field", "assignment_group")
+	v.Set("node", "node-1")
+	e, err := newServiceNowExporter(v)
+	assert.NoError(t, err)
+
+	controls := issueControls()
+	controls.Groups[0].Checks[0].Owner = "node-team"
+	controls.Groups[0].Checks[2].State = check.PASS
+	controls.Groups[0].Checks[3].State = check.WARN
+	assert.NoError(t, e.Export(controls))
+
+	// New findings get a record, open records are updated, or resolved once
+	// their check passes.
+	assert.Len(t, records, 2)
+	assert.Equal(t, "security", records[0]["category"])
+	assert.Equal(t, "node-team", records[0]["assignment_group"])
+	assert.Equal(t, "kube-bench/node-1/4.2.1", records[0]["correlation_id"])
+	assert.Contains(t, records[1]["short_description"], "WARN 4.2.4")
+	assert.Len(t, updated, 2)
+	assert.Equal(t, "kube-bench/node-1/4.2.2", updated["/api/now/table/u_compliance_finding/abc"]["correlation_id"])
+	assert.Equal(t, "6", updated["/api/now/table/u_compliance_finding/def"]["state"])
+
+	_, err = newServiceNowExporter(viper.New())
+	assert.Error(t, err)
+}
@@ -0,0 +1,101 @@
+package cmd
+
+import (
+	"fmt"
+	"os"
+	"strings"
+
+	"github.com/spf13/cobra"
+	"github.com/spf13/pflag"
+	"github.com/spf13/viper"
+)
+
+// settingKey returns the config.yaml key of a flag. Its environment variable
+// is the key in upper case, prefixed with KUBE_BENCH_.
+func settingKey(flag string) string {
+	return strings.Replace(flag, "-", "_", -1)
+}
+
+func settingEnv(flag string) string {
+	return envVarsPrefix + "_" + strings.ToUpper(settingKey(flag))
+}
+
+// bootstrapFlags locate the config file, or the settings read from it, so they
+// can't be set in it.
+var bootstrapFlags = map[string]bool{"config": true, "config-dir": true, "help": true, "pipeline": true}
+
+// applyEnvSettings sets the flags missing from the command line from their
+// environment variables, so that kube-bench can be configured through the
+// environment of a pod instead of its arguments.
+func applyEnvSettings(cmd *cobra.Command) error {
+	return applySettings(cmd, func(name string) (string, string, bool) {
+		if name == "help" {
+			return "", "", false
+		}
+		value, ok := os.LookupEnv(settingEnv(name))
+		return value, settingEnv(name), ok
+	})
+}
+
+// applyConfigSettings sets the flags missing from both the command line and
+// the environment from their key in config.yaml.
+func applyConfigSettings(cmd *cobra.Command) error {
+	return applySettings(cmd, func(name string) (string, string, bool) {
+		key := settingKey(name)
+		if bootstrapFlags[name] || !viper.InConfig(key) {
+			return "", "", false
+		}
+		from := fmt.Sprintf("%s in %s", key, viper.ConfigFileUsed())
+		return settingValue(viper.Get(key)), from, true
+	})
+}
+
+// settingValue returns the value of a flag set from a YAML value, joining
+// lists with commas.
+func settingValue(value interface{}) string {
+	switch v := value.(type) {
+	case nil:
+		return ""
+	case []interface{}:
+		values := make([]string, len(v))
+		for i, e := range v {
+			values[i] = fmt.Sprint(e)
+		}
+		return strings.Join(values, ",")
+	}
+	return fmt.Sprint(value)
+}
+
+// applySettings sets the flags of cmd and of its subcommands that weren't
+// given on the command line to the value returned by lookup, if any, which
+// also tells where the value comes from. The flags it sets count as given, so
+// the settings of a lower precedence applied next don't override them.
+func applySettings(cmd *cobra.Command, lookup func(name string) (value, from string, ok bool)) error {
+	var errs []string
+	seen := make(map[*pflag.Flag]bool)
+	var walk func(c *cobra.Command)
+	walk = func(c *cobra.Command) {
+		for _, flags := range []*pflag.FlagSet{c.PersistentFlags(), c.Flags()} {
+			flags.VisitAll(func(f *pflag.Flag) {
+				if seen[f] || f.Changed {
+					return
+				}
+				seen[f] = true
+				if value, from, ok := lookup(f.Name); ok {
+					if err := flags.Set(f.Name, value); err != nil {
+						errs = append(errs, fmt.Sprintf("%s: %v", from, err))
+					}
+				}
+			})
+		}
+		for _, sub := range c.Commands() {
+			walk(sub)
+		}
+	}
+	walk(cmd)
+
+	if len(errs) > 0 {
+		return fmt.Errorf("%s", strings.Join(errs, "\n"))
+	}
+	return nil
+}
@@ -0,0 +1,67 @@
+package cmd
+
+import (
+	"os"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/spf13/cobra"
+	"github.com/spf13/viper"
+	"github.com/stretchr/testify/assert"
+)
+
+func TestApplySettings(t *testing.T) {
+	var (
+		json, scored      bool
+		scanID, format    string
+		timeout, interval time.Duration
+		targets           []string
+	)
+	root := &cobra.Command{Use: "root"}
+	root.PersistentFlags().BoolVar(&json, "json", false, "")
+	root.PersistentFlags().BoolVar(&scored, "scored", true, "")
+	root.PersistentFlags().StringVar(&scanID, "scan-id", "", "")
+	root.PersistentFlags().StringVar(&format, "format", "", "")
+	root.PersistentFlags().DurationVar(&timeout, "timeout", 0, "")
+	sub := &cobra.Command{Use: "serve"}
+	sub.Flags().StringSliceVarP(&targets, "targets", "s", []string{}, "")
+	sub.Flags().DurationVar(&interval, "interval", time.Hour, "")
+	root.AddCommand(sub)
+
+	// The command line takes precedence over the environment, which takes
+	// precedence over the config file.
+	assert.NoError(t, root.PersistentFlags().Set("format", "csv"))
+	for env, value := range map[string]string{
+		"KUBE_BENCH_FORMAT":   "sarif",
+		"KUBE_BENCH_SCAN_ID":  "from-env",
+		"KUBE_BENCH_TARGETS":  "master,node",
+		"KUBE_BENCH_INTERVAL": "30m",
+	} {
+		os.Setenv(env, value)
+		defer os.Unsetenv(env)
+	}
+	defer viper.Reset()
+	viper.SetConfigType("yaml")
+	assert.NoError(t, viper.ReadConfig(strings.NewReader("json: true\nscored: false\nscan_id: from-config\ntimeout: 10m\n")))
+
+	assert.NoError(t, applyEnvSettings(root))
+	assert.NoError(t, applyConfigSettings(root))
+	assert.Equal(t, "csv", format)
+	assert.Equal(t, "from-env", scanID)
+	assert.Equal(t, []string{"master", "node"}, targets)
+	assert.Equal(t, 30*time.Minute, interval)
+	assert.True(t, json)
+	assert.False(t, scored)
+	assert.Equal(t, 10*time.Minute, timeout)
+
+	t.Run("invalid value", func(t *testing.T) {
+		root := &cobra.Command{Use: "root"}
+		root.Flags().BoolVar(&json, "json", false, "")
+		os.Setenv("KUBE_BENCH_JSON", "maybe")
+		defer os.Unsetenv("KUBE_BENCH_JSON")
+		err := applyEnvSettings(root)
+		assert.Error(t, err)
+		assert.Contains(t, err.Error(), `KUBE_BENCH_JSON: invalid argument "maybe" for "--json" flag`)
+	})
+}
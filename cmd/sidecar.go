@@ -0,0 +1,168 @@
+package cmd
+
+import (
+	"fmt"
+	"os"
+	"path/filepath"
+	"regexp"
+	"sort"
+	"strconv"
+	"strings"
+
+	"github.com/aquasecurity/kube-bench/check"
+	"github.com/golang/glog"
+	"github.com/spf13/viper"
+)
+
+var (
+	sidecarMode      bool
+	sidecarComponent string
+
+	// sidecar is the component scanned with --sidecar.
+	sidecar *sidecarTarget
+
+	// sidecarNodeTypes are the targets whose components are looked for in the
+	// pod, in order, so that etcd is scanned with the etcd target rather than
+	// as a component of the master.
+	sidecarNodeTypes = []check.NodeType{check.ETCD, check.MASTER, check.NODE}
+)
+
+// sidecarTarget is the component kube-bench scans from a sidecar container
+// sharing the process namespace of its pod.
+type sidecarTarget struct {
+	component string
+	nodetype  check.NodeType
+	bin       string
+	pid       int
+}
+
+// setupSidecar looks for the component to scan among the processes of the
+// pod, which kube-bench sees when the pod has shareProcessNamespace set, and
+// restricts the run to its checks.
+func setupSidecar() {
+	if mockMode || flagzMode {
+		exitWithError(configError{fmt.Errorf("--sidecar can't be used with --mock or --flagz")})
+	}
+
+	target, err := findSidecarTarget(viper.GetViper(), readProcessTable(procDir), sidecarComponent)
+	if err != nil {
+		exitWithError(err)
+	}
+	glog.V(1).Info(fmt.Sprintf("Scanning %s component %s, running %s as PID %d", target.nodetype, target.component, target.bin, target.pid))
+	sidecar = target
+
+	hostCapsOnce.Do(func() {
+		hostCaps = hostCapabilities{pid: true, root: os.Geteuid() == 0}
+	})
+}
+
+// findSidecarTarget returns the component of the config whose binary runs as
+// one of processes, or component if it is set. There must be a single one.
+func findSidecarTarget(v *viper.Viper, processes []process, component string) (*sidecarTarget, error) {
+	found := make(map[string]*sidecarTarget)
+	for _, nodetype := range sidecarNodeTypes {
+		s := v.Sub(string(nodetype))
+		if s == nil {
+			continue
+		}
+		for _, name := range s.GetStringSlice("components") {
+			if _, ok := found[name]; ok || (component != "" && name != component) {
+				continue
+			}
+			if t := findSidecarProcess(s.GetStringSlice(name+".bins"), processes); t != nil {
+				t.component, t.nodetype = name, nodetype
+				found[name] = t
+			}
+		}
+	}
+
+	var names []string
+	for name := range found {
+		names = append(names, name)
+	}
+	sort.Strings(names)
+
+	switch {
+	case len(names) == 0 && component != "":
+		return nil, environmentError{fmt.Errorf("component %s isn't running in the pod", component)}
+	case len(names) == 0:
+		return nil, environmentError{fmt.Errorf("no component found among the processes of the pod, --sidecar requires shareProcessNamespace to be set")}
+	case len(names) > 1:
+		return nil, configError{fmt.Errorf("components %s are running in the pod, select one with --sidecar-component", strings.Join(names, ", "))}
+	}
+	return found[names[0]], nil
+}
+
+// findSidecarProcess returns the first process running one of bins, if any.
+func findSidecarProcess(bins []string, processes []process) *sidecarTarget {
+	for _, bin := range bins {
+		bin = strings.Trim(bin, "'\"")
+		re := regexp.MustCompile(`^(\S*/)*` + regexp.QuoteMeta(bin) + `(\s|$)`)
+		for _, p := range processes {
+			if re.MatchString(p.cmdline) {
+				return &sidecarTarget{bin: bin, pid: p.pid}
+			}
+		}
+	}
+	return nil
+}
+
+// runs tells whether the checks of nodetype are run, which are only those of
+// the target of the component with --sidecar.
+func (t *sidecarTarget) runs(nodetype check.NodeType) bool {
+	return t == nil || t.nodetype == nodetype
+}
+
+// optional tells whether component may not be running, which is the case of
+// all the components but the one scanned with --sidecar.
+func (t *sidecarTarget) optional(component string) bool {
+	return t != nil && t.component != component
+}
+
+// processFiles points the files of the component at their copy in the root
+// filesystem of its process, for those which aren't in that of kube-bench.
+func (t *sidecarTarget) processFiles(filemaps ...map[string]string) {
+	if t == nil {
+		return
+	}
+	root := filepath.Join(procDir, strconv.Itoa(t.pid), "root")
+	for _, m := range filemaps {
+		file, ok := m[t.component]
+		if !ok || !filepath.IsAbs(file) {
+			continue
+		}
+		if _, err := statFunc(file); err == nil {
+			continue
+		}
+		if _, err := statFunc(filepath.Join(root, file)); err == nil {
+			glog.V(2).Info(fmt.Sprintf("Using %s in the root filesystem of %s", file, t.component))
+			m[t.component] = filepath.Join(root, file)
+		}
+	}
+}
+
+// filter only runs the checks of next whose audits refer to the binary or
+// files of the component, from the substitutions of filemaps.
+func (t *sidecarTarget) filter(next check.Predicate, filemaps ...map[string]string) check.Predicate {
+	if t == nil {
+		return next
+	}
+
+	var refs []string
+	for _, m := range filemaps {
+		if v := m[t.component]; v != "" && v != t.component {
+			refs = append(refs, v)
+		}
+	}
+	return func(g *check.Group, c *check.Check) bool {
+		if !next(g, c) {
+			return false
+		}
+		for _, ref := range refs {
+			if strings.Contains(c.Audit, ref) || strings.Contains(c.AuditConfig, ref) {
+				return true
+			}
+		}
+		return false
+	}
+}
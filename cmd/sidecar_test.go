@@ -0,0 +1,93 @@
+package cmd
+
+import (
+	"errors"
+	"io/ioutil"
+	"os"
+	"path/filepath"
+	"testing"
+
+	"github.com/aquasecurity/kube-bench/check"
+	"github.com/spf13/viper"
+	"github.com/stretchr/testify/assert"
+)
+
+func TestFindSidecarTarget(t *testing.T) {
+	v := viper.New()
+	v.SetConfigFile(filepath.Join("..", "cfg", "config.yaml"))
+	assert.NoError(t, v.ReadInConfig())
+
+	apiserver := process{pid: 7, cmdline: "kube-apiserver --advertise-address=10.0.0.1 --etcd-servers=https://127.0.0.1:2379"}
+	etcd := process{pid: 9, cmdline: "/usr/local/bin/etcd --data-dir=/var/lib/etcd"}
+	pause := process{pid: 1, cmdline: "/pause"}
+
+	target, err := findSidecarTarget(v, []process{pause, apiserver}, "")
+	assert.NoError(t, err)
+	assert.Equal(t, &sidecarTarget{component: "apiserver", nodetype: check.MASTER, bin: "kube-apiserver", pid: 7}, target)
+
+	// etcd is scanned with the etcd target, though it's also a master component.
+	target, err = findSidecarTarget(v, []process{pause, etcd}, "")
+	assert.NoError(t, err)
+	assert.Equal(t, &sidecarTarget{component: "etcd", nodetype: check.ETCD, bin: "etcd", pid: 9}, target)
+
+	_, err = findSidecarTarget(v, []process{pause, apiserver, etcd}, "")
+	assert.EqualError(t, err, "components apiserver, etcd are running in the pod, select one with --sidecar-component")
+	assert.True(t, errors.As(err, &configError{}))
+
+	target, err = findSidecarTarget(v, []process{pause, apiserver, etcd}, "apiserver")
+	assert.NoError(t, err)
+	assert.Equal(t, "apiserver", target.component)
+
+	_, err = findSidecarTarget(v, []process{pause}, "")
+	assert.True(t, errors.As(err, &environmentError{}))
+	_, err = findSidecarTarget(v, []process{pause, etcd}, "apiserver")
+	assert.EqualError(t, err, "component apiserver isn't running in the pod")
+}
+
+func TestSidecarTarget(t *testing.T) {
+	var none *sidecarTarget
+	assert.True(t, none.runs(check.NODE))
+	assert.False(t, none.optional("kubelet"))
+
+	target := &sidecarTarget{component: "apiserver", nodetype: check.MASTER, bin: "kube-apiserver", pid: 7}
+	assert.True(t, target.runs(check.MASTER))
+	assert.False(t, target.runs(check.NODE))
+	assert.False(t, target.optional("apiserver"))
+	assert.True(t, target.optional("scheduler"))
+
+	all := func(*check.Group, *check.Check) bool { return true }
+	filter := target.filter(all,
+		map[string]string{"apiserver": "kube-apiserver", "scheduler": "kube-scheduler"},
+		map[string]string{"apiserver": "/etc/kubernetes/manifests/kube-apiserver.yaml"},
+		map[string]string{"apiserver": "apiserver"})
+	g := &check.Group{ID: "1.1"}
+	assert.True(t, filter(g, &check.Check{ID: "1.2.1", Audit: "/bin/ps -ef | grep kube-apiserver | grep -v grep"}))
+	assert.True(t, filter(g, &check.Check{ID: "1.1.1", Audit: "stat -c %a /etc/kubernetes/manifests/kube-apiserver.yaml"}))
+	assert.False(t, filter(g, &check.Check{ID: "1.4.1", Audit: "/bin/ps -ef | grep kube-scheduler | grep -v grep"}))
+	assert.False(t, filter(g, &check.Check{ID: "1.1.21", Audit: "ls -laR /etc/kubernetes/pki/"}), "the missing files of the component, substituted by its name, don't match")
+	assert.False(t, target.filter(func(*check.Group, *check.Check) bool { return false }, map[string]string{"apiserver": "kube-apiserver"})(g, &check.Check{Audit: "ps -ef | grep kube-apiserver"}))
+}
+
+func TestSidecarProcessFiles(t *testing.T) {
+	dir, err := ioutil.TempDir("", "kube-bench-sidecar-")
+	assert.NoError(t, err)
+	defer os.RemoveAll(dir)
+
+	defer func(d string) { procDir = d }(procDir)
+	procDir = dir
+	assert.NoError(t, os.MkdirAll(filepath.Join(dir, "7", "root", "etc", "kubernetes"), 0755))
+	assert.NoError(t, ioutil.WriteFile(filepath.Join(dir, "7", "root", "etc", "kubernetes", "kubelet.conf"), nil, 0600))
+	local := filepath.Join(dir, "local.conf")
+	assert.NoError(t, ioutil.WriteFile(local, nil, 0600))
+
+	target := &sidecarTarget{component: "kubelet", nodetype: check.NODE, pid: 7}
+	confmap := map[string]string{"kubelet": "/etc/kubernetes/kubelet.conf", "proxy": "/etc/kubernetes/kubelet.conf"}
+	kubeconfmap := map[string]string{"kubelet": local}
+	svcmap := map[string]string{"kubelet": "/etc/systemd/system/kubelet.service.d/10-kubeadm.conf"}
+	target.processFiles(confmap, kubeconfmap, svcmap)
+
+	assert.Equal(t, filepath.Join(dir, "7", "root", "etc", "kubernetes", "kubelet.conf"), confmap["kubelet"])
+	assert.Equal(t, "/etc/kubernetes/kubelet.conf", confmap["proxy"], "only the files of the component are looked up in its root")
+	assert.Equal(t, local, kubeconfmap["kubelet"], "files found locally are kept")
+	assert.Equal(t, "/etc/systemd/system/kubelet.service.d/10-kubeadm.conf", svcmap["kubelet"], "missing files are kept")
+}
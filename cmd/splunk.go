@@ -0,0 +1,140 @@
+package cmd
+
+import (
+	"encoding/json"
+	"fmt"
+	"net/http"
+	"strings"
+	"time"
+
+	"github.com/aquasecurity/kube-bench/check"
+	"github.com/golang/glog"
+	"github.com/spf13/viper"
+)
+
+const defaultSplunkBatchSize = 100
+
+func init() {
+	exporterFactories["splunk"] = newSplunkExporter
+}
+
+// splunkEvent is an event of the Splunk HTTP Event Collector.
+type splunkEvent struct {
+	Time       int64       `json:"time"`
+	Host       string      `json:"host,omitempty"`
+	Index      string      `json:"index,omitempty"`
+	Source     string      `json:"source,omitempty"`
+	SourceType string      `json:"sourcetype,omitempty"`
+	Event      splunkCheck `json:"event"`
+}
+
+// splunkCheck is the result of a check sent as the body of an event.
+type splunkCheck struct {
+	ScanID   string         `json:"scan_id,omitempty"`
+	NodeType check.NodeType `json:"node_type"`
+	Version  string         `json:"version"`
+	Section  string         `json:"section"`
+	Group    string         `json:"group"`
+	*check.Check
+}
+
+// splunkExporter sends one event per check to a Splunk HTTP Event Collector,
+// batching several events in each request.
+type splunkExporter struct {
+	url        string
+	token      string
+	index      string
+	source     string
+	sourceType string
+	batchSize  int
+	host       string
+	now        func() time.Time
+}
+
+func newSplunkExporter(v *viper.Viper) (Exporter, error) {
+	e := &splunkExporter{
+		url:        strings.TrimSuffix(v.GetString("url"), "/"),
+		token:      v.GetString("token"),
+		index:      v.GetString("index"),
+		source:     v.GetString("source"),
+		sourceType: v.GetString("sourcetype"),
+		batchSize:  v.GetInt("batch_size"),
+		host:       v.GetString("host"),
+		now:        time.Now,
+	}
+	if e.url == "" || e.token == "" {
+		return nil, fmt.Errorf("url and token must be set")
+	}
+	if !strings.Contains(e.url, "/services/collector") {
+		e.url += "/services/collector/event"
+	}
+	if e.source == "" {
+		e.source = "kube-bench"
+	}
+	if e.sourceType == "" {
+		e.sourceType = "kube-bench:check"
+	}
+	if e.batchSize <= 0 {
+		e.batchSize = defaultSplunkBatchSize
+	}
+	if e.host == "" {
+		e.host = nodeName()
+	}
+	return e, nil
+}
+
+func (e *splunkExporter) Name() string { return "splunk" }
+
+func (e *splunkExporter) Export(controls *check.Controls) error {
+	ts := e.now().Unix()
+
+	var events []splunkEvent
+	for _, g := range controls.Groups {
+		for _, c := range g.Checks {
+			events = append(events, splunkEvent{
+				Time:       ts,
+				Host:       e.host,
+				Index:      e.index,
+				Source:     e.source,
+				SourceType: e.sourceType,
+				Event: splunkCheck{
+					ScanID:   controls.ScanID,
+					NodeType: controls.Type,
+					Version:  controls.Version,
+					Section:  g.ID,
+					Group:    g.Text,
+					Check:    c,
+				},
+			})
+		}
+	}
+
+	for start := 0; start < len(events); start += e.batchSize {
+		end := start + e.batchSize
+		if end > len(events) {
+			end = len(events)
+		}
+		if err := e.send(events[start:end]); err != nil {
+			return err
+		}
+	}
+
+	return nil
+}
+
+// send posts a batch of events, which HEC accepts as concatenated JSON objects.
+func (e *splunkExporter) send(events []splunkEvent) error {
+	var body []byte
+	for _, ev := range events {
+		b, err := json.Marshal(ev)
+		if err != nil {
+			return err
+		}
+		body = append(body, b...)
+	}
+
+	glog.V(2).Info(fmt.Sprintf("Sending %d events to Splunk", len(events)))
+	header := http.Header{}
+	header.Set("Authorization", "Splunk "+e.token)
+	return doJSON(http.MethodPost, e.url, header, body, nil)
+}
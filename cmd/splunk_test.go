@@ -0,0 +1,59 @@
+package cmd
+
+import (
+	"encoding/json"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+	"time"
+
+	"github.com/spf13/viper"
+	"github.com/stretchr/testify/assert"
+)
+
+func TestSplunkExporter(t *testing.T) {
+	var batches [][]map[string]interface{}
+	ts := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		assert.Equal(t, "/services/collector/event", r.URL.Path)
+		assert.Equal(t, "Splunk secret", r.Header.Get("Authorization"))
+
+		var batch []map[string]interface{}
+		dec := json.NewDecoder(r.Body)
+		for dec.More() {
+			var ev map[string]interface{}
+			if err := dec.Decode(&ev); err != nil {
+				t.Fatalf("invalid event: %v", err)
+			}
+			batch = append(batch, ev)
+		}
+		batches = append(batches, batch)
+		w.Write([]byte(`{"text":"Success","code":0}`))
+	}))
+	defer ts.Close()
+
+	v := viper.New()
+	v.Set("url", ts.URL)
+	v.Set("token", "secret")
+	v.Set("index", "security")
+	v.Set("batch_size", 3)
+	exp, err := newSplunkExporter(v)
+	assert.NoError(t, err)
+	exp.(*splunkExporter).now = func() time.Time { return time.Unix(1577836800, 0) }
+
+	assert.NoError(t, exp.Export(issueControls()))
+	assert.Len(t, batches, 2)
+	assert.Len(t, batches[0], 3)
+	assert.Len(t, batches[1], 1)
+
+	ev := batches[0][0]
+	assert.Equal(t, float64(1577836800), ev["time"])
+	assert.Equal(t, "security", ev["index"])
+	assert.Equal(t, "kube-bench", ev["source"])
+	event := ev["event"].(map[string]interface{})
+	assert.Equal(t, "4.2.1", event["test_number"])
+	assert.Equal(t, "FAIL", event["status"])
+	assert.Equal(t, "scan", event["scan_id"])
+
+	_, err = newSplunkExporter(viper.New())
+	assert.Error(t, err)
+}
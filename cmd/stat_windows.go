@@ -0,0 +1,9 @@
+package cmd
+
+import "os"
+
+// fileIDs never finds the owner of a file on Windows, where files have no user
+// and group IDs.
+func fileIDs(fi os.FileInfo) (uid, gid uint32, ok bool) {
+	return 0, 0, false
+}
@@ -0,0 +1,123 @@
+package cmd
+
+import (
+	"bytes"
+	"fmt"
+	"net"
+	"strings"
+
+	"github.com/aquasecurity/kube-bench/check"
+	"github.com/golang/glog"
+	"github.com/spf13/viper"
+)
+
+const (
+	defaultStatsdAddress = "127.0.0.1:8125"
+	defaultStatsdPrefix  = "kube_bench"
+	// statsdMaxPacketSize keeps packets within the MTU of most networks.
+	statsdMaxPacketSize = 1432
+)
+
+func init() {
+	exporterFactories["statsd"] = newStatsdExporter
+}
+
+// statsdExporter emits the pass/fail/warn/info totals of a target and of each
+// of its sections as statsd gauges. With dogstatsd set, the node type and the
+// section are sent as DogStatsD tags instead of being part of the metric names.
+type statsdExporter struct {
+	address   string
+	prefix    string
+	dogstatsd bool
+	tags      []string
+}
+
+func newStatsdExporter(v *viper.Viper) (Exporter, error) {
+	e := &statsdExporter{
+		address:   v.GetString("address"),
+		prefix:    strings.TrimSuffix(v.GetString("prefix"), "."),
+		dogstatsd: v.GetBool("dogstatsd"),
+		tags:      v.GetStringSlice("tags"),
+	}
+	if e.address == "" {
+		e.address = defaultStatsdAddress
+	}
+	if e.prefix == "" {
+		e.prefix = defaultStatsdPrefix
+	}
+	return e, nil
+}
+
+func (e *statsdExporter) Name() string { return "statsd" }
+
+func (e *statsdExporter) Export(controls *check.Controls) error {
+	conn, err := net.Dial("udp", e.address)
+	if err != nil {
+		return err
+	}
+	defer conn.Close()
+
+	var packet bytes.Buffer
+	for _, m := range e.metrics(controls) {
+		if packet.Len() > 0 && packet.Len()+len(m)+1 > statsdMaxPacketSize {
+			if _, err := conn.Write(packet.Bytes()); err != nil {
+				return err
+			}
+			packet.Reset()
+		}
+		if packet.Len() > 0 {
+			packet.WriteByte('\n')
+		}
+		packet.WriteString(m)
+	}
+
+	if packet.Len() > 0 {
+		_, err = conn.Write(packet.Bytes())
+	}
+	return err
+}
+
+// metrics returns the lines of the gauges of a target.
+func (e *statsdExporter) metrics(controls *check.Controls) []string {
+	nodeType := string(controls.Type)
+	metrics := e.gauges(controls.Totals, nodeType+".total", "total", []string{"node_type:" + nodeType})
+
+	for _, g := range controls.Groups {
+		section := strings.Replace(g.ID, ".", "_", -1)
+		tags := []string{"node_type:" + nodeType, "section:" + g.ID}
+		metrics = append(metrics, e.gauges(g.Summary, nodeType+".section."+section, "section", tags)...)
+	}
+
+	glog.V(3).Info(fmt.Sprintf("statsd metrics: %v", metrics))
+	return metrics
+}
+
+// gauges returns a gauge for each count. Plain statsd metrics are named after
+// their scope, while DogStatsD metrics are named after the kind of scope and
+// tagged instead.
+func (e *statsdExporter) gauges(counts check.Counts, scope, kind string, tags []string) []string {
+	name := e.prefix + "." + scope
+	suffix := ""
+	if e.dogstatsd {
+		name = e.prefix + "." + kind
+		suffix = "|#" + strings.Join(append(append([]string{}, e.tags...), tags...), ",")
+	}
+
+	values := []struct {
+		state string
+		value int
+	}{
+		{"pass", counts.Pass},
+		{"fail", counts.Fail},
+		{"warn", counts.Warn},
+		{"info", counts.Info},
+		{"incomplete", counts.Incomplete},
+		{"error", counts.Error},
+	}
+
+	var lines []string
+	for _, v := range values {
+		lines = append(lines, fmt.Sprintf("%s.%s:%d|g%s", name, v.state, v.value, suffix))
+	}
+	return lines
+}
@@ -0,0 +1,63 @@
+package cmd
+
+import (
+	"net"
+	"strings"
+	"testing"
+
+	"github.com/aquasecurity/kube-bench/check"
+	"github.com/spf13/viper"
+	"github.com/stretchr/testify/assert"
+)
+
+func statsdControls() *check.Controls {
+	return &check.Controls{
+		Type:   check.MASTER,
+		Totals: check.Counts{Pass: 3, Fail: 2, Warn: 1, Error: 1, Total: 7},
+		Groups: []*check.Group{
+			{ID: "1.1", Summary: check.Counts{Pass: 3, Fail: 1, Total: 4}},
+			{ID: "1.2", Summary: check.Counts{Fail: 1, Warn: 1, Error: 1, Total: 3}},
+		},
+	}
+}
+
+func TestStatsdMetrics(t *testing.T) {
+	e, _ := newStatsdExporter(viper.New())
+	metrics := e.(*statsdExporter).metrics(statsdControls())
+	assert.Len(t, metrics, 18)
+	assert.Contains(t, metrics, "kube_bench.master.total.fail:2|g")
+	assert.Contains(t, metrics, "kube_bench.master.section.1_2.warn:1|g")
+	assert.Contains(t, metrics, "kube_bench.master.total.error:1|g", "the gauges add up to the total")
+	assert.Contains(t, metrics, "kube_bench.master.section.1_2.error:1|g")
+	assert.Contains(t, metrics, "kube_bench.master.section.1_1.incomplete:0|g")
+
+	v := viper.New()
+	v.Set("dogstatsd", true)
+	v.Set("prefix", "cis")
+	v.Set("tags", []string{"cluster:prod"})
+	e, _ = newStatsdExporter(v)
+	metrics = e.(*statsdExporter).metrics(statsdControls())
+	assert.Contains(t, metrics, "cis.total.pass:3|g|#cluster:prod,node_type:master")
+	assert.Contains(t, metrics, "cis.section.fail:1|g|#cluster:prod,node_type:master,section:1.1")
+}
+
+func TestStatsdExporter(t *testing.T) {
+	conn, err := net.ListenPacket("udp", "127.0.0.1:0")
+	if err != nil {
+		t.Fatalf("unable to listen: %v", err)
+	}
+	defer conn.Close()
+
+	v := viper.New()
+	v.Set("address", conn.LocalAddr().String())
+	e, err := newStatsdExporter(v)
+	assert.NoError(t, err)
+	assert.NoError(t, e.Export(statsdControls()))
+
+	buf := make([]byte, statsdMaxPacketSize)
+	n, _, err := conn.ReadFrom(buf)
+	assert.NoError(t, err)
+	lines := strings.Split(string(buf[:n]), "\n")
+	assert.Len(t, lines, 18)
+	assert.Equal(t, "kube_bench.master.total.pass:3|g", lines[0])
+}
@@ -0,0 +1,65 @@
+//go:build !nousage
+// +build !nousage
+
+package cmd
+
+import (
+	"bytes"
+	"encoding/json"
+	"fmt"
+	"net/http"
+	"runtime"
+	"time"
+
+	"github.com/golang/glog"
+)
+
+// usageMetricsBuiltIn tells whether the usage metrics are compiled in. Build
+// with -tags nousage to leave them out entirely.
+const usageMetricsBuiltIn = true
+
+var usageClient = &http.Client{Timeout: 5 * time.Second}
+
+// usageMetrics is everything sent to --usage-metrics-endpoint after a run. It
+// holds nothing about the cluster or its results.
+type usageMetrics struct {
+	Version         string  `json:"kube_bench_version"`
+	Benchmark       string  `json:"benchmark"`
+	DurationSeconds float64 `json:"duration_seconds"`
+	Platform        string  `json:"platform"`
+}
+
+func newUsageMetrics(benchmark string, duration time.Duration) usageMetrics {
+	return usageMetrics{
+		Version:         KubeBenchVersion,
+		Benchmark:       benchmark,
+		DurationSeconds: duration.Round(time.Second).Seconds(),
+		Platform:        runtime.GOOS + "/" + runtime.GOARCH,
+	}
+}
+
+// sendUsageMetrics posts the anonymous usage metrics of a run to the
+// endpoint the user opted in with. Nothing is sent without one, and failures
+// never affect the run.
+func sendUsageMetrics(benchmark string, duration time.Duration) {
+	if usageEndpoint == "" {
+		return
+	}
+
+	body, err := json.Marshal(newUsageMetrics(benchmark, duration))
+	if err != nil {
+		glog.V(1).Info(fmt.Sprintf("Failed to marshal usage metrics: %v", err))
+		return
+	}
+	glog.V(1).Info(fmt.Sprintf("Sending usage metrics to %s: %s", usageEndpoint, body))
+
+	resp, err := usageClient.Post(usageEndpoint, "application/json", bytes.NewReader(body))
+	if err != nil {
+		glog.V(1).Info(fmt.Sprintf("Failed to send usage metrics: %v", err))
+		return
+	}
+	resp.Body.Close()
+	if resp.StatusCode >= 300 {
+		glog.V(1).Info(fmt.Sprintf("Usage metrics endpoint returned status %d", resp.StatusCode))
+	}
+}
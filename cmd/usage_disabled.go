@@ -0,0 +1,19 @@
+//go:build nousage
+// +build nousage
+
+package cmd
+
+import (
+	"time"
+
+	"github.com/golang/glog"
+)
+
+const usageMetricsBuiltIn = false
+
+// sendUsageMetrics does nothing, the usage metrics are compiled out.
+func sendUsageMetrics(benchmark string, duration time.Duration) {
+	if usageEndpoint != "" {
+		glog.Warningf("kube-bench was built without usage metrics, ignoring --usage-metrics-endpoint")
+	}
+}
@@ -0,0 +1,45 @@
+//go:build !nousage
+// +build !nousage
+
+package cmd
+
+import (
+	"encoding/json"
+	"io/ioutil"
+	"net/http"
+	"net/http/httptest"
+	"runtime"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+)
+
+func TestSendUsageMetrics(t *testing.T) {
+	var got []map[string]interface{}
+	srv := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		body, _ := ioutil.ReadAll(r.Body)
+		var m map[string]interface{}
+		assert.NoError(t, json.Unmarshal(body, &m))
+		got = append(got, m)
+	}))
+	defer srv.Close()
+	defer func() { usageEndpoint = "" }()
+
+	// Nothing is sent unless an endpoint is set.
+	sendUsageMetrics("cis-1.5", time.Minute)
+	assert.Empty(t, got)
+
+	usageEndpoint = srv.URL
+	sendUsageMetrics("cis-1.5", 61400*time.Millisecond)
+	assert.Equal(t, []map[string]interface{}{{
+		"kube_bench_version": KubeBenchVersion,
+		"benchmark":          "cis-1.5",
+		"duration_seconds":   float64(61),
+		"platform":           runtime.GOOS + "/" + runtime.GOARCH,
+	}}, got)
+
+	// A failure to send doesn't affect the run.
+	srv.Close()
+	sendUsageMetrics("cis-1.5", time.Minute)
+}
@@ -1,6 +1,7 @@
 package cmd
 
 import (
+	"crypto/rand"
 	"fmt"
 	"os"
 	"os/exec"
@@ -8,6 +9,7 @@ import (
 	"regexp"
 	"strconv"
 	"strings"
+	"time"
 
 	"github.com/aquasecurity/kube-bench/check"
 	"github.com/fatih/color"
@@ -18,10 +20,12 @@ import (
 var (
 	// Print colors
 	colors = map[check.State]*color.Color{
-		check.PASS: color.New(color.FgGreen),
-		check.FAIL: color.New(color.FgRed),
-		check.WARN: color.New(color.FgYellow),
-		check.INFO: color.New(color.FgBlue),
+		check.PASS:       color.New(color.FgGreen),
+		check.FAIL:       color.New(color.FgRed),
+		check.WARN:       color.New(color.FgYellow),
+		check.INFO:       color.New(color.FgBlue),
+		check.INCOMPLETE: color.New(color.FgMagenta),
+		check.ERROR:      color.New(color.FgHiRed),
 	}
 )
 
@@ -45,7 +49,7 @@ func exitWithError(err error) {
 	fmt.Fprintf(os.Stderr, "\n%v\n", err)
 	// flush before exit non-zero
 	glog.Flush()
-	os.Exit(1)
+	os.Exit(errorExitCode(err))
 }
 
 func continueWithError(err error, msg string) string {
@@ -100,7 +104,7 @@ func getBinaries(v *viper.Viper, nodetype check.NodeType) (map[string]string, er
 			continue
 		}
 
-		optional := s.GetBool("optional")
+		optional := s.GetBool("optional") || sidecar.optional(component)
 		bins := s.GetStringSlice("bins")
 		if len(bins) > 0 {
 			bin, err := findExecutable(bins)
@@ -147,6 +151,11 @@ func getYamlFilesFromDir(path string) (names []string, err error) {
 		}
 
 		_, name := filepath.Split(path)
+		// Mounted ConfigMaps keep their files in a timestamped ..<date> directory,
+		// linked from the mount point.
+		if info.IsDir() && strings.HasPrefix(name, "..") && name != ".." {
+			return filepath.SkipDir
+		}
 		if name != "" && name != "config.yaml" && filepath.Ext(name) == ".yaml" {
 			names = append(names, path)
 		}
@@ -292,11 +301,23 @@ Alternatively, you can specify the version with --version
    kube-bench --version <VERSION> ...
 `
 
+const missingHostKubectlKubeletMessage = `
+Unable to find the programs kubectl or kubelet in the PATH.
+These programs are used to determine which version of Kubernetes is running.
+Make sure the directory holding them is in the PATH of kube-bench.
+
+Alternatively, you can specify the version with --version
+   kube-bench --host --version <VERSION> ...
+`
+
 func getKubeVersion() (string, error) {
 
-	if k8sVer, err := getKubeVersionFromRESTAPI(); err == nil {
-		glog.V(2).Info(fmt.Sprintf("Kubernetes REST API Reported version: %s", k8sVer))
-		return k8sVer, nil
+	// Only pods have the service account token to reach the REST API with.
+	if !hostMode {
+		if k8sVer, err := getKubeVersionFromRESTAPI(); err == nil {
+			glog.V(2).Info(fmt.Sprintf("Kubernetes REST API Reported version: %s", k8sVer))
+			return k8sVer, nil
+		}
 	}
 
 	// These executables might not be on the user's path.
@@ -312,7 +333,11 @@ func getKubeVersion() (string, error) {
 				return getVersionFromKubeletOutput(string(out)), nil
 			}
 
-			glog.Warning(missingKubectlKubeletMessage)
+			if hostMode {
+				glog.Warning(missingHostKubectlKubeletMessage)
+			} else {
+				glog.Warning(missingKubectlKubeletMessage)
+			}
 			return "", fmt.Errorf("unable to find the programs kubectl or kubelet in the PATH")
 		}
 		return getKubeVersionFromKubelet(), nil
@@ -376,6 +401,65 @@ func makeSubstitutions(s string, ext string, m map[string]string) string {
 	return s
 }
 
+// nodeName returns the hostname of the node kube-bench runs on, anonymized with --anonymize.
+func nodeName() string {
+	return displayNode(hostName())
+}
+
+// hostName returns the hostname of the node kube-bench runs on. Unlike
+// nodeName, it isn't anonymized, so that the keys derived from it are the same
+// on every run.
+func hostName() string {
+	hostname, _ := os.Hostname()
+	return hostname
+}
+
+// displayNode returns the name of a node as shown in results and payloads,
+// hashed with --anonymize.
+func displayNode(node string) string {
+	if anonymize && node != "" {
+		return getAnonymizer().hash("host", node)
+	}
+	return node
+}
+
+// formatTime formats t as RFC3339 in UTC, the format of every time in the
+// results and payloads, whatever the locale and time zone of the host.
+func formatTime(t time.Time) string {
+	return t.UTC().Format(time.RFC3339)
+}
+
+// displayTime formats a time of the results in the time zone of --timezone,
+// for the human-readable output.
+func displayTime(ts string) string {
+	t, err := time.Parse(time.RFC3339, ts)
+	if err != nil {
+		return ts
+	}
+	return t.In(displayTimezone).Format(time.RFC3339)
+}
+
+// newScanID returns a random (version 4) UUID identifying a run of kube-bench.
+func newScanID() string {
+	id, err := newUUID()
+	if err != nil {
+		exitWithError(fmt.Errorf("failed to generate scan ID: %v", err))
+	}
+	return id
+}
+
+// newUUID returns a random (version 4) UUID.
+func newUUID() (string, error) {
+	b := make([]byte, 16)
+	if _, err := rand.Read(b); err != nil {
+		return "", err
+	}
+	b[6] = (b[6] & 0x0f) | 0x40
+	b[8] = (b[8] & 0x3f) | 0x80
+
+	return fmt.Sprintf("%x-%x-%x-%x-%x", b[0:4], b[4:6], b[6:8], b[8:10], b[10:]), nil
+}
+
 func isEmpty(str string) bool {
 	return len(strings.TrimSpace(str)) == 0
 
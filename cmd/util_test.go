@@ -19,8 +19,10 @@ import (
 	"os"
 	"path/filepath"
 	"reflect"
+	"regexp"
 	"strconv"
 	"testing"
+	"time"
 
 	"github.com/aquasecurity/kube-bench/check"
 	"github.com/spf13/viper"
@@ -496,6 +498,15 @@ func TestGetYamlFilesFromDir(t *testing.T) {
 	if err != nil {
 		t.Fatalf("error writing file %v", err)
 	}
+	// The files of a mounted ConfigMap are linked from a timestamped directory.
+	err = os.Mkdir(filepath.Join(d, "..2020_01_01_00_00_00.000000000"), 0766)
+	if err != nil {
+		t.Fatalf("Failed to create temp dir")
+	}
+	err = ioutil.WriteFile(filepath.Join(d, "..2020_01_01_00_00_00.000000000", "something.yaml"), []byte("hello world"), 0666)
+	if err != nil {
+		t.Fatalf("error writing file %v", err)
+	}
 
 	files, err := getYamlFilesFromDir(d)
 	if err != nil {
@@ -509,3 +520,38 @@ func TestGetYamlFilesFromDir(t *testing.T) {
 		t.Fatalf("Expected to find something.yaml, found %s", files[0])
 	}
 }
+
+func TestNewScanID(t *testing.T) {
+	uuidRe := regexp.MustCompile(`^[0-9a-f]{8}-[0-9a-f]{4}-4[0-9a-f]{3}-[89ab][0-9a-f]{3}-[0-9a-f]{12}$`)
+
+	id := newScanID()
+	if !uuidRe.MatchString(id) {
+		t.Fatalf("Expected a UUID, got %q", id)
+	}
+	if id == newScanID() {
+		t.Fatalf("Expected unique scan IDs, got %q twice", id)
+	}
+}
+
+func TestFormatTime(t *testing.T) {
+	paris, err := time.LoadLocation("Europe/Paris")
+	if err != nil {
+		t.Fatalf("error loading time zone %v", err)
+	}
+	ts := time.Date(2020, 7, 14, 12, 30, 0, 0, paris)
+	if got := formatTime(ts); got != "2020-07-14T10:30:00Z" {
+		t.Errorf("formatTime: expected %q, got %q", "2020-07-14T10:30:00Z", got)
+	}
+
+	defer func() { displayTimezone = time.UTC }()
+	if got := displayTime("2020-07-14T10:30:00Z"); got != "2020-07-14T10:30:00Z" {
+		t.Errorf("displayTime in UTC: expected %q, got %q", "2020-07-14T10:30:00Z", got)
+	}
+	displayTimezone = paris
+	if got := displayTime("2020-07-14T10:30:00Z"); got != "2020-07-14T12:30:00+02:00" {
+		t.Errorf("displayTime in Europe/Paris: expected %q, got %q", "2020-07-14T12:30:00+02:00", got)
+	}
+	if got := displayTime("not a time"); got != "not a time" {
+		t.Errorf("displayTime of an invalid time: expected it unchanged, got %q", got)
+	}
+}
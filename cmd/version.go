@@ -1,11 +1,46 @@
 package cmd
 
 import (
+	"encoding/json"
 	"fmt"
+	"io/ioutil"
+	"path/filepath"
+	"runtime"
+	"sort"
+
 	"github.com/spf13/cobra"
+	"github.com/spf13/viper"
+)
+
+var (
+	KubeBenchVersion string
+	// KubeBenchCommit is the commit kube-bench was built from, set at build time.
+	KubeBenchCommit string
+
+	detailedVersion bool
 )
 
-var KubeBenchVersion string
+// versionInfo is the inventory printed by version --detailed, for automation
+// deciding whether this kube-bench can check a given cluster.
+type versionInfo struct {
+	Version    string          `json:"version"`
+	Commit     string          `json:"commit"`
+	GoVersion  string          `json:"go_version"`
+	Platform   string          `json:"platform"`
+	Benchmarks []benchmarkInfo `json:"benchmarks"`
+	// Platforms are the Kubernetes versions and distributions which map to
+	// one of the benchmarks.
+	Platforms []string `json:"platforms"`
+	// UsageMetrics tells whether the opt-in usage metrics are compiled in.
+	UsageMetrics bool `json:"usage_metrics"`
+}
+
+// benchmarkInfo describes one of the benchmarks of the config directory.
+type benchmarkInfo struct {
+	Name     string   `json:"name"`
+	Targets  []string `json:"targets"`
+	Versions []string `json:"versions"`
+}
 
 // versionCmd represents the version command
 var versionCmd = &cobra.Command{
@@ -13,11 +48,76 @@ var versionCmd = &cobra.Command{
 	Short: "Shows the version of kube-bench.",
 	Long:  `Shows the version of kube-bench.`,
 	Run: func(cmd *cobra.Command, args []string) {
-		fmt.Println(KubeBenchVersion)
+		if !detailedVersion {
+			fmt.Println(KubeBenchVersion)
+			return
+		}
+
+		mapping, err := loadVersionMapping(viper.GetViper())
+		if err != nil {
+			exitWithError(err)
+		}
+		info, err := getVersionInfo(cfgDir, mapping)
+		if err != nil {
+			exitWithError(err)
+		}
+		out, err := json.MarshalIndent(info, "", "  ")
+		if err != nil {
+			exitWithError(fmt.Errorf("failed to marshal version: %v", err))
+		}
+		fmt.Println(string(out))
 	},
 }
 
+// getVersionInfo lists the benchmarks of dir, with their targets and the
+// Kubernetes versions that versionMapping maps to them.
+func getVersionInfo(dir string, versionMapping map[string]string) (*versionInfo, error) {
+	info := &versionInfo{
+		Version:      KubeBenchVersion,
+		Commit:       KubeBenchCommit,
+		GoVersion:    runtime.Version(),
+		Platform:     runtime.GOOS + "/" + runtime.GOARCH,
+		Benchmarks:   []benchmarkInfo{},
+		Platforms:    []string{},
+		UsageMetrics: usageMetricsBuiltIn,
+	}
+
+	entries, err := ioutil.ReadDir(dir)
+	if err != nil {
+		return nil, fmt.Errorf("failed to list benchmarks: %v", err)
+	}
+	for _, e := range entries {
+		if !e.IsDir() || e.Name() == "mock" || e.Name() == versionMappingDir {
+			continue
+		}
+		files, err := getYamlFilesFromDir(filepath.Join(dir, e.Name()))
+		if err != nil {
+			return nil, fmt.Errorf("failed to list benchmark %s: %v", e.Name(), err)
+		}
+
+		b := benchmarkInfo{Name: e.Name(), Targets: []string{}, Versions: []string{}}
+		for _, file := range files {
+			b.Targets = append(b.Targets, string(targetType(file)))
+		}
+		for kv, bv := range versionMapping {
+			if bv == b.Name {
+				b.Versions = append(b.Versions, kv)
+			}
+		}
+		if len(b.Targets) == 0 {
+			continue
+		}
+		sort.Strings(b.Targets)
+		sort.Strings(b.Versions)
+		info.Benchmarks = append(info.Benchmarks, b)
+		info.Platforms = append(info.Platforms, b.Versions...)
+	}
+	sort.Strings(info.Platforms)
+
+	return info, nil
+}
+
 func init() {
+	versionCmd.Flags().BoolVar(&detailedVersion, "detailed", false, "Prints the version, commit, available benchmarks and supported platforms as JSON")
 	RootCmd.AddCommand(versionCmd)
 }
-
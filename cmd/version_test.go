@@ -0,0 +1,34 @@
+package cmd
+
+import (
+	"io/ioutil"
+	"os"
+	"path/filepath"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+)
+
+func TestGetVersionInfo(t *testing.T) {
+	dir, err := ioutil.TempDir("", "kube-bench-version")
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer os.RemoveAll(dir)
+	for _, file := range []string{"config.yaml", "cis-1.5/config.yaml", "cis-1.5/node.yaml", "cis-1.5/master.yaml", "gke-1.0/node.yaml", "mock/host.yaml", "empty/config.yaml"} {
+		path := filepath.Join(dir, file)
+		assert.NoError(t, os.MkdirAll(filepath.Dir(path), 0755))
+		assert.NoError(t, ioutil.WriteFile(path, nil, 0644))
+	}
+
+	info, err := getVersionInfo(dir, map[string]string{"1.16": "cis-1.5", "1.15": "cis-1.5", "gke-1.0": "gke-1.0", "1.11": "cis-1.3"})
+	assert.NoError(t, err)
+	assert.Equal(t, []benchmarkInfo{
+		{Name: "cis-1.5", Targets: []string{"master", "node"}, Versions: []string{"1.15", "1.16"}},
+		{Name: "gke-1.0", Targets: []string{"node"}, Versions: []string{"gke-1.0"}},
+	}, info.Benchmarks)
+	assert.Equal(t, []string{"1.15", "1.16", "gke-1.0"}, info.Platforms, "only versions with a benchmark are supported")
+
+	_, err = getVersionInfo(filepath.Join(dir, "missing"), nil)
+	assert.Error(t, err)
+}
@@ -0,0 +1,104 @@
+package cmd
+
+import (
+	"fmt"
+	"io/ioutil"
+	"path/filepath"
+	"sort"
+	"strings"
+
+	"github.com/golang/glog"
+	"github.com/spf13/viper"
+	"gopkg.in/yaml.v2"
+)
+
+// versionMappingDir is the directory of the config directory where
+// distributions drop their own mapping of versions to benchmarks.
+const versionMappingDir = "version_mapping.d"
+
+// versionMappingProvider supplies a mapping of Kubernetes versions, or of
+// distribution versions such as ocp-3.11, to benchmarks.
+type versionMappingProvider interface {
+	Name() string
+	VersionMapping() (map[string]string, error)
+}
+
+// configVersionMapping is the version_mapping section of config.yaml.
+type configVersionMapping struct {
+	v *viper.Viper
+}
+
+func (p configVersionMapping) Name() string { return "config file" }
+
+func (p configVersionMapping) VersionMapping() (map[string]string, error) {
+	return p.v.GetStringMapString("version_mapping"), nil
+}
+
+// fileVersionMapping is a mapping file shipped by a distribution, which has a
+// version_mapping section like config.yaml.
+type fileVersionMapping struct {
+	path string
+}
+
+func (p fileVersionMapping) Name() string { return p.path }
+
+func (p fileVersionMapping) VersionMapping() (map[string]string, error) {
+	in, err := ioutil.ReadFile(p.path)
+	if err != nil {
+		return nil, fmt.Errorf("failed to read version mapping file: %v", err)
+	}
+
+	var file struct {
+		VersionMapping map[string]string `yaml:"version_mapping"`
+	}
+	if err := yaml.Unmarshal(in, &file); err != nil {
+		return nil, fmt.Errorf("failed to parse version mapping file %s: %v", p.path, err)
+	}
+	return file.VersionMapping, nil
+}
+
+// versionMappingProviders returns the version_mapping section of the config,
+// followed by the *.yaml files of the version_mapping.d directory of dir in
+// lexical order.
+func versionMappingProviders(v *viper.Viper, dir string) []versionMappingProvider {
+	providers := []versionMappingProvider{configVersionMapping{v: v}}
+
+	files, _ := filepath.Glob(filepath.Join(dir, versionMappingDir, "*.yaml"))
+	sort.Strings(files)
+	for _, file := range files {
+		providers = append(providers, fileVersionMapping{path: file})
+	}
+	return providers
+}
+
+// mergeVersionMappings merges the mappings of the providers. A provider's
+// mapping of a version replaces that of the providers before it, so that a
+// distribution can remap the versions of config.yaml.
+func mergeVersionMappings(providers []versionMappingProvider) (map[string]string, error) {
+	kubeToBenchmarkMap := make(map[string]string)
+	for _, p := range providers {
+		m, err := p.VersionMapping()
+		if err != nil {
+			return nil, err
+		}
+		for kv, bv := range m {
+			kv = strings.ToLower(kv)
+			if old, ok := kubeToBenchmarkMap[kv]; ok && old != bv {
+				glog.V(1).Info(fmt.Sprintf("Version %s mapped to %s by %s instead of %s", kv, bv, p.Name(), old))
+			}
+			kubeToBenchmarkMap[kv] = bv
+		}
+		glog.V(2).Info(fmt.Sprintf("Loaded %d version mappings from %s", len(m), p.Name()))
+	}
+
+	if len(kubeToBenchmarkMap) == 0 {
+		return nil, fmt.Errorf("config file is missing 'version_mapping' section")
+	}
+	return kubeToBenchmarkMap, nil
+}
+
+// loadVersionMapping returns the mapping of versions to benchmarks of the
+// config and of the mapping files of the config directory.
+func loadVersionMapping(v *viper.Viper) (map[string]string, error) {
+	return mergeVersionMappings(versionMappingProviders(v, cfgDir))
+}
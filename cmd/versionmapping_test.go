@@ -0,0 +1,46 @@
+package cmd
+
+import (
+	"io/ioutil"
+	"os"
+	"path/filepath"
+	"testing"
+
+	"github.com/spf13/viper"
+	"github.com/stretchr/testify/assert"
+)
+
+func TestMergeVersionMappings(t *testing.T) {
+	dir, err := ioutil.TempDir("", "kube-bench-version-mapping-")
+	assert.NoError(t, err)
+	defer os.RemoveAll(dir)
+
+	assert.NoError(t, os.MkdirAll(filepath.Join(dir, versionMappingDir), 0755))
+	assert.NoError(t, ioutil.WriteFile(filepath.Join(dir, versionMappingDir, "10-rke2.yaml"), []byte("version_mapping:\n  \"rke2-1.18\": \"rke2-cis-1.5\"\n  \"1.17\": \"cis-1.4\"\n"), 0644))
+	assert.NoError(t, ioutil.WriteFile(filepath.Join(dir, versionMappingDir, "20-k3s.yaml"), []byte("version_mapping:\n  \"K3S-1.18\": \"k3s-cis-1.5\"\n  \"1.17\": \"cis-1.5\"\n"), 0644))
+	assert.NoError(t, ioutil.WriteFile(filepath.Join(dir, versionMappingDir, "README"), []byte("not a mapping"), 0644))
+
+	v := viper.New()
+	v.Set("version_mapping", map[string]string{"1.16": "cis-1.5", "1.17": "cis-1.5"})
+
+	mapping, err := mergeVersionMappings(versionMappingProviders(v, dir))
+	assert.NoError(t, err)
+	assert.Equal(t, map[string]string{
+		"1.16":      "cis-1.5",
+		"1.17":      "cis-1.5", // the last file wins
+		"rke2-1.18": "rke2-cis-1.5",
+		"k3s-1.18":  "k3s-cis-1.5",
+	}, mapping)
+
+	// The files are enough without a version_mapping section in the config.
+	mapping, err = mergeVersionMappings(versionMappingProviders(viper.New(), dir))
+	assert.NoError(t, err)
+	assert.Equal(t, "rke2-cis-1.5", mapping["rke2-1.18"])
+
+	_, err = mergeVersionMappings(versionMappingProviders(viper.New(), filepath.Join(dir, "nonexistent")))
+	assert.EqualError(t, err, "config file is missing 'version_mapping' section")
+
+	assert.NoError(t, ioutil.WriteFile(filepath.Join(dir, versionMappingDir, "30-broken.yaml"), []byte("version_mapping: [\n"), 0644))
+	_, err = mergeVersionMappings(versionMappingProviders(v, dir))
+	assert.Error(t, err)
+}
@@ -0,0 +1,55 @@
+package cmd
+
+import (
+	"fmt"
+	"net/http"
+
+	"github.com/aquasecurity/kube-bench/check"
+	"github.com/golang/glog"
+	"github.com/spf13/viper"
+)
+
+func init() {
+	exporterFactories["webhook"] = newWebhookExporter
+}
+
+// webhookExporter posts the JSON results of a target to a URL, compressed or
+// split into chunks for receivers which limit the size of the requests.
+type webhookExporter struct {
+	url            string
+	maxPayloadSize int
+	compress       bool
+}
+
+func newWebhookExporter(v *viper.Viper) (Exporter, error) {
+	e := &webhookExporter{
+		url:            v.GetString("url"),
+		maxPayloadSize: v.GetInt("max_payload_size"),
+		compress:       v.GetBool("compress"),
+	}
+	if e.url == "" {
+		return nil, fmt.Errorf("url is not set")
+	}
+	return e, nil
+}
+
+func (e *webhookExporter) Name() string { return "webhook" }
+
+func (e *webhookExporter) Export(controls *check.Controls) error {
+	out, err := controls.JSON()
+	if err != nil {
+		return err
+	}
+
+	bodies, err := chunkPayload(fmt.Sprintf("%s/%s", controls.ScanID, controls.Type), out, e.maxPayloadSize, e.compress)
+	if err != nil {
+		return err
+	}
+	glog.V(2).Info(fmt.Sprintf("Sending %s results to %s in %d requests", controls.Type, e.url, len(bodies)))
+	for _, body := range bodies {
+		if err := doJSON(http.MethodPost, e.url, nil, body, nil); err != nil {
+			return err
+		}
+	}
+	return nil
+}
@@ -0,0 +1,50 @@
+package cmd
+
+import (
+	"fmt"
+	"io/ioutil"
+	"net/http"
+	"net/http/httptest"
+	"os"
+	"path/filepath"
+	"testing"
+
+	"github.com/aquasecurity/kube-bench/check"
+	"github.com/spf13/viper"
+	"github.com/stretchr/testify/assert"
+)
+
+func TestWebhookExporter(t *testing.T) {
+	dir, err := ioutil.TempDir("", "kube-bench-webhook-")
+	assert.NoError(t, err)
+	defer os.RemoveAll(dir)
+
+	// The receiver writes each request to a file, from which the results are
+	// reassembled.
+	var requests int
+	ts := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		body, _ := ioutil.ReadAll(r.Body)
+		assert.True(t, len(body) <= 300, "request of %d bytes", len(body))
+		requests++
+		ioutil.WriteFile(filepath.Join(dir, fmt.Sprintf("%03d.json", requests)), body, 0644)
+	}))
+	defer ts.Close()
+
+	v := viper.New()
+	v.Set("url", ts.URL)
+	v.Set("max_payload_size", 300)
+	v.Set("compress", true)
+	e, err := newWebhookExporter(v)
+	assert.NoError(t, err)
+	assert.NoError(t, e.Export(issueControls()))
+	assert.True(t, requests > 1)
+
+	reports, err := loadReports(dir)
+	assert.NoError(t, err)
+	assert.Len(t, reports, 1)
+	assert.Equal(t, check.NODE, reports[0].Type)
+	assert.Equal(t, check.FAIL, reports[0].Groups[0].Checks[0].State)
+
+	_, err = newWebhookExporter(viper.New())
+	assert.EqualError(t, err, "url is not set")
+}
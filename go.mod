@@ -1,6 +1,6 @@
 module github.com/aquasecurity/kube-bench
 
-go 1.13
+go 1.16
 
 require (
 	github.com/denisenkom/go-mssqldb v0.0.0-20190515213511-eb9f6a1743f3 // indirect
@@ -19,6 +19,7 @@ require (
 	github.com/onsi/ginkgo v1.10.1
 	github.com/pkg/errors v0.8.1
 	github.com/spf13/cobra v0.0.3
+	github.com/spf13/pflag v1.0.3
 	github.com/spf13/viper v1.4.0
 	github.com/stretchr/testify v1.3.0
 	golang.org/x/oauth2 v0.0.0-20190402181905-9f3314589c9a // indirect
@@ -15,9 +15,21 @@
 package main
 
 import (
+	"embed"
+	"io/fs"
+	// The time zones of --timezone, for hosts and images without tzdata.
+	_ "time/tzdata"
+
 	"github.com/aquasecurity/kube-bench/cmd"
 )
 
+// cfgFS is the default config directory, built into the binary so that it
+// can run on hosts without the config directory alongside it.
+//
+//go:embed cfg
+var cfgFS embed.FS
+
 func main() {
+	cmd.EmbeddedCfg, _ = fs.Sub(cfgFS, "cfg")
 	cmd.Execute()
 }